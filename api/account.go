@@ -1,14 +1,13 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
-	"github.com/gofiber/fiber/v2" // Import for handling PostgreSQL-specific errors
-	"github.com/lib/pq"
+	"github.com/gofiber/fiber/v2"
 	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"      // Token handling (JWT/Paseto)
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 )
 
 // ---------------------------
@@ -17,12 +16,13 @@ import (
 
 // **createAccountRequest** defines the structure for incoming JSON data in the `createAccount` handler.
 type createAccountRequest struct {
-	Currency string `json:"currency" validate:"required,currency"` // This field is required and must be a valid currency code.
+	Currency string `json:"currency" validate:"required,currency"`  // This field is required and must be a valid currency code.
+	Type     string `json:"type" validate:"omitempty,account_type"` // Optional account type ("checking" or "savings"), defaults to checking.
 }
 
 // **getAccountRequest** defines the structure for path parameters in the `getAccount` handler.
 type getAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"` // This field is required and must be a positive integer (minimum value of 1).
+	ID int64 `params:"id" validate:"required,min=1"` // This field is required and must be a positive integer (minimum value of 1).
 }
 
 // listAccountRequest represents query parameters for listing accounts
@@ -33,7 +33,35 @@ type listAccountRequest struct {
 
 // **deleteAccountRequest** defines the structure for path parameters in the `deleteAccount` handler.
 type deleteAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"` // This field is required and must be a positive integer (minimum value of 1).
+	ID int64 `params:"id" validate:"required,min=1"` // This field is required and must be a positive integer (minimum value of 1).
+}
+
+// ---------------------------
+// Response Structs
+// ---------------------------
+
+// accountResponse is an account as /v1 returns it, with the balance in the currency's
+// smallest unit
+// @Description Account response payload
+type accountResponse struct {
+	ID        int64     `json:"id"`         // Account ID
+	Owner     string    `json:"owner"`      // Username of the owner
+	Balance   int64     `json:"balance"`    // Balance in the currency's smallest unit (e.g. cents)
+	Currency  string    `json:"currency"`   // Currency code, e.g. USD
+	CreatedAt time.Time `json:"created_at"` // Timestamp of account creation
+	Type      string    `json:"type"`       // checking or savings
+}
+
+// newAccountResponse converts db.Account struct into an accountResponse for API response
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:        account.ID,
+		Owner:     account.Owner,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		CreatedAt: account.CreatedAt,
+		Type:      account.Type,
+	}
 }
 
 // ---------------------------
@@ -50,24 +78,20 @@ type deleteAccountRequest struct {
 // @Accept json
 // @Produce json
 // @Param account body createAccountRequest true "Account info"
-// @Success 200 {object} db.Account
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} accountResponse
+// @Failure 400 {object} problemResponse
+// @Failure 401 {object} problemResponse
+// @Failure 403 {object} problemResponse
+// @Failure 500 {object} problemResponse
 // @Security ApiKeyAuth
-// @Router /accounts [post]
+// @Router /v1/accounts [post]
 func (server *Server) createAccount(c *fiber.Ctx) error {
 	// 1. Parse Request Body
 	var req createAccountRequest
 
-	// Attempt to parse the request body to the `createAccountRequest` struct.
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
-	}
-
-	if err := server.validate.Struct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	// Parse the request body into the `createAccountRequest` struct and validate it.
+	if err := server.bind(c, &req); err != nil {
+		return err
 	}
 
 	// 2. Extract Authentication Information
@@ -75,45 +99,54 @@ func (server *Server) createAccount(c *fiber.Ctx) error {
 	// This assumes the context has a key named `authorizationPayloadKey` containing the token payload.
 	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
 	if !ok || payload == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("unauthorized")))
+		return unauthorized()
 	}
 	// Get username from payload
 	username := payload.Username
 
+	// Accounts are checking accounts unless a type is requested
+	accountType := req.Type
+	if accountType == "" {
+		accountType = util.CheckingAccount
+	}
+
 	// 3. Prepare Database Arguments
 	// Create a `db.CreateAccountParams` struct to hold arguments for the database call.
 	arg := db.CreateAccountParams{
 		Owner:    username,     // Set the owner based on the authenticated user.
 		Currency: req.Currency, // Set the currency from the request.
 		Balance:  0,            // Initial balance is set to 0.
+		Type:     accountType,  // Set the account type (checking or savings).
 	}
 
 	// 4. Call Database Function
-	// Call the `CreateAccount` function from the `server.store` object (assuming it interacts with the database).
-	// This function likely creates a new account in the database based on the provided arguments.
-	account, err := server.store.CreateAccount(c.Context(), arg)
+	// Call the `CreateAccountTx` function, which creates the account and records an
+	// `account.created` outbox event in the same transaction.
+	account, err := server.store.CreateAccountTx(c.UserContext(), arg, func(account db.Account) db.AppendAuditEventTxParams {
+		return auditEvent(c, username, auditAccountCreated, auditTarget("account", account.ID), nil, account)
+	})
 
 	// Handle Database Errors
 	if err != nil {
-		// Check if the error is a specific type (`*pq.Error`) indicating a PostgreSQL error.
-		if pqErr, ok := err.(*pq.Error); ok {
-			// If it's a PostgreSQL error, check the error code.
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				// Handle foreign key or unique constraint violations (e.g., invalid owner or duplicate currency).
-				return c.Status(fiber.StatusForbidden).JSON(errorResponse(err)) // Send a forbidden response with the error message.
-			}
+		// Check the PostgreSQL error code, if the error came from PostgreSQL.
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation:
+			// The user already has an account in this currency
+			return &apiError{Status: fiber.StatusForbidden, Code: errCodeAccountExists, Detail: "an account in this currency already exists", Err: err}
+		case db.ForeignKeyViolation:
+			// The owner doesn't exist
+			return &apiError{Status: fiber.StatusForbidden, Code: errCodeForbidden, Detail: "the account owner doesn't exist", Err: err}
 		}
 	}
 
 	// If it's not a specific PostgreSQL error, handle it as a generic internal server error.
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err)) // Send an internal server error response with the error message.
+		return internalError(err)
 	}
 
 	// 5. Success Response
 	// If everything is successful, send a JSON response with the created account data.
-	return c.Status(fiber.StatusOK).JSON(account)
+	return c.Status(fiber.StatusOK).JSON(versionOf(c).account(account))
 }
 
 // ------------ **API Functionality for Getting an Account** ------------
@@ -126,44 +159,44 @@ func (server *Server) createAccount(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Account ID"
-// @Success 200 {object} db.Account
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} accountResponse
+// @Failure 400 {object} problemResponse
+// @Failure 401 {object} problemResponse
+// @Failure 404 {object} problemResponse
+// @Failure 500 {object} problemResponse
 // @Security ApiKeyAuth
-// @Router /accounts/{id} [get]
+// @Router /v1/accounts/{id} [get]
 func (server *Server) getAccount(c *fiber.Ctx) error {
 	// 1. Parse account ID from URL parameters
 	var req getAccountRequest
-	if err := c.ParamsParser(&req); err != nil || req.ID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(fmt.Errorf("invalid account ID")))
+	if err := server.bind(c, &req); err != nil {
+		return err
 	}
 
 	// 2. Retrieve account from database
-	account, err := server.store.GetAccount(c.Context(), req.ID)
+	account, err := server.store.GetAccount(c.UserContext(), req.ID)
 	if err != nil {
 		switch err {
-		case sql.ErrNoRows:
-			return c.Status(fiber.StatusNotFound).JSON(errorResponse(fmt.Errorf("account not found")))
+		case db.ErrRecordNotFound:
+			return newAPIError(fiber.StatusNotFound, errCodeAccountNotFound, "account not found")
 		default:
-			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+			return internalError(err)
 		}
 	}
 
 	// 3. Extract authentication payload
 	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
 	if !ok || payload == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("unauthorized")))
+		return unauthorized()
 	}
 
 	// 4. Check ownership
 	if account.Owner != payload.Username {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("account doesn't belong to the authenticated user")))
+		return newAPIError(fiber.StatusUnauthorized, errCodeAccountNotOwned, "account doesn't belong to the authenticated user")
 	}
 
 	// 5. Return successful response
-	return c.Status(fiber.StatusOK).JSON(account)
+	return c.Status(fiber.StatusOK).JSON(versionOf(c).account(account))
 }
 
 // ------------ **API Functionality for Listing Accounts** ------------
@@ -177,24 +210,20 @@ func (server *Server) getAccount(c *fiber.Ctx) error {
 // @Produce json
 // @Param page_id query int true "Page number"
 // @Param page_size query int true "Items per page"
-// @Success 200 {array} db.Account
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {array} accountResponse
+// @Failure 400 {object} problemResponse
+// @Failure 401 {object} problemResponse
+// @Failure 404 {object} problemResponse
+// @Failure 500 {object} problemResponse
 // @Security ApiKeyAuth
-// @Router /accounts [get]
+// @Router /v1/accounts [get]
 func (server *Server) listAccount(c *fiber.Ctx) error {
 	// 1. Parse query parameters
 	var req listAccountRequest
 
 	// Attempt to parse query parameters to the `listAccountRequest` struct.
-	if err := c.QueryParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
-	}
-	// Validate query params
-	if err := server.validate.Struct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	if err := server.bind(c, &req); err != nil {
+		return err
 	}
 
 	// 2. Extract Authentication Information
@@ -202,7 +231,7 @@ func (server *Server) listAccount(c *fiber.Ctx) error {
 	// This assumes the context has a key named `authorizationPayloadKey` containing the token payload.
 	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
 	if !ok || payload == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("unauthorized")))
+		return unauthorized()
 	}
 	// Get username from payload
 	username := payload.Username
@@ -218,23 +247,28 @@ func (server *Server) listAccount(c *fiber.Ctx) error {
 	// 4. Call Database Function
 	// Call the `ListAccounts` function from the `server.store` object (assuming it interacts with the database).
 	// This function likely retrieves a list of accounts for the authenticated user with pagination.
-	accounts, err := server.store.ListAccounts(c.Context(), arg)
+	accounts, err := server.store.ListAccounts(c.UserContext(), arg)
 
 	// 5. Handle Database Errors
 	if err != nil {
-		// Check if the error is a specific error indicating no rows found (`sql.ErrNoRows`).
-		if err == sql.ErrNoRows {
+		// Check if the error is a specific error indicating no rows found (`db.ErrRecordNotFound`).
+		if err == db.ErrRecordNotFound {
 			// Handle the case where no account is found.
-			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err)) // Send a not found response with the error message.
+			return &apiError{Status: fiber.StatusNotFound, Code: errCodeAccountNotFound, Detail: "no accounts found", Err: err}
 		}
 
 		// If it's not a specific error, handle it as a generic internal server error.
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err)) // Send an internal server error response with the error message.
+		return internalError(err)
 	}
 
 	// 6. Success Response
 	// If everything is successful, send a JSON response with the list of accounts.
-	return c.Status(fiber.StatusOK).JSON(accounts)
+	version := versionOf(c)
+	resp := make([]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		resp = append(resp, version.account(account))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 // ------------ **API Functionality for Deleting an Account** ------------
@@ -242,48 +276,56 @@ func (server *Server) listAccount(c *fiber.Ctx) error {
 // deleteAccount handles DELETE /accounts/:id endpoint
 
 // @Summary Delete account
-// @Description Deletes an account by ID. Must belong to authenticated user.
+// @Description Deletes an account by ID. Must belong to authenticated user. Refused with 409 once entries, transfers or interest refer to the account.
 // @Tags Accounts
 // @Accept json
 // @Produce json
 // @Param id path int true "Account ID"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problemResponse
+// @Failure 401 {object} problemResponse
+// @Failure 404 {object} problemResponse
+// @Failure 409 {object} problemResponse
+// @Failure 500 {object} problemResponse
 // @Security ApiKeyAuth
-// @Router /accounts/{id} [delete]
+// @Router /v1/accounts/{id} [delete]
 func (server *Server) deleteAccount(c *fiber.Ctx) error {
 	// 1. Parse account ID from URL path
 	var req deleteAccountRequest
-	if err := c.ParamsParser(&req); err != nil || req.ID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(fmt.Errorf("invalid account ID")))
+	if err := server.bind(c, &req); err != nil {
+		return err
 	}
 
 	// 2. Retrieve the account from DB to confirm existence and ownership
-	account, err := server.store.GetAccount(c.Context(), req.ID)
+	account, err := server.store.GetAccount(c.UserContext(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return c.Status(fiber.StatusNotFound).JSON(errorResponse(fmt.Errorf("account not found")))
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusNotFound, errCodeAccountNotFound, "account not found")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return internalError(err)
 	}
 
 	// 3. Retrieve authentication payload from context
 	authPayload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
 	if !ok || authPayload == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("unauthorized")))
+		return unauthorized()
 	}
 
 	// 4. Ensure account belongs to the authenticated user
 	if account.Owner != authPayload.Username {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("account doesn't belong to the authenticated user")))
+		return newAPIError(fiber.StatusUnauthorized, errCodeAccountNotOwned, "account doesn't belong to the authenticated user")
 	}
 
-	// 5. Perform account deletion
-	if err := server.store.DeleteAccount(c.Context(), req.ID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	// 5. Perform account deletion (also records an `account.closed` outbox event)
+	_, err = server.store.DeleteAccountTx(c.UserContext(), req.ID, func(closed db.Account) db.AppendAuditEventTxParams {
+		return auditEvent(c, authPayload.Username, auditAccountClosed, auditTarget("account", req.ID), account, closed)
+	})
+	if err != nil {
+		// The ledger keeps every account money has moved through or interest accrued on
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			return &apiError{Status: fiber.StatusConflict, Code: errCodeAccountInLedger, Detail: "the account has ledger history and can't be deleted", Err: err}
+		}
+		return internalError(err)
 	}
 
 	// 6. Respond with success message
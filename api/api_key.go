@@ -0,0 +1,228 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"      // Token handling (JWT/Paseto)
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // API key generation and hashing
+)
+
+// ---------------------------
+// Request and Response Structs
+// ---------------------------
+
+// createAPIKeyRequest represents the expected JSON body for creating an API key
+// @Description API key creation payload
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=64"`                 // Label to recognize the key by
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,api_scope"` // e.g. ["accounts:read", "transfers:write"]
+	ExpiresAt *time.Time `json:"expires_at"`                                      // Optional; the key never expires when omitted
+}
+
+// apiKeyIDRequest defines the path parameter for a single API key
+type apiKeyIDRequest struct {
+	ID int64 `params:"id" validate:"required,min=1"`
+}
+
+// listAPIKeyRequest represents query parameters for paginated API key listings
+type listAPIKeyRequest struct {
+	PageID   int `query:"page_id" validate:"required,min=1"`          // Current page number, min 1
+	PageSize int `query:"page_size" validate:"required,min=5,max=10"` // Items per page, 5–10
+}
+
+// apiKeyResponse represents an API key returned by the API
+// @Description Personal API key
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Public part of the key, to tell keys apart
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Key        string     `json:"key,omitempty"` // The full key, only returned when it is created
+}
+
+// newAPIKeyResponse converts a db.ApiKey without its secret
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if apiKey.ExpiresAt.Valid {
+		resp.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.LastUsedAt.Valid {
+		resp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	return resp
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// createAPIKey handles POST /api_keys endpoint
+
+// CreateAPIKey godoc
+// @Summary      Create an API key
+// @Description  Creates a personal API key for machine clients, sent as "Authorization: ApiKey <key>". It can only call routes covered by its scopes. The key is only returned once.
+// @Tags         API Keys
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request  body      createAPIKeyRequest  true  "Key name, scopes and expiry"
+// @Success      200      {object}  apiKeyResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      401      {object}  problemResponse
+// @Failure      403      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/api_keys [post]
+func (server *Server) createAPIKey(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req createAPIKeyRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return invalidField("expires_at", "gt", "must be in the future")
+		}
+		expiresAt = sql.NullTime{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+
+	// 2. Retrieve authenticated user from payload
+	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || payload == nil {
+		return unauthorized()
+	}
+
+	// 3. Generate the key; only its prefix and the hash of its secret are stored
+	key, prefix, secret, err := util.GenerateAPIKey()
+	if err != nil {
+		return internalError(err)
+	}
+
+	apiKey, err := server.store.CreateAPIKeyTx(c.UserContext(), db.CreateAPIKeyParams{
+		Owner:      payload.Username,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: util.HashSecureToken(secret),
+		Scopes:     req.Scopes,
+		ExpiresAt:  expiresAt,
+	}, func(apiKey db.ApiKey) db.AppendAuditEventTxParams {
+		return auditEvent(c, payload.Username, auditAPIKeyCreated, auditTarget("api_key", apiKey.ID), nil, newAPIKeyResponse(apiKey))
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 4. Return the key this one time
+	resp := newAPIKeyResponse(apiKey)
+	resp.Key = key
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// listAPIKeys handles GET /api_keys endpoint
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  Returns a paginated list of the authenticated user's API keys, without their secrets
+// @Tags         API Keys
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        page_id    query  int  true  "Page number"
+// @Param        page_size  query  int  true  "Items per page"
+// @Success      200  {array}   apiKeyResponse
+// @Failure      400  {object}  problemResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      403  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/api_keys [get]
+func (server *Server) listAPIKeys(c *fiber.Ctx) error {
+	// 1. Parse and validate query parameters
+	var req listAPIKeyRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Retrieve authenticated user from payload
+	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || payload == nil {
+		return unauthorized()
+	}
+
+	// 3. Fetch the page
+	apiKeys, err := server.store.ListAPIKeys(c.UserContext(), db.ListAPIKeysParams{
+		Owner:  payload.Username,
+		Limit:  int64(req.PageSize),
+		Offset: int64((req.PageID - 1) * req.PageSize),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, newAPIKeyResponse(apiKey))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// deleteAPIKey handles DELETE /api_keys/:id endpoint
+
+// DeleteAPIKey godoc
+// @Summary      Revoke an API key
+// @Description  Deletes an API key; requests made with it fail from then on. Must belong to authenticated user.
+// @Tags         API Keys
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "API key ID"
+// @Success      200  {object}  messageResponse
+// @Failure      400  {object}  problemResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      403  {object}  problemResponse
+// @Failure      404  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/api_keys/{id} [delete]
+func (server *Server) deleteAPIKey(c *fiber.Ctx) error {
+	// 1. Parse API key ID from URL path
+	var req apiKeyIDRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Retrieve authenticated user from payload
+	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || payload == nil {
+		return unauthorized()
+	}
+
+	// 3. Fetch the key and check ownership; other users' keys look like missing ones
+	apiKey, err := server.store.GetAPIKey(c.UserContext(), req.ID)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return internalError(err)
+	}
+	if err != nil || apiKey.Owner != payload.Username {
+		return newAPIError(fiber.StatusNotFound, errCodeAPIKeyNotFound, "api key not found")
+	}
+
+	// 4. Revoke it
+	_, err = server.store.DeleteAPIKeyTx(c.UserContext(), apiKey.ID, func(apiKey db.ApiKey) db.AppendAuditEventTxParams {
+		return auditEvent(c, payload.Username, auditAPIKeyDeleted, auditTarget("api_key", apiKey.ID), newAPIKeyResponse(apiKey), nil)
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(messageResponse{Message: "api key revoked"})
+}
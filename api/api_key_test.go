@@ -0,0 +1,404 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// randomAPIKey creates a fake API key with the given scopes, returning it with its full key
+func randomAPIKey(t *testing.T, owner string, scopes ...string) (db.ApiKey, string) {
+	key, prefix, secret, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	return db.ApiKey{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Name:       util.RandomOwner(),
+		Prefix:     prefix,
+		SecretHash: util.HashSecureToken(secret),
+		Scopes:     scopes,
+		CreatedAt:  time.Now().UTC(),
+	}, key
+}
+
+// addAPIKeyAuthorization authenticates the request with a personal API key
+func addAPIKeyAuthorization(request *http.Request, key string) {
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+}
+
+// ---------------------------
+// TestAPIKeyAuth
+// ---------------------------
+
+func TestAPIKeyAuth(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	apiKey, key := randomAPIKey(t, user.Username, util.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		key           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			method: http.MethodPost,
+			path:   "/transfers",
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeInsufficientScope)
+			},
+		},
+		{
+			name:   "UserOnlyRoute",
+			method: http.MethodPost,
+			path:   "/api_keys",
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "WrongSecret",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			key:    fmt.Sprintf("sbk_%s_wrongsecret", apiKey.Prefix),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(apiKey, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "Expired",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiresAt = sql.NullTime{Time: time.Now().UTC().Add(-time.Minute), Valid: true}
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).Times(1).Return(expired, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "UnknownKey",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "MalformedKey",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			key:    "not-a-key",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			key:    key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, tc.method, tc.path, fiber.Map{}, func(request *http.Request) {
+				addAPIKeyAuthorization(request, tc.key)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestCreateAPIKeyAPI
+// ---------------------------
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		body          fiber.Map
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: fiber.Map{
+				"name":       "payroll",
+				"scopes":     []string{util.ScopeAccountsRead, util.ScopeTransfersWrite},
+				"expires_at": expiresAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams, _ db.APIKeyAudit) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, "payroll", arg.Name)
+						require.Equal(t, []string{util.ScopeAccountsRead, util.ScopeTransfersWrite}, arg.Scopes)
+						require.True(t, arg.ExpiresAt.Valid)
+						require.True(t, expiresAt.Equal(arg.ExpiresAt.Time))
+						require.Len(t, arg.SecretHash, 64)
+						return db.ApiKey{
+							ID:         1,
+							Owner:      arg.Owner,
+							Name:       arg.Name,
+							Prefix:     arg.Prefix,
+							SecretHash: arg.SecretHash,
+							Scopes:     arg.Scopes,
+							ExpiresAt:  arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				prefix, _, err := util.ParseAPIKey(resp.Key)
+				require.NoError(t, err)
+				require.Equal(t, resp.Prefix, prefix)
+				require.NotContains(t, recorder.Body.String(), "secret_hash")
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: fiber.Map{"name": "payroll", "scopes": []string{"everything"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: fiber.Map{"name": "payroll", "scopes": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiryInThePast",
+			body: fiber.Map{
+				"name":       "payroll",
+				"scopes":     []string{util.ScopeAccountsRead},
+				"expires_at": time.Now().Add(-time.Hour),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: fiber.Map{"name": "payroll", "scopes": []string{util.ScopeAccountsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodPost, "/api_keys", tc.body, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestListAPIKeysAPI
+// ---------------------------
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, _ := randomAPIKey(t, user.Username, util.ScopeAccountsRead)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAPIKeys(gomock.Any(), gomock.Eq(db.ListAPIKeysParams{Owner: user.Username, Limit: 5, Offset: 0})).
+		Times(1).
+		Return([]db.ApiKey{apiKey}, nil)
+	stubAuthUser(store)
+
+	server := newFiberTestServer(t, store)
+	recorder := doJSONRequest(t, server, http.MethodGet, "/api_keys?page_id=1&page_size=5", nil, func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	})
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp []apiKeyResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+	require.Equal(t, apiKey.Prefix, resp[0].Prefix)
+	require.Empty(t, resp[0].Key)
+}
+
+// ---------------------------
+// TestDeleteAPIKeyAPI
+// ---------------------------
+
+func TestDeleteAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, _ := randomAPIKey(t, user.Username, util.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(apiKey, nil)
+				store.EXPECT().DeleteAPIKeyTx(gomock.Any(), gomock.Eq(apiKey.ID), gomock.Any()).Times(1).Return(apiKey, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherUsersKey",
+			username: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(apiKey, nil)
+				store.EXPECT().DeleteAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+				store.EXPECT().DeleteAPIKeyTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodDelete, fmt.Sprintf("/api_keys/%d", apiKey.ID), nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
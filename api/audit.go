@@ -0,0 +1,330 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/logging"    // Request IDs
+)
+
+// Audited actions, named <what>.<what happened>
+const (
+	auditUserLogin           = "user.login"
+	auditUserLoginFailed     = "user.login_failed"
+	auditUserUpdated         = "user.updated"
+	auditUserPasswordChanged = "user.password_changed"
+	auditUserPasswordReset   = "user.password_reset"
+	auditUserDeleted         = "user.deleted"
+	auditUserUnlocked        = "user.unlocked"
+	auditUserImpersonated    = "user.impersonated"
+	auditMFAEnabled          = "mfa.enabled"
+	auditMFAStepUpFailed     = "mfa.step_up_failed"
+	auditAPIKeyCreated       = "api_key.created"
+	auditAPIKeyDeleted       = "api_key.deleted"
+	auditAccountCreated      = "account.created"
+	auditAccountClosed       = "account.closed"
+	auditTransferCreated     = "transfer.created"
+)
+
+// auditExportBatch is how many events an export reads at a time
+const auditExportBatch = 500
+
+// ---------------------------
+// Request Structs
+// ---------------------------
+
+// auditEventFilter holds the query parameters narrowing down audit events
+type auditEventFilter struct {
+	Actor  string `query:"actor" validate:"omitempty,max=64"`
+	Action string `query:"action" validate:"omitempty,max=64"`
+	Target string `query:"target" validate:"omitempty,max=128"`
+	From   string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // RFC 3339, inclusive
+	To     string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`   // RFC 3339, exclusive
+}
+
+// listAuditEventsRequest represents query parameters for paginated audit event listings
+type listAuditEventsRequest struct {
+	auditEventFilter
+	PageID   int `query:"page_id" validate:"required,min=1"`          // Current page number, min 1
+	PageSize int `query:"page_size" validate:"required,min=5,max=10"` // Items per page, 5–10
+}
+
+// exportAuditEventsRequest represents query parameters for an audit event export
+type exportAuditEventsRequest struct {
+	auditEventFilter
+	Format string `query:"format" validate:"omitempty,oneof=ndjson csv"` // ndjson (default) or csv
+}
+
+// auditEventResponse represents an audit event returned by the API
+// @Description Audit event
+type auditEventResponse struct {
+	ID           int64           `json:"id"`
+	Actor        string          `json:"actor"`        // Empty for anonymous requests such as failed logins
+	Impersonator string          `json:"impersonator"` // Banker acting as the actor, if any
+	Action       string          `json:"action"`       // e.g. transfer.created
+	Target       string          `json:"target"`       // e.g. account:42
+	ClientIP     string          `json:"client_ip"`
+	RequestID    string          `json:"request_id"`
+	Before       json.RawMessage `json:"before" swaggertype:"object"`
+	After        json.RawMessage `json:"after" swaggertype:"object"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// newAuditEventResponse converts a db.AuditEvent
+func newAuditEventResponse(event db.AuditEvent) auditEventResponse {
+	return auditEventResponse{
+		ID:           event.ID,
+		Actor:        event.Actor,
+		Impersonator: event.Impersonator,
+		Action:       event.Action,
+		Target:       event.Target,
+		ClientIP:     event.ClientIp,
+		RequestID:    event.RequestID,
+		Before:       event.Before,
+		After:        event.After,
+		PrevHash:     event.PrevHash,
+		Hash:         event.Hash,
+		CreatedAt:    event.CreatedAt,
+	}
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// listAuditEvents handles GET /audit_events endpoint
+
+// ListAuditEvents godoc
+// @Summary      List audit events
+// @Description  Lists audit events, newest first, optionally filtered by actor, action, target and time range. Bankers only.
+// @Tags         Audit
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        actor      query     string  false  "Username of the actor"
+// @Param        action     query     string  false  "Action, e.g. transfer.created"
+// @Param        target     query     string  false  "Target, e.g. account:42"
+// @Param        from       query     string  false  "Earliest time (RFC 3339, inclusive)"
+// @Param        to         query     string  false  "Latest time (RFC 3339, exclusive)"
+// @Param        page_id    query     int     true   "Page number (min 1)"
+// @Param        page_size  query     int     true   "Page size (5–10)"
+// @Success      200        {array}   auditEventResponse
+// @Failure      400        {object}  problemResponse
+// @Failure      401        {object}  problemResponse
+// @Failure      403        {object}  problemResponse
+// @Failure      500        {object}  problemResponse
+// @Router       /v1/audit_events [get]
+func (server *Server) listAuditEvents(c *fiber.Ctx) error {
+	// 1. Parse and validate query parameters
+	var req listAuditEventsRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+	filter, err := req.params()
+	if err != nil {
+		return err
+	}
+
+	// 2. Fetch the page
+	events, err := server.store.ListAuditEvents(c.UserContext(), db.ListAuditEventsParams{
+		Actor:    filter.Actor,
+		Action:   filter.Action,
+		Target:   filter.Target,
+		FromTime: filter.FromTime,
+		ToTime:   filter.ToTime,
+		Limit:    int64(req.PageSize),
+		Offset:   int64((req.PageID - 1) * req.PageSize),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := make([]auditEventResponse, 0, len(events))
+	for _, event := range events {
+		resp = append(resp, newAuditEventResponse(event))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// exportAuditEvents handles GET /audit_events/export endpoint
+
+// ExportAuditEvents godoc
+// @Summary      Export audit events
+// @Description  Downloads every matching audit event, oldest first, as newline-delimited JSON or CSV, hashes included. An unfiltered export can be checked offline by recomputing the hash chain. Bankers only.
+// @Tags         Audit
+// @Produce      plain
+// @Security     ApiKeyAuth
+// @Param        actor   query     string  false  "Username of the actor"
+// @Param        action  query     string  false  "Action, e.g. transfer.created"
+// @Param        target  query     string  false  "Target, e.g. account:42"
+// @Param        from    query     string  false  "Earliest time (RFC 3339, inclusive)"
+// @Param        to      query     string  false  "Latest time (RFC 3339, exclusive)"
+// @Param        format  query     string  false  "ndjson (default) or csv"
+// @Success      200     {string}  string
+// @Failure      400     {object}  problemResponse
+// @Failure      401     {object}  problemResponse
+// @Failure      403     {object}  problemResponse
+// @Failure      500     {object}  problemResponse
+// @Router       /v1/audit_events/export [get]
+func (server *Server) exportAuditEvents(c *fiber.Ctx) error {
+	// 1. Parse and validate query parameters
+	var req exportAuditEventsRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+	arg, err := req.params()
+	if err != nil {
+		return err
+	}
+
+	// 2. Write the events batch by batch, in chain order
+	var w *csv.Writer
+	if req.Format == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="audit_events.csv"`)
+		w = csv.NewWriter(c)
+		_ = w.Write([]string{"id", "created_at", "actor", "impersonator", "action", "target", "client_ip", "request_id", "before", "after", "prev_hash", "hash"})
+	} else {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="audit_events.ndjson"`)
+	}
+
+	arg.Limit = auditExportBatch
+	for {
+		events, err := server.store.ListAuditEventsAfter(c.UserContext(), arg)
+		if err != nil {
+			c.Response().Header.Del(fiber.HeaderContentDisposition)
+			c.Response().ResetBody()
+			return internalError(err)
+		}
+
+		for _, event := range events {
+			if w != nil {
+				_ = w.Write([]string{
+					strconv.FormatInt(event.ID, 10),
+					event.CreatedAt.UTC().Format(time.RFC3339Nano),
+					event.Actor,
+					event.Impersonator,
+					event.Action,
+					event.Target,
+					event.ClientIp,
+					event.RequestID,
+					string(event.Before),
+					string(event.After),
+					event.PrevHash,
+					event.Hash,
+				})
+				continue
+			}
+
+			line, err := json.Marshal(newAuditEventResponse(event))
+			if err != nil {
+				return internalError(err)
+			}
+			_, _ = c.Write(append(line, '\n'))
+		}
+
+		if len(events) < auditExportBatch {
+			break
+		}
+		arg.AfterID = events[len(events)-1].ID
+	}
+
+	if w != nil {
+		w.Flush()
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// verifyAuditChain handles GET /audit_events/verify endpoint
+
+// VerifyAuditChain godoc
+// @Summary      Verify the audit log
+// @Description  Recomputes the audit log's hash chain and reports the first event that was altered or whose predecessor was removed. Bankers only.
+// @Tags         Audit
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  db.AuditChainStatus
+// @Failure      401  {object}  problemResponse
+// @Failure      403  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/audit_events/verify [get]
+func (server *Server) verifyAuditChain(c *fiber.Ctx) error {
+	status, err := server.store.VerifyAuditChain(c.UserContext())
+	if err != nil {
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(status)
+}
+
+// ---------------------------
+// Helper Functions
+// ---------------------------
+
+// auditEvent describes an action for the audit log. actor is the user who did it, empty
+// when unknown; a banker impersonating them is recorded alongside. Changes hand it to
+// their transaction, which appends it before committing.
+func auditEvent(c *fiber.Ctx, actor, action, target string, before, after any) db.AppendAuditEventTxParams {
+	arg := db.AppendAuditEventTxParams{
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		ClientIP:  clientIP(c),
+		RequestID: logging.RequestID(c.UserContext()),
+		Before:    before,
+		After:     after,
+	}
+	if impersonator, ok := c.Locals(authorizationActorKey).(db.User); ok {
+		arg.Impersonator = impersonator.Username
+	}
+	return arg
+}
+
+// audit appends an event that goes with no change of its own, such as a login, to the
+// audit log. An event that can't be recorded fails the request with a 500.
+func (server *Server) audit(c *fiber.Ctx, actor, action, target string, before, after any) error {
+	if _, err := server.store.AppendAuditEventTx(c.UserContext(), auditEvent(c, actor, action, target, before, after)); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+// auditTarget names the object of an audit event, e.g. account:42
+func auditTarget(kind string, id any) string {
+	return fmt.Sprintf("%s:%v", kind, id)
+}
+
+// params converts the filter to query arguments; empty fields don't filter
+func (filter auditEventFilter) params() (db.ListAuditEventsAfterParams, error) {
+	arg := db.ListAuditEventsAfterParams{
+		Actor:  sql.NullString{String: filter.Actor, Valid: filter.Actor != ""},
+		Action: sql.NullString{String: filter.Action, Valid: filter.Action != ""},
+		Target: sql.NullString{String: filter.Target, Valid: filter.Target != ""},
+	}
+
+	// created_at is stored in UTC
+	for _, bound := range []struct {
+		field string
+		value string
+		dst   *sql.NullTime
+	}{{"from", filter.From, &arg.FromTime}, {"to", filter.To, &arg.ToTime}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return arg, invalidField(bound.field, "datetime", "must be an RFC 3339 timestamp")
+		}
+		*bound.dst = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+
+	return arg, nil
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAudit accepts any audit event; specific expectations set before it take precedence
+func stubAudit(store *mockdb.MockStore) {
+	store.EXPECT().
+		AppendAuditEventTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.AuditEvent{}, nil)
+}
+
+// eqAuditEventMatcher matches an audit event by who did what to what
+type eqAuditEventMatcher struct {
+	actor, action, target string
+}
+
+func (e eqAuditEventMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.AppendAuditEventTxParams)
+	return ok && arg.Actor == e.actor && arg.Action == e.action && arg.Target == e.target
+}
+
+func (e eqAuditEventMatcher) String() string {
+	return fmt.Sprintf("audit event %s by %q on %s", e.action, e.actor, e.target)
+}
+
+// expectAudit expects exactly one audit event with the given actor, action and target
+func expectAudit(store *mockdb.MockStore, actor, action, target string) {
+	store.EXPECT().
+		AppendAuditEventTx(gomock.Any(), eqAuditEventMatcher{actor, action, target}).
+		Times(1).
+		Return(db.AuditEvent{}, nil)
+}
+
+// eqAuditFuncMatcher matches the audit func handed to a transaction by the event it
+// builds from the transaction's result
+type eqAuditFuncMatcher struct {
+	result any
+	event  eqAuditEventMatcher
+}
+
+func (e eqAuditFuncMatcher) Matches(x interface{}) bool {
+	fn := reflect.ValueOf(x)
+	if fn.Kind() != reflect.Func || fn.IsNil() || fn.Type().NumIn() != 1 {
+		return false
+	}
+	return e.event.Matches(fn.Call([]reflect.Value{reflect.ValueOf(e.result)})[0].Interface())
+}
+
+func (e eqAuditFuncMatcher) String() string {
+	return "audit func building " + e.event.String()
+}
+
+// eqAuditFunc matches an audit func that builds the given event from result
+func eqAuditFunc(result any, actor, action, target string) gomock.Matcher {
+	return eqAuditFuncMatcher{result, eqAuditEventMatcher{actor, action, target}}
+}
+
+func randomAuditEvent(actor string) db.AuditEvent {
+	return db.AuditEvent{
+		ID:        util.RandomInt(1, 1000),
+		Actor:     actor,
+		Action:    auditTransferCreated,
+		Target:    auditTarget("transfer", util.RandomInt(1, 1000)),
+		ClientIp:  "203.0.113.7",
+		Before:    json.RawMessage(`{}`),
+		After:     json.RawMessage(`{"amount":10}`),
+		PrevHash:  util.RandomString(64),
+		Hash:      util.RandomString(64),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// ---------------------------
+// TestListAuditEventsAPI
+// ---------------------------
+
+func TestListAuditEventsAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	event := randomAuditEvent(util.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		role          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			role:  util.BankerRole,
+			query: "page_id=2&page_size=5&actor=" + event.Actor + "&from=2024-01-01T00:00:00%2B02:00",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditEvents(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+						require.Equal(t, event.Actor, arg.Actor.String)
+						require.True(t, arg.Actor.Valid)
+						require.False(t, arg.Action.Valid)
+						require.False(t, arg.ToTime.Valid)
+						require.Equal(t, time.Date(2023, 12, 31, 22, 0, 0, 0, time.UTC), arg.FromTime.Time)
+						require.Equal(t, int64(5), arg.Limit)
+						require.Equal(t, int64(5), arg.Offset)
+						return []db.AuditEvent{event}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var events []db.AuditEvent
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &events))
+				require.Len(t, events, 1)
+				require.Equal(t, event.Hash, events[0].Hash)
+			},
+		},
+		{
+			name:  "NotBanker",
+			role:  util.DepositorRole,
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidTime",
+			role:  util.BankerRole,
+			query: "page_id=1&page_size=5&to=yesterday",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			role:  util.BankerRole,
+			query: "page_id=1&page_size=50",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthRole(store, tc.role)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodGet, "/audit_events?"+tc.query, nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, banker, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestExportAuditEventsAPI
+// ---------------------------
+
+func TestExportAuditEventsAPI(t *testing.T) {
+	banker := util.RandomOwner()
+
+	// More events than one batch, so the export has to page through them
+	events := make([]db.AuditEvent, auditExportBatch+1)
+	for i := range events {
+		events[i] = randomAuditEvent(util.RandomOwner())
+		events[i].ID = int64(i + 1)
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "NDJSON",
+			query: "",
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+				require.Len(t, lines, len(events))
+				var last db.AuditEvent
+				require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+				require.Equal(t, events[len(events)-1].Hash, last.Hash)
+			},
+		},
+		{
+			name:  "CSV",
+			query: "?format=csv",
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				records, err := csv.NewReader(recorder.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, len(events)+1)
+				require.Equal(t, "hash", records[0][11])
+				require.Equal(t, events[0].Hash, records[1][11])
+				require.Equal(t, `{"amount":10}`, records[1][9])
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			gomock.InOrder(
+				store.EXPECT().
+					ListAuditEventsAfter(gomock.Any(), gomock.Eq(db.ListAuditEventsAfterParams{AfterID: 0, Limit: auditExportBatch})).
+					Times(1).
+					Return(events[:auditExportBatch], nil),
+				store.EXPECT().
+					ListAuditEventsAfter(gomock.Any(), gomock.Eq(db.ListAuditEventsAfterParams{AfterID: auditExportBatch, Limit: auditExportBatch})).
+					Times(1).
+					Return(events[auditExportBatch:], nil),
+			)
+			stubAuthRole(store, util.BankerRole)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodGet, "/audit_events/export"+tc.query, nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, banker, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestVerifyAuditChainAPI
+// ---------------------------
+
+func TestVerifyAuditChainAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	status := db.AuditChainStatus{Valid: false, Checked: 41, BrokenAt: 42, LastHash: util.RandomString(64)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().VerifyAuditChain(gomock.Any()).Times(1).Return(status, nil)
+	stubAuthRole(store, util.BankerRole)
+
+	server := newFiberTestServer(t, store)
+	recorder := doJSONRequest(t, server, http.MethodGet, "/audit_events/verify", nil, func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, banker, time.Minute)
+	})
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp db.AuditChainStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, status, resp)
+}
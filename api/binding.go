@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// requestSource is a set of the request parts a request struct is bound from
+type requestSource uint8
+
+const (
+	fromParams requestSource = 1 << iota // Path parameters, `params` tags
+	fromQuery                            // Query string, `query` tags
+	fromBody                             // JSON or form body, `json` or `form` tags
+)
+
+// requestSources caches the sources of each request struct type
+var requestSources sync.Map // reflect.Type → requestSource
+
+// bind fills req, a pointer to a request struct, from the request parts its field tags
+// name, then validates it against its `validate` tags. Parse and validation failures
+// come back as 400 problems listing the invalid fields.
+func (server *Server) bind(c *fiber.Ctx, req interface{}) error {
+	sources := sourcesOf(reflect.TypeOf(req))
+
+	if sources&fromParams != 0 {
+		if err := c.ParamsParser(req); err != nil {
+			return invalidRequest(err)
+		}
+	}
+	if sources&fromQuery != 0 {
+		if err := c.QueryParser(req); err != nil {
+			return invalidRequest(err)
+		}
+	}
+	if sources&fromBody != 0 {
+		if err := c.BodyParser(req); err != nil {
+			return invalidRequest(err)
+		}
+	}
+
+	if err := server.validate.Struct(req); err != nil {
+		return invalidRequest(err)
+	}
+	return nil
+}
+
+// sourcesOf returns the request parts the struct behind t declares fields for
+func sourcesOf(t reflect.Type) requestSource {
+	if cached, ok := requestSources.Load(t); ok {
+		return cached.(requestSource)
+	}
+
+	var sources requestSource
+	structType := t
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if _, ok := field.Tag.Lookup("params"); ok {
+			sources |= fromParams
+		}
+		if _, ok := field.Tag.Lookup("query"); ok {
+			sources |= fromQuery
+		}
+		if _, ok := field.Tag.Lookup("json"); ok {
+			sources |= fromBody
+		}
+		if _, ok := field.Tag.Lookup("form"); ok {
+			sources |= fromBody
+		}
+	}
+
+	requestSources.Store(t, sources)
+	return sources
+}
+
+// parseErrorFields lists the fields a parse error blames on values of the wrong type,
+// or returns nil when the request couldn't be parsed at all
+func parseErrorFields(err error) []fieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []fieldError{{Field: typeErr.Field, Rule: "type", Message: typeMessage(typeErr.Type)}}
+	}
+
+	var multiErr fiber.MultiError
+	if !errors.As(err, &multiErr) {
+		return nil
+	}
+	fields := make([]fieldError, 0, len(multiErr))
+	for key, keyErr := range multiErr {
+		message := "is invalid"
+		var conversionErr fiber.ConversionError
+		if errors.As(keyErr, &conversionErr) {
+			message = typeMessage(conversionErr.Type)
+		}
+		fields = append(fields, fieldError{Field: key, Rule: "type", Message: message})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// typeMessage explains what kind of value a field of type t takes
+func typeMessage(t reflect.Type) string {
+	if t == nil {
+		return "is invalid"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "must be an integer"
+	case reflect.Float32, reflect.Float64:
+		return "must be a number"
+	case reflect.Bool:
+		return "must be true or false"
+	case reflect.String:
+		return "must be a string"
+	case reflect.Slice, reflect.Array:
+		return "must be a list"
+	}
+	return "is invalid"
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+func TestBind(t *testing.T) {
+	owner := util.RandomOwner()
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   fiber.Map
+		auth   bool
+		fields []fieldError
+	}{
+		{
+			name:   "UserFields",
+			method: http.MethodPost,
+			path:   "/users",
+			body:   fiber.Map{"username": "no spaces", "password": "secret123", "email": "not-an-email"},
+			fields: []fieldError{
+				{Field: "username", Rule: "username", Message: "must be 3 to 64 letters and digits"},
+				{Field: "full_name", Rule: "required", Message: "is required"},
+				{Field: "email", Rule: "email", Message: "must be a valid email address"},
+			},
+		},
+		{
+			name:   "SignUpUsername",
+			method: http.MethodPost,
+			path:   "/users",
+			body:   fiber.Map{"username": "ab", "password": "secret123", "full_name": "A B", "email": "ab@example.com"},
+			fields: []fieldError{{Field: "username", Rule: "username", Message: "must be 3 to 64 letters and digits"}},
+		},
+		{
+			name:   "LoginUsernameTooLong",
+			method: http.MethodPost,
+			path:   "/users/login",
+			body:   fiber.Map{"username": strings.Repeat("a", 65), "password": "secret123"},
+			fields: []fieldError{{Field: "username", Rule: "max", Message: "must be at most 64 characters long"}},
+		},
+		{
+			name:   "TransferAmountTooLarge",
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   fiber.Map{"from_account_id": 1, "to_account_id": 2, "amount": util.MaxAmount + 1, "currency": util.USD},
+			auth:   true,
+			fields: []fieldError{{Field: "amount", Rule: "amount", Message: fmt.Sprintf("must be greater than 0 and at most %d", util.MaxAmount)}},
+		},
+		{
+			name:   "TransferCurrency",
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   fiber.Map{"from_account_id": 1, "to_account_id": 2, "amount": -5, "currency": "XYZ"},
+			auth:   true,
+			fields: []fieldError{
+				{Field: "amount", Rule: "amount", Message: fmt.Sprintf("must be greater than 0 and at most %d", util.MaxAmount)},
+				{Field: "currency", Rule: "currency", Message: "must be a supported currency"},
+			},
+		},
+		{
+			name:   "BodyWrongType",
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   fiber.Map{"from_account_id": 1, "to_account_id": 2, "amount": "ten", "currency": util.USD},
+			auth:   true,
+			fields: []fieldError{{Field: "amount", Rule: "type", Message: "must be an integer"}},
+		},
+		{
+			name:   "QueryWrongType",
+			method: http.MethodGet,
+			path:   "/accounts?page_id=first&page_size=5",
+			auth:   true,
+			fields: []fieldError{{Field: "page_id", Rule: "type", Message: "must be an integer"}},
+		},
+		{
+			name:   "PathParam",
+			method: http.MethodGet,
+			path:   "/accounts/0",
+			auth:   true,
+			fields: []fieldError{{Field: "id", Rule: "required", Message: "is required"}},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Nothing may reach the database
+			store := mockdb.NewMockStore(ctrl)
+			stubAuthUser(store)
+			server := newFiberTestServer(t, store)
+
+			var setupAuth func(*http.Request)
+			if tc.auth {
+				setupAuth = func(req *http.Request) {
+					addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, owner, time.Minute)
+				}
+			}
+
+			resp := doJSONRequest(t, server, tc.method, tc.path, tc.body, setupAuth)
+			problem := requireProblem(t, resp, http.StatusBadRequest, errCodeValidationFailed)
+			require.Equal(t, tc.fields, problem.Errors)
+		})
+	}
+}
+
+func TestSourcesOf(t *testing.T) {
+	require.Equal(t, fromBody, sourcesOf(reflect.TypeOf(&transferRequest{})))
+	require.Equal(t, fromQuery, sourcesOf(reflect.TypeOf(&listAccountRequest{})))
+	require.Equal(t, fromParams, sourcesOf(reflect.TypeOf(&getAccountRequest{})))
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util" // Configuration
+)
+
+// clientIPKey is the Fiber locals key of the address of the client that made a request
+const clientIPKey = "client_ip"
+
+// defaultProxyHeader is where trusted proxies report the client address when
+// PROXY_HEADER is not set
+const defaultProxyHeader = fiber.HeaderXForwardedFor
+
+// trustedProxies are the load balancers and reverse proxies allowed to tell the API the
+// address of the client they forward a request for
+type trustedProxies struct {
+	networks []netip.Prefix // Addresses of the proxies, single IPs as /32 or /128
+	header   string         // Header they report the client address in
+}
+
+// newTrustedProxies parses TRUSTED_PROXIES, a comma-separated list of IPs and CIDRs.
+// With none, every request is attributed to the address it came from.
+func newTrustedProxies(config util.Config) (trustedProxies, error) {
+	proxies := trustedProxies{header: config.ProxyHeader}
+	if proxies.header == "" {
+		proxies.header = defaultProxyHeader
+	}
+
+	for _, entry := range strings.Split(config.TrustedProxies, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return trustedProxies{}, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			network = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		proxies.networks = append(proxies.networks, network.Masked())
+	}
+	return proxies, nil
+}
+
+// list returns the proxies as Fiber's TrustedProxies setting
+func (proxies trustedProxies) list() []string {
+	list := make([]string, 0, len(proxies.networks))
+	for _, network := range proxies.networks {
+		list = append(list, network.String())
+	}
+	return list
+}
+
+// trusts reports whether addr belongs to a trusted proxy
+func (proxies trustedProxies) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, network := range proxies.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP finds the address of the client behind any trusted proxies. Proxies append
+// the address they received a request from to X-Forwarded-For, so the header is read
+// from the right, skipping trusted hops: anything left of the first untrusted address
+// was written by the client and can't be believed. Fiber's own c.IP() takes the
+// leftmost address instead, which lets clients pick their IP.
+func (proxies trustedProxies) clientIP(c *fiber.Ctx) string {
+	remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok || !proxies.trusts(remote) {
+		return c.Context().RemoteIP().String()
+	}
+
+	var hops []string
+	for _, value := range c.Request().Header.PeekAll(proxies.header) {
+		hops = append(hops, strings.Split(string(value), ",")...)
+	}
+
+	client := remote.Unmap()
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !proxies.trusts(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// clientAddress returns the middleware recording the client address of every request
+// for clientIP
+func clientAddress(proxies trustedProxies) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(clientIPKey, proxies.clientIP(c))
+		return c.Next()
+	}
+}
+
+// clientIP returns the address of the client that made the request, as seen past any
+// trusted proxies. Login lockouts, rate limits, logs and the audit trail all key on it.
+func clientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(clientIPKey).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
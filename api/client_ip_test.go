@@ -0,0 +1,172 @@
+package api
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// testRemoteIP is the address requests sent with app.Test come from
+const testRemoteIP = "0.0.0.0"
+
+func TestNewTrustedProxies(t *testing.T) {
+	proxies, err := newTrustedProxies(util.Config{TrustedProxies: "10.0.0.0/8, 192.168.1.5,,2001:db8::/32"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/8", "192.168.1.5/32", "2001:db8::/32"}, proxies.list())
+	require.Equal(t, fiber.HeaderXForwardedFor, proxies.header)
+
+	proxies, err = newTrustedProxies(util.Config{ProxyHeader: "X-Real-IP"})
+	require.NoError(t, err)
+	require.Empty(t, proxies.list())
+	require.Equal(t, "X-Real-IP", proxies.header)
+
+	_, err = newTrustedProxies(util.Config{TrustedProxies: "10.0.0.0/33"})
+	require.Error(t, err)
+	_, err = newTrustedProxies(util.Config{TrustedProxies: "load-balancer"})
+	require.Error(t, err)
+}
+
+func TestClientIP(t *testing.T) {
+	testCases := []struct {
+		name      string
+		trusted   string
+		header    string
+		forwarded []string
+		want      string
+	}{
+		{
+			name:      "NoTrustedProxies",
+			forwarded: []string{"203.0.113.7"},
+			want:      testRemoteIP,
+		},
+		{
+			name:      "UntrustedPeer",
+			trusted:   "10.0.0.0/8",
+			forwarded: []string{"203.0.113.7"},
+			want:      testRemoteIP,
+		},
+		{
+			name:      "TrustedProxy",
+			trusted:   testRemoteIP,
+			forwarded: []string{"203.0.113.7"},
+			want:      "203.0.113.7",
+		},
+		{
+			name:      "SpoofedHopsAreIgnored",
+			trusted:   testRemoteIP,
+			forwarded: []string{"127.0.0.1, 198.51.100.1, 203.0.113.7"},
+			want:      "203.0.113.7",
+		},
+		{
+			name:      "ChainOfTrustedProxies",
+			trusted:   testRemoteIP + ",10.0.0.0/8",
+			forwarded: []string{"198.51.100.1, 203.0.113.7, 10.1.2.3"},
+			want:      "203.0.113.7",
+		},
+		{
+			name:      "SeveralHeaderLines",
+			trusted:   testRemoteIP,
+			forwarded: []string{"198.51.100.1", "203.0.113.7"},
+			want:      "203.0.113.7",
+		},
+		{
+			name:      "InvalidHop",
+			trusted:   testRemoteIP,
+			forwarded: []string{"203.0.113.7, not-an-ip"},
+			want:      testRemoteIP,
+		},
+		{
+			name:      "OtherHeader",
+			trusted:   testRemoteIP,
+			header:    "X-Real-IP",
+			forwarded: []string{"203.0.113.7"},
+			want:      "203.0.113.7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxies, err := newTrustedProxies(util.Config{TrustedProxies: tc.trusted, ProxyHeader: tc.header})
+			require.NoError(t, err)
+
+			app := fiber.New()
+			app.Use(clientAddress(proxies))
+			app.Get("/", func(c *fiber.Ctx) error {
+				return c.SendString(clientIP(c))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, value := range tc.forwarded {
+				req.Header.Add(proxies.header, value)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(body))
+		})
+	}
+}
+
+func TestLoginLockoutBehindProxy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The lockout applies to the client the load balancer forwards for, not the balancer
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetLoginIPFailure(gomock.Any(), gomock.Eq("203.0.113.7")).
+		Times(1).
+		Return(db.LoginIpFailure{LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}}, nil)
+	store.EXPECT().
+		GetLoginIPFailure(gomock.Any(), gomock.Eq("198.51.100.1")).
+		Times(1).
+		Return(db.LoginIpFailure{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.User{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.RecordLoginIPFailureParams) (db.LoginIpFailure, error) {
+			require.Equal(t, "198.51.100.1", arg.Ip)
+			return db.LoginIpFailure{Ip: arg.Ip, FailedAttempts: 1}, nil
+		})
+	store.EXPECT().
+		AppendAuditEventTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.AppendAuditEventTxParams) (db.AuditEvent, error) {
+			require.Equal(t, "198.51.100.1", arg.ClientIP)
+			return db.AuditEvent{}, nil
+		})
+
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		TrustedProxies:    testRemoteIP,
+	}, store)
+	require.NoError(t, err)
+
+	login := fiber.Map{"username": util.RandomOwner(), "password": "secret123"}
+	resp := doJSONRequest(t, server, fiber.MethodPost, "/users/login", login, func(req *http.Request) {
+		req.Header.Set(fiber.HeaderXForwardedFor, "203.0.113.7")
+	})
+	requireProblem(t, resp, http.StatusTooManyRequests, errCodeTooManyLoginAttempts)
+
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/users/login", login, func(req *http.Request) {
+		req.Header.Set(fiber.HeaderXForwardedFor, "198.51.100.1")
+	})
+	requireProblem(t, resp, http.StatusUnauthorized, errCodeInvalidCredentials)
+}
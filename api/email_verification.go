@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Utility functions (e.g., token hashing)
+)
+
+// ---------------------------
+// Request Structs
+// ---------------------------
+
+// verifyEmailRequest represents query parameters of the link in the verification email
+type verifyEmailRequest struct {
+	Token string `query:"token" validate:"required"`
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// verifyEmail handles GET /users/verify_email endpoint
+
+// VerifyEmail godoc
+// @Summary      Verify an email address
+// @Description  Confirms the user's email address with the token from the verification email
+// @Tags         Users
+// @Produce      json
+// @Param        token  query     string  true  "Verification token"
+// @Success      200    {object}  userResponse
+// @Failure      400    {object}  problemResponse
+// @Failure      500    {object}  problemResponse
+// @Router       /v1/users/verify_email [get]
+func (server *Server) verifyEmail(c *fiber.Ctx) error {
+	// 1. Parse and validate the query parameters
+	var req verifyEmailRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Consume the token and mark the email verified
+	user, err := server.store.VerifyEmailTx(c.UserContext(), util.HashSecureToken(req.Token))
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidVerificationToken) {
+			return newAPIError(fiber.StatusBadRequest, errCodeInvalidLink, err.Error())
+		}
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserResponse(user))
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/worker" // Background job states
+)
+
+// readinessTimeout bounds the database checks of a readiness probe
+const readinessTimeout = 2 * time.Second
+
+// Check outcomes reported by /readyz
+const (
+	checkOK    = "ok"
+	checkError = "error"
+)
+
+// ---------------------------
+// Response Structs
+// ---------------------------
+
+// healthResponse is the liveness probe's answer
+// @Description Liveness status
+type healthResponse struct {
+	Status string `json:"status"` // Always "ok" while the process serves requests
+}
+
+// checkResult is the outcome of one readiness check
+type checkResult struct {
+	Status string `json:"status"`          // ok or error
+	Error  string `json:"error,omitempty"` // Why the check failed; the cause itself is only logged
+}
+
+// migrationCheckResult reports the database schema version
+type migrationCheckResult struct {
+	checkResult
+	Version int64 `json:"version"`
+	Dirty   bool  `json:"dirty"` // The last migration failed halfway
+}
+
+// workerCheckResult reports the background jobs
+type workerCheckResult struct {
+	checkResult
+	Workers []worker.State `json:"workers"`
+}
+
+// readyResponse is the readiness probe's answer
+// @Description Readiness status and the checks behind it
+type readyResponse struct {
+	Status     string               `json:"status"` // ready, not_ready or shutting_down
+	Database   checkResult          `json:"database"`
+	Migrations migrationCheckResult `json:"migrations"`
+	Workers    workerCheckResult    `json:"workers"`
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// healthz handles GET /healthz endpoint
+
+// Healthz godoc
+// @Summary      Liveness probe
+// @Description  Answers 200 while the process is able to serve requests. It checks no dependencies, so a database outage doesn't get the process restarted.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  healthResponse
+// @Router       /healthz [get]
+func (server *Server) healthz(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(healthResponse{Status: checkOK})
+}
+
+// readyz handles GET /readyz endpoint
+
+// Readyz godoc
+// @Summary      Readiness probe
+// @Description  Answers 200 when the database answers, its migrations applied cleanly and every background worker is running; 503 otherwise, and from the moment a shutdown begins.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  readyResponse
+// @Failure      503  {object}  readyResponse
+// @Router       /readyz [get]
+func (server *Server) readyz(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), readinessTimeout)
+	defer cancel()
+
+	resp := readyResponse{Status: "ready"}
+
+	// 1. The database answers
+	resp.Database.Status = checkOK
+	if err := server.store.Ping(ctx); err != nil {
+		slog.ErrorContext(ctx, "readiness check failed", "check", "database", "error", err)
+		resp.Database = checkResult{Status: checkError, Error: "database is unreachable"}
+	}
+
+	// 2. The schema was migrated, and the last migration didn't fail halfway
+	resp.Migrations.Status = checkOK
+	version, dirty, err := server.store.MigrationVersion(ctx)
+	switch {
+	case err != nil:
+		slog.ErrorContext(ctx, "readiness check failed", "check", "migrations", "error", err)
+		resp.Migrations.checkResult = checkResult{Status: checkError, Error: "cannot read the migration version"}
+	case dirty:
+		resp.Migrations.checkResult = checkResult{Status: checkError, Error: "last migration failed; fix it and force the version"}
+	}
+	resp.Migrations.Version, resp.Migrations.Dirty = version, dirty
+
+	// 3. Every background worker is still running; panic messages were logged when they
+	// happened and stay out of the response
+	resp.Workers.Status = checkOK
+	resp.Workers.Workers = []worker.State{}
+	if server.workers != nil {
+		resp.Workers.Workers = server.workers.States()
+	}
+	for i := range resp.Workers.Workers {
+		resp.Workers.Workers[i].Error = ""
+	}
+	for _, state := range resp.Workers.Workers {
+		if !state.Running {
+			resp.Workers.checkResult = checkResult{Status: checkError, Error: state.Name + " is not running"}
+			break
+		}
+	}
+
+	// 4. Sum up; a draining server takes no new traffic whatever its checks say
+	if resp.Database.Status != checkOK || resp.Migrations.Status != checkOK || resp.Workers.Status != checkOK {
+		resp.Status = "not_ready"
+	}
+	if server.draining.Load() {
+		resp.Status = "shutting_down"
+	}
+	if resp.Status != "ready" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(resp)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
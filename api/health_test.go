@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/worker"
+	"github.com/stretchr/testify/require"
+)
+
+// ---------------------------
+// TestHealthzAPI
+// ---------------------------
+
+func TestHealthzAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Liveness doesn't touch the database
+	store := mockdb.NewMockStore(ctrl)
+	server := newFiberTestServer(t, store)
+
+	recorder := doJSONRequest(t, server, http.MethodGet, "/healthz", nil, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
+
+// ---------------------------
+// TestReadyzAPI
+// ---------------------------
+
+func TestReadyzAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		setupServer   func(server *Server)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Ready",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(14), false, nil)
+			},
+			setupServer: func(server *Server) {
+				workers := worker.NewGroup()
+				ctx, cancel := context.WithCancel(context.Background())
+				t.Cleanup(cancel)
+				workers.Go(ctx, "relay", func(ctx context.Context) { <-ctx.Done() })
+				server.SetWorkers(workers)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				resp := decodeReadyResponse(t, recorder)
+				require.Equal(t, "ready", resp.Status)
+				require.Equal(t, int64(14), resp.Migrations.Version)
+				require.Len(t, resp.Workers.Workers, 1)
+				require.True(t, resp.Workers.Workers[0].Running)
+			},
+		},
+		{
+			name: "DatabaseDown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(errors.New("connection refused"))
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(0), false, errors.New("connection refused"))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+				resp := decodeReadyResponse(t, recorder)
+				require.Equal(t, "not_ready", resp.Status)
+				require.Equal(t, checkError, resp.Database.Status)
+				require.Equal(t, "database is unreachable", resp.Database.Error)
+				require.Equal(t, "cannot read the migration version", resp.Migrations.Error)
+				require.NotContains(t, recorder.Body.String(), "connection refused")
+			},
+		},
+		{
+			name: "NoMigrations",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(0), false, db.ErrNoMigrations)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Equal(t, checkError, decodeReadyResponse(t, recorder).Migrations.Status)
+			},
+		},
+		{
+			name: "DirtyMigration",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(14), true, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+				resp := decodeReadyResponse(t, recorder)
+				require.Equal(t, checkError, resp.Migrations.Status)
+				require.True(t, resp.Migrations.Dirty)
+			},
+		},
+		{
+			name: "WorkerStopped",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(14), false, nil)
+			},
+			setupServer: func(server *Server) {
+				workers := worker.NewGroup()
+				workers.Go(context.Background(), "relay", func(context.Context) { panic("boom") })
+				require.NoError(t, workers.Wait(context.Background()))
+				server.SetWorkers(workers)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+				resp := decodeReadyResponse(t, recorder)
+				require.Equal(t, checkError, resp.Workers.Status)
+				require.Equal(t, "relay is not running", resp.Workers.Error)
+				require.Empty(t, resp.Workers.Workers[0].Error)
+				require.NotContains(t, recorder.Body.String(), "boom")
+			},
+		},
+		{
+			name: "ShuttingDown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(14), false, nil)
+			},
+			setupServer: func(server *Server) {
+				server.draining.Store(true)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Equal(t, "shutting_down", decodeReadyResponse(t, recorder).Status)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newFiberTestServer(t, store)
+			if tc.setupServer != nil {
+				tc.setupServer(server)
+			}
+
+			recorder := doJSONRequest(t, server, http.MethodGet, "/readyz", nil, nil)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func decodeReadyResponse(t *testing.T, recorder *httptest.ResponseRecorder) readyResponse {
+	var resp readyResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	return resp
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Roles
+)
+
+// defaultImpersonationDuration is how long an act-as token lasts when IMPERSONATION_TOKEN_DURATION is not configured
+const defaultImpersonationDuration = 15 * time.Minute
+
+// ---------------------------
+// Request and Response Structs
+// ---------------------------
+
+// impersonateUserPathRequest defines the path parameter for the user to act as
+type impersonateUserPathRequest struct {
+	Username string `params:"username" validate:"required,max=64"`
+}
+
+// impersonateUserRequest represents the expected JSON body for starting an impersonation
+// @Description Impersonation request payload
+type impersonateUserRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"` // Why the customer's view is needed, e.g. a ticket number
+}
+
+// impersonateUserResponse carries the act-as token
+// @Description Impersonation token
+type impersonateUserResponse struct {
+	AccessToken string       `json:"access_token"` // Use as a Bearer token; acts as the user
+	ExpiresAt   time.Time    `json:"expires_at"`
+	Actor       string       `json:"actor"` // The banker the token was issued to
+	User        userResponse `json:"user"`  // The impersonated user
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// impersonateUser handles POST /users/:username/impersonate endpoint
+
+// ImpersonateUser godoc
+// @Summary      Act as a user
+// @Description  Issues a short-lived token that acts as the user, so support staff see exactly what they see. Requests made with it are logged with the banker as the actor. Credential management is refused with it, and so are transfers and account closures unless IMPERSONATION_MONEY_MOVEMENT is set. Bankers only; other bankers can't be impersonated.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        username  path      string                  true  "Username"
+// @Param        request   body      impersonateUserRequest  true  "Reason for the impersonation"
+// @Success      200       {object}  impersonateUserResponse
+// @Failure      400       {object}  problemResponse
+// @Failure      401       {object}  problemResponse
+// @Failure      403       {object}  problemResponse
+// @Failure      404       {object}  problemResponse
+// @Failure      500       {object}  problemResponse
+// @Router       /v1/users/{username}/impersonate [post]
+func (server *Server) impersonateUser(c *fiber.Ctx) error {
+	// 1. Parse and validate the path and body
+	var pathReq impersonateUserPathRequest
+	if err := server.bind(c, &pathReq); err != nil {
+		return err
+	}
+	var req impersonateUserRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	actor, ok := c.Locals(authorizationUserKey).(db.User)
+	if !ok {
+		return unauthorized()
+	}
+
+	// 2. Load the user to act as; bankers can't act as each other
+	subject, err := server.store.GetUser(c.UserContext(), pathReq.Username)
+	if err == nil && subject.DeletedAt.Valid {
+		err = db.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusNotFound, errCodeUserNotFound, "user not found")
+		}
+		return internalError(err)
+	}
+	if subject.Role == util.BankerRole {
+		return newAPIError(fiber.StatusForbidden, errCodeForbidden, "bankers can't be impersonated")
+	}
+
+	// 3. Issue the act-as token
+	duration := server.config.ActAsDuration
+	if duration <= 0 {
+		duration = defaultImpersonationDuration
+	}
+	accessToken, payload, err := server.tokenMaker.CreateImpersonationToken(actor.Username, subject.Username, duration)
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 4. Record who got it, for whom and why; no record, no token
+	_, err = server.store.CreateImpersonation(c.UserContext(), db.CreateImpersonationParams{
+		TokenID:   payload.ID,
+		Actor:     actor.Username,
+		Subject:   subject.Username,
+		Reason:    req.Reason,
+		ExpiresAt: payload.ExpiredAt.UTC(),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	slog.InfoContext(c.UserContext(), "impersonation started", "actor", actor.Username, "subject", subject.Username, "token_id", payload.ID, "reason", req.Reason)
+	err = server.audit(c, actor.Username, auditUserImpersonated, auditTarget("user", subject.Username), nil, fiber.Map{
+		"reason":     req.Reason,
+		"token_id":   payload.ID,
+		"expires_at": payload.ExpiredAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(impersonateUserResponse{
+		AccessToken: accessToken,
+		ExpiresAt:   payload.ExpiredAt,
+		Actor:       actor.Username,
+		User:        newUserResponse(subject),
+	})
+}
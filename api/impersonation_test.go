@@ -0,0 +1,327 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// addImpersonation authenticates the request with a token letting actor act as subject
+func addImpersonation(t *testing.T, request *http.Request, tokenMaker token.Maker, actor, subject string) {
+	accessToken, _, err := tokenMaker.CreateImpersonationToken(actor, subject, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+}
+
+// ---------------------------
+// TestImpersonateUserAPI
+// ---------------------------
+
+func TestImpersonateUserAPI(t *testing.T) {
+	banker := db.User{Username: util.RandomOwner(), Role: util.BankerRole, IsEmailVerified: true}
+	customer, _ := randomUser(t)
+	customer.Role = util.DepositorRole
+
+	testCases := []struct {
+		name          string
+		actor         db.User
+		username      string
+		body          fiber.Map
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			actor:    banker,
+			username: customer.Username,
+			body:     fiber.Map{"reason": "ticket #4242"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(customer, nil)
+				store.EXPECT().
+					CreateImpersonation(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateImpersonationParams) (db.Impersonation, error) {
+						require.Equal(t, banker.Username, arg.Actor)
+						require.Equal(t, customer.Username, arg.Subject)
+						require.Equal(t, "ticket #4242", arg.Reason)
+						require.WithinDuration(t, time.Now().UTC().Add(defaultImpersonationDuration), arg.ExpiresAt, time.Second)
+						return db.Impersonation{TokenID: arg.TokenID, Actor: arg.Actor, Subject: arg.Subject}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp impersonateUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, banker.Username, resp.Actor)
+				require.Equal(t, customer.Username, resp.User.Username)
+
+				payload, err := server.tokenMaker.VerifyToken(resp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, token.TypeImpersonation, payload.Type)
+				require.Equal(t, banker.Username, payload.Actor)
+				require.Equal(t, customer.Username, payload.Username)
+			},
+		},
+		{
+			name:     "NotBanker",
+			actor:    db.User{Username: util.RandomOwner(), Role: util.DepositorRole},
+			username: customer.Username,
+			body:     fiber.Map{"reason": "ticket #4242"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateImpersonation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "SubjectIsBanker",
+			actor:    banker,
+			username: customer.Username,
+			body:     fiber.Map{"reason": "ticket #4242"},
+			buildStubs: func(store *mockdb.MockStore) {
+				otherBanker := customer
+				otherBanker.Role = util.BankerRole
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(otherBanker, nil)
+				store.EXPECT().CreateImpersonation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			actor:    banker,
+			username: customer.Username,
+			body:     fiber.Map{"reason": "ticket #4242"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateImpersonation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "MissingReason",
+			actor:    banker,
+			username: customer.Username,
+			body:     fiber.Map{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateImpersonation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "RecordError",
+			actor:    banker,
+			username: customer.Username,
+			body:     fiber.Map{"reason": "ticket #4242"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(customer, nil)
+				store.EXPECT().CreateImpersonation(gomock.Any(), gomock.Any()).Times(1).Return(db.Impersonation{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthAs(store, tc.actor)
+
+			server := newFiberTestServer(t, store)
+			path := fmt.Sprintf("/users/%s/impersonate", tc.username)
+			recorder := doJSONRequest(t, server, http.MethodPost, path, tc.body, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.actor.Username, time.Minute)
+			})
+
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestImpersonationAuth
+// ---------------------------
+
+func TestImpersonationAuth(t *testing.T) {
+	banker := db.User{Username: util.RandomOwner(), Role: util.BankerRole}
+	customer, _ := randomUser(t)
+	customer.IsEmailVerified = true
+	account := randomAccount(customer.Username)
+
+	testCases := []struct {
+		name          string
+		config        util.Config
+		method        string
+		path          string
+		body          fiber.Map
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ActsAsSubject",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAuthAs(store, banker)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:   "TransferBlocked",
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   fiber.Map{"from_account_id": account.ID, "to_account_id": account.ID + 1, "amount": 10, "currency": account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAuthAs(store, banker)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeActAsForbidden)
+			},
+		},
+		{
+			name:   "CloseAccountBlocked",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAuthAs(store, banker)
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "TransferAllowed",
+			config: util.Config{ActAsMoneyMovement: true},
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   fiber.Map{"amount": -1},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAuthAs(store, banker)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// Past the impersonation check, into the handler's own validation
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CredentialsBlocked",
+			method: http.MethodPut,
+			path:   "/users/me/password",
+			body:   fiber.Map{"old_password": "secret123", "new_password": "newsecret123"},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAuthAs(store, banker)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeActAsForbidden)
+			},
+		},
+		{
+			name:   "WebhookBlocked",
+			method: http.MethodPost,
+			path:   "/webhooks",
+			body:   fiber.Map{"url": "https://attacker.example/hooks", "event_types": []string{db.EventTransferCreated}},
+			buildStubs: func(store *mockdb.MockStore) {
+				stubAuthAs(store, banker)
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeActAsForbidden)
+			},
+		},
+		{
+			name:   "ActorDemoted",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				demoted := banker
+				demoted.Role = util.DepositorRole
+				stubAuthAs(store, demoted)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ActorNotFound",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(banker.Username)).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(banker.Username)).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthAs(store, customer)
+
+			tc.config.TokenSymmetricKey = util.RandomString(32)
+			server, err := NewServer(tc.config, store)
+			require.NoError(t, err)
+
+			recorder := doJSONRequest(t, server, tc.method, tc.path, tc.body, func(request *http.Request) {
+				addImpersonation(t, request, server.tokenMaker, banker.Username, customer.Username)
+			})
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Lockout policy and password hashing
+)
+
+// Defaults applied when the login lockout settings are not configured
+const (
+	defaultLoginMaxAttempts   = 5
+	defaultLoginIPMaxAttempts = 20
+	defaultLoginLockoutBase   = time.Minute
+	defaultLoginLockoutMax    = time.Hour
+
+	// loginIPWindow is how long an IP's failure count lasts without new failures
+	loginIPWindow = 24 * time.Hour
+)
+
+// errInvalidCredentials is the only error a failed login reveals, whether the
+// username is unknown, the password wrong or the account locked
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// ---------------------------
+// Request Structs
+// ---------------------------
+
+// unlockUserRequest defines the path parameter for unlocking a user
+type unlockUserRequest struct {
+	Username string `params:"username" validate:"required,max=64"`
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// unlockUser handles POST /users/:username/unlock endpoint
+
+// UnlockUser godoc
+// @Summary      Unlock a user
+// @Description  Clears a user's failed login attempts and lockout. Bankers only.
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        username  path      string  true  "Username"
+// @Success      200       {object}  userResponse
+// @Failure      401       {object}  problemResponse
+// @Failure      403       {object}  problemResponse
+// @Failure      404       {object}  problemResponse
+// @Failure      500       {object}  problemResponse
+// @Router       /v1/users/{username}/unlock [post]
+func (server *Server) unlockUser(c *fiber.Ctx) error {
+	// 1. Parse and validate the path parameter
+	var req unlockUserRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Reset the failure counter and lock
+	banker, _ := c.Locals(authorizationUserKey).(db.User)
+	user, err := server.store.UnlockUserTx(c.UserContext(), req.Username, func(user db.User) db.AppendAuditEventTxParams {
+		return auditEvent(c, banker.Username, auditUserUnlocked, auditTarget("user", user.Username), nil, newUserResponse(user))
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusNotFound, errCodeUserNotFound, "user not found")
+		}
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserResponse(user))
+}
+
+// ---------------------------
+// Helper Functions
+// ---------------------------
+
+// checkLoginIP rejects requests from an IP that is locked out after too many failed
+// logins with a 429, setting Retry-After
+func (server *Server) checkLoginIP(c *fiber.Ctx) error {
+	failure, err := server.store.GetLoginIPFailure(c.UserContext(), clientIP(c))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil
+		}
+		return internalError(err)
+	}
+
+	if !isLocked(failure.LockedUntil) {
+		return nil
+	}
+
+	setRetryAfter(c, failure.LockedUntil.Time)
+	return newAPIError(fiber.StatusTooManyRequests, errCodeTooManyLoginAttempts, "too many failed login attempts, try again later")
+}
+
+// failLogin records a failed login for username against the client IP and, when known,
+// the user, locking either out once it exceeds its allowance, then writes the uniform 401
+func (server *Server) failLogin(c *fiber.Ctx, username string, user *db.User) error {
+	// 1. Count the failure against the IP and the user
+	outcome, err := server.recordLoginFailure(c, user)
+	if err != nil {
+		return err
+	}
+
+	// 2. Record it; a signed-in user re-entering their password is the actor
+	actor := ""
+	if current, ok := c.Locals(authorizationUserKey).(db.User); ok {
+		actor = current.Username
+	}
+	if err := server.audit(c, actor, auditUserLoginFailed, auditTarget("user", username), nil, outcome); err != nil {
+		return err
+	}
+
+	return newAPIError(fiber.StatusUnauthorized, errCodeInvalidCredentials, errInvalidCredentials.Error())
+}
+
+// recordLoginFailure counts a failed password or second-factor check against the client
+// IP and, when known, the user, locking either out once it exceeds its allowance. It
+// returns what happened, for the audit log.
+func (server *Server) recordLoginFailure(c *fiber.Ctx, user *db.User) (fiber.Map, error) {
+	base, max := server.loginLockout()
+	outcome := fiber.Map{"known_user": user != nil, "ip_locked": false, "user_locked": false}
+
+	// 1. Count the failure against the IP
+	failure, err := server.store.RecordLoginIPFailure(c.UserContext(), db.RecordLoginIPFailureParams{
+		Ip:          clientIP(c),
+		WindowStart: time.Now().UTC().Add(-loginIPWindow),
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	if lockout := util.LockoutDuration(failure.FailedAttempts, server.loginIPMaxAttempts(), base, max); lockout > 0 {
+		err := server.store.LockLoginIP(c.UserContext(), db.LockLoginIPParams{
+			Ip:          failure.Ip,
+			LockedUntil: sql.NullTime{Time: time.Now().UTC().Add(lockout), Valid: true},
+		})
+		if err != nil {
+			return nil, internalError(err)
+		}
+		outcome["ip_locked"] = true
+	}
+
+	// 2. Count the failure against the user
+	if user != nil {
+		attempts, err := server.store.RecordUserLoginFailure(c.UserContext(), user.Username)
+		if err != nil {
+			return nil, internalError(err)
+		}
+
+		if lockout := util.LockoutDuration(attempts, server.loginMaxAttempts(), base, max); lockout > 0 {
+			err := server.store.LockUser(c.UserContext(), db.LockUserParams{
+				Username:    user.Username,
+				LockedUntil: sql.NullTime{Time: time.Now().UTC().Add(lockout), Valid: true},
+			})
+			if err != nil {
+				return nil, internalError(err)
+			}
+			outcome["user_locked"] = true
+		}
+	}
+
+	return outcome, nil
+}
+
+// clearLoginFailures resets the user's failure counter after a complete login
+func (server *Server) clearLoginFailures(c *fiber.Ctx, user db.User) (db.User, error) {
+	if user.FailedLoginAttempts == 0 && !user.LockedUntil.Valid {
+		return user, nil
+	}
+	return server.store.UnlockUser(c.UserContext(), user.Username)
+}
+
+// setRetryAfter tells the client when a lockout (stored in UTC) ends
+func setRetryAfter(c *fiber.Ctx, lockedUntil time.Time) {
+	retryAfter := time.Until(lockedUntil)
+	c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// isLocked reports whether a lockout (stored in UTC) is still in force
+func isLocked(lockedUntil sql.NullTime) bool {
+	return lockedUntil.Valid && time.Now().UTC().Before(lockedUntil.Time)
+}
+
+// loginMaxAttempts is how many consecutive failures a user gets before lockout
+func (server *Server) loginMaxAttempts() int32 {
+	if server.config.LoginMaxAttempts <= 0 {
+		return defaultLoginMaxAttempts
+	}
+	return server.config.LoginMaxAttempts
+}
+
+// loginIPMaxAttempts is how many failures an IP gets within a day before lockout
+func (server *Server) loginIPMaxAttempts() int32 {
+	if server.config.LoginIPMaxAttempts <= 0 {
+		return defaultLoginIPMaxAttempts
+	}
+	return server.config.LoginIPMaxAttempts
+}
+
+// loginLockout returns the first and the longest lockout durations
+func (server *Server) loginLockout() (time.Duration, time.Duration) {
+	base, max := server.config.LoginLockoutBase, server.config.LoginLockoutMax
+	if base <= 0 {
+		base = defaultLoginLockoutBase
+	}
+	if max <= 0 {
+		max = defaultLoginLockoutMax
+	}
+	return base, max
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLoginIPAllowed lets every client IP attempt a login
+func stubLoginIPAllowed(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetLoginIPFailure(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.LoginIpFailure{}, db.ErrRecordNotFound)
+}
+
+// stubAuthRole lets the auth middleware find any token's user with the given role
+func stubAuthRole(store *mockdb.MockStore, role string) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, Role: role, IsEmailVerified: true}, nil
+		})
+}
+
+// ---------------------------
+// TestUnlockUserAPI
+// ---------------------------
+
+func TestUnlockUserAPI(t *testing.T) {
+	banker := util.RandomOwner()
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		role          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			role:     util.BankerRole,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UnlockUserTx(gomock.Any(), gomock.Eq(user.Username), eqAuditFunc(user, banker, auditUserUnlocked, "user:"+user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, user.Username, resp.Username)
+			},
+		},
+		{
+			name:     "NotBanker",
+			role:     util.DepositorRole,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UnlockUserTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			role:     util.BankerRole,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UnlockUserTx(gomock.Any(), gomock.Eq(user.Username), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "LegacyUsername",
+			role:     util.BankerRole,
+			username: "legacy.user",
+			buildStubs: func(store *mockdb.MockStore) {
+				// Names made before the sign-up rule can still be looked up
+				store.EXPECT().
+					UnlockUserTx(gomock.Any(), gomock.Eq("legacy.user"), gomock.Any()).
+					Times(1).
+					Return(db.User{Username: "legacy.user"}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidUsername",
+			role:     util.BankerRole,
+			username: strings.Repeat("a", 65),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UnlockUserTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthRole(store, tc.role)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodPost, fmt.Sprintf("/users/%s/unlock", tc.username), nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, banker, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests no route handled, so scanners probing random paths
+// can't blow up the number of series
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "simplebank",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "simplebank",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// httpMetrics returns a middleware counting requests and timing them per route. It is
+// registered ahead of requestLogger, so errors have already become a response status.
+func httpMetrics() fiber.Handler {
+	routeOf := newRouteResolver()
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		route := routeOf(c)
+		method := c.Method()
+		status := strconv.Itoa(c.Response().StatusCode())
+		httpRequestsTotal.WithLabelValues(method, route, status).Inc()
+		httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// newRouteResolver returns a function giving the pattern of the route that handled a
+// request (/accounts/:id rather than /accounts/42), or unmatchedRoute. Call it after
+// c.Next(): when no route matched, c.Route() is the last middleware that ran.
+func newRouteResolver() func(c *fiber.Ctx) string {
+	// Routes are all registered before the first request arrives
+	var once sync.Once
+	var routes map[string]bool
+
+	return func(c *fiber.Ctx) string {
+		once.Do(func() {
+			routes = make(map[string]bool)
+			for _, route := range c.App().GetRoutes(true) {
+				routes[route.Method+" "+route.Path] = true
+			}
+		})
+
+		if route := c.Route(); routes[route.Method+" "+route.Path] {
+			return route.Path
+		}
+		return unmatchedRoute
+	}
+}
+
+// NewMetricsServer returns an HTTP server exposing the Prometheus metrics at /metrics.
+// It is meant to listen on an address reachable only by the monitoring system.
+func NewMetricsServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// ---------------------------
+// TestHTTPMetrics
+// ---------------------------
+
+func TestHTTPMetrics(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+	stubAuthUser(store)
+
+	server := newFiberTestServer(t, store)
+
+	// Requests are counted under their route pattern with the status actually sent
+	notFound := httpRequestsTotal.WithLabelValues(http.MethodGet, "/accounts/:id", "404")
+	before := testutil.ToFloat64(notFound)
+	doJSONRequest(t, server, http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil, func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	})
+	require.Equal(t, before+1, testutil.ToFloat64(notFound))
+
+	// Paths matching no route share one label
+	unmatched := httpRequestsTotal.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	before = testutil.ToFloat64(unmatched)
+	doJSONRequest(t, server, http.MethodGet, "/no/such/route", nil, func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	})
+	require.Equal(t, before+1, testutil.ToFloat64(unmatched))
+}
+
+// ---------------------------
+// TestMetricsServer
+// ---------------------------
+
+func TestMetricsServer(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewMetricsServer("127.0.0.1:0").Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(recorder.Body.String(), "go_goroutines"))
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"      // Token handling (JWT/Paseto)
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // TOTP and token hashing
+)
+
+// recoveryCodeCount is how many recovery codes are issued when 2FA is enabled
+const recoveryCodeCount = 10
+
+// Defaults applied when the MFA settings are not configured
+const (
+	defaultMFAIssuer        = "Simple Bank"
+	defaultMFATokenDuration = 5 * time.Minute
+)
+
+// ---------------------------
+// Request and Response Structs
+// ---------------------------
+
+// enrollTOTPResponse carries the secret to load into an authenticator app
+// @Description Pending TOTP enrollment
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`      // Base32 secret, for manual entry
+	OtpauthURI string `json:"otpauth_uri"` // otpauth:// URI, usually rendered as a QR code
+}
+
+// confirmTOTPRequest represents the expected JSON body for confirming a TOTP enrollment
+// @Description TOTP confirmation payload
+type confirmTOTPRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"` // Current code from the authenticator app
+}
+
+// confirmTOTPResponse returns the recovery codes, which are only shown once
+// @Description Enabled two-factor authentication
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaChallengeResponse is returned by login instead of an access token when 2FA is enabled
+// @Description Two-factor challenge
+type mfaChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"` // Pass to POST /users/login/mfa with a code
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// loginMFARequest represents the expected JSON body for the second login step
+// @Description Second login step payload
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"` // TOTP code or a recovery code
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// enrollTOTP handles POST /users/mfa/totp endpoint
+
+// EnrollTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generates a TOTP secret for the authenticated user. Two-factor authentication is only enabled once a code is confirmed.
+// @Tags         MFA
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  enrollTOTPResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      409  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/users/mfa/totp [post]
+func (server *Server) enrollTOTP(c *fiber.Ctx) error {
+	// 1. Retrieve authenticated user from payload
+	authPayload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || authPayload == nil {
+		return unauthorized()
+	}
+
+	// 2. Generate a secret and store it as a pending enrollment
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return internalError(err)
+	}
+
+	_, err = server.store.UpsertPendingUserMFA(c.UserContext(), db.UpsertPendingUserMFAParams{
+		Username:   authPayload.Username,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		// An enabled enrollment is never overwritten
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusConflict, errCodeMFAAlreadyEnabled, "two-factor authentication is already enabled")
+		}
+		return internalError(err)
+	}
+
+	// 3. Return the secret and provisioning URI
+	return c.Status(fiber.StatusOK).JSON(enrollTOTPResponse{
+		Secret:     secret,
+		OtpauthURI: util.TOTPURI(server.mfaIssuer(), authPayload.Username, secret),
+	})
+}
+
+// confirmTOTP handles POST /users/mfa/totp/confirm endpoint
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Enables two-factor authentication with a code from the authenticator app and returns single-use recovery codes
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request  body      confirmTOTPRequest  true  "Current TOTP code"
+// @Success      200      {object}  confirmTOTPResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      401      {object}  problemResponse
+// @Failure      409      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/users/mfa/totp/confirm [post]
+func (server *Server) confirmTOTP(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req confirmTOTPRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Load the pending enrollment
+	authPayload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || authPayload == nil {
+		return unauthorized()
+	}
+	mfa, err := server.store.GetUserMFA(c.UserContext(), authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusConflict, errCodeMFANotPending, db.ErrMFANotPending.Error())
+		}
+		return internalError(err)
+	}
+	if mfa.IsEnabled {
+		return newAPIError(fiber.StatusConflict, errCodeMFAAlreadyEnabled, "two-factor authentication is already enabled")
+	}
+
+	// 3. Check the code proves the secret was loaded
+	step, ok := util.ValidateTOTP(mfa.TotpSecret, req.Code, time.Now())
+	if !ok {
+		return newAPIError(fiber.StatusBadRequest, errCodeInvalidMFACode, "invalid two-factor code")
+	}
+
+	// 4. Generate recovery codes and store only their hashes
+	recoveryCodes, err := util.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return internalError(err)
+	}
+
+	hashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = util.HashSecureToken(code)
+	}
+
+	// 5. Enable 2FA
+	_, err = server.store.EnableMFATx(c.UserContext(), db.EnableMFATxParams{
+		Username:           authPayload.Username,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	}, func(db.UserMfa) db.AppendAuditEventTxParams {
+		return auditEvent(c, authPayload.Username, auditMFAEnabled, auditTarget("user", authPayload.Username), nil, fiber.Map{"method": "totp"})
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrMFANotPending) {
+			return newAPIError(fiber.StatusConflict, errCodeMFANotPending, err.Error())
+		}
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(confirmTOTPResponse{RecoveryCodes: recoveryCodes})
+}
+
+// loginMFA handles POST /users/login/mfa endpoint
+
+// LoginMFA godoc
+// @Summary      Complete a two-factor login
+// @Description  Exchanges the MFA challenge token from POST /users/login and a TOTP or recovery code for an access token
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      loginMFARequest  true  "Challenge token and code"
+// @Success      200      {object}  loginUserResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      401      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/users/login/mfa [post]
+func (server *Server) loginMFA(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req loginMFARequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Verify the challenge token
+	payload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil || payload.Type != token.TypeMFAChallenge {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "invalid or expired MFA token")
+	}
+
+	// 3. Refuse clients locked out after too many failures
+	if err := server.checkLoginIP(c); err != nil {
+		return err
+	}
+
+	// 4. Load the user; a password change, lockout or deletion since the first step voids the challenge
+	user, err := server.store.GetUser(c.UserContext(), payload.Username)
+	if err != nil {
+		return internalError(err)
+	}
+	if payload.IssuedAt.Before(user.PasswordChangedAt) || isLocked(user.LockedUntil) || user.DeletedAt.Valid {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "invalid or expired MFA token")
+	}
+
+	// 5. Check the second factor; wrong codes count as failed logins
+	mfa, err := server.store.GetUserMFA(c.UserContext(), payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "invalid or expired MFA token")
+		}
+		return internalError(err)
+	}
+
+	ok, err := server.verifyMFACode(c.UserContext(), mfa, req.Code)
+	if err != nil {
+		return internalError(err)
+	}
+	if !ok {
+		return server.failLogin(c, user.Username, &user)
+	}
+
+	// 6. The login succeeded: forget earlier failures
+	if user, err = server.clearLoginFailures(c, user); err != nil {
+		return internalError(err)
+	}
+
+	// 7. Issue the access token
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	if err != nil {
+		return internalError(err)
+	}
+	if err := server.audit(c, user.Username, auditUserLogin, auditTarget("user", user.Username), nil, fiber.Map{"method": "password+mfa"}); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(loginUserResponse{
+		AccessToken: accessToken,
+		User:        newUserResponse(user),
+	})
+}
+
+// ---------------------------
+// Helper Functions
+// ---------------------------
+
+// mfaChallenge writes the MFA challenge response for a user who passed the password step
+func (server *Server) mfaChallenge(c *fiber.Ctx, username string) error {
+	duration := server.config.MFATokenDuration
+	if duration <= 0 {
+		duration = defaultMFATokenDuration
+	}
+
+	mfaToken, payload, err := server.tokenMaker.CreateTypedToken(username, token.TypeMFAChallenge, duration)
+	if err != nil {
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(mfaChallengeResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresAt:   payload.ExpiredAt,
+	})
+}
+
+// verifyMFACode accepts a current TOTP code or an unused recovery code. Both are
+// consumed, so the same code can't be replayed.
+func (server *Server) verifyMFACode(ctx context.Context, mfa db.UserMfa, code string) (bool, error) {
+	if !mfa.IsEnabled {
+		return false, nil
+	}
+
+	if step, ok := util.ValidateTOTP(mfa.TotpSecret, code, time.Now()); ok {
+		n, err := server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Username:     mfa.Username,
+			LastUsedStep: step,
+		})
+		return n == 1, err
+	}
+
+	n, err := server.store.UseMFARecoveryCode(ctx, db.UseMFARecoveryCodeParams{
+		Username: mfa.Username,
+		CodeHash: util.HashSecureToken(util.NormalizeRecoveryCode(code)),
+	})
+	return n == 1, err
+}
+
+// requireStepUp checks the second factor for transfers above MFA_STEP_UP_AMOUNT,
+// returning the problem to answer with if it is missing or wrong. Wrong codes count as
+// failed logins, so guessing codes locks the user and client out like at login.
+func (server *Server) requireStepUp(c *fiber.Ctx, username string, amount int64, code string) error {
+	threshold := server.config.MFAStepUpAmount
+	if threshold <= 0 || amount <= threshold {
+		return nil
+	}
+
+	user, ok := c.Locals(authorizationUserKey).(db.User)
+	if !ok {
+		return unauthorized()
+	}
+
+	// 1. Large transfers are only possible with 2FA enabled
+	mfa, err := server.store.GetUserMFA(c.UserContext(), username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return internalError(err)
+	}
+	if err != nil || !mfa.IsEnabled {
+		return newAPIError(fiber.StatusForbidden, errCodeMFAEnrollmentRequired, "two-factor authentication must be enabled for transfers of this amount")
+	}
+
+	// 2. A fresh code must accompany the request
+	if code == "" {
+		return newAPIError(fiber.StatusForbidden, errCodeMFARequired, "a two-factor code is required for transfers of this amount")
+	}
+
+	// 3. Refuse users and clients locked out after too many failures
+	if err := server.checkLoginIP(c); err != nil {
+		return err
+	}
+	if isLocked(user.LockedUntil) {
+		setRetryAfter(c, user.LockedUntil.Time)
+		return newAPIError(fiber.StatusTooManyRequests, errCodeTooManyMFAAttempts, "too many failed two-factor attempts, try again later")
+	}
+
+	// 4. Check the code; a wrong one is counted and audited
+	ok, err = server.verifyMFACode(c.UserContext(), mfa, code)
+	if err != nil {
+		return internalError(err)
+	}
+	if !ok {
+		outcome, err := server.recordLoginFailure(c, &user)
+		if err != nil {
+			return err
+		}
+		if err := server.audit(c, user.Username, auditMFAStepUpFailed, auditTarget("user", user.Username), nil, outcome); err != nil {
+			return err
+		}
+		return newAPIError(fiber.StatusForbidden, errCodeInvalidMFACode, "invalid two-factor code")
+	}
+
+	// 5. The right code forgets earlier failures
+	if _, err := server.clearLoginFailures(c, user); err != nil {
+		return internalError(err)
+	}
+
+	return nil
+}
+
+// mfaIssuer is the issuer name shown in authenticator apps
+func (server *Server) mfaIssuer() string {
+	if server.config.MFAIssuer == "" {
+		return defaultMFAIssuer
+	}
+	return server.config.MFAIssuer
+}
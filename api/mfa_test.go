@@ -0,0 +1,527 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// randomMFA creates a fake TOTP enrollment for testing
+func randomMFA(t *testing.T, username string, enabled bool) db.UserMfa {
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	return db.UserMfa{
+		Username:   username,
+		TotpSecret: secret,
+		IsEnabled:  enabled,
+	}
+}
+
+// currentTOTP returns the code an authenticator app would show right now
+func currentTOTP(t *testing.T, secret string) string {
+	code, err := util.TOTPCode(secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+// doJSONRequest sends body as JSON to the server and records the response
+func doJSONRequest(t *testing.T, server *Server, method, path string, body fiber.Map, setupAuth func(request *http.Request)) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if setupAuth != nil {
+		setupAuth(req)
+	}
+
+	resp, err := server.app.Test(req, -1)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	bodyBytes := new(bytes.Buffer)
+	_, err = bodyBytes.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	recorder.Body = bodyBytes
+	recorder.Code = resp.StatusCode
+	for key, values := range resp.Header {
+		recorder.Header()[key] = values
+	}
+	return recorder
+}
+
+// ---------------------------
+// TestEnrollTOTPAPI
+// ---------------------------
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertPendingUserMFA(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpsertPendingUserMFAParams) (db.UserMfa, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.UserMfa{Username: arg.Username, TotpSecret: arg.TotpSecret}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.Secret)
+				require.Contains(t, resp.OtpauthURI, "secret="+resp.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertPendingUserMFA(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserMfa{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodPost, "/users/mfa/totp", nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestConfirmTOTPAPI
+// ---------------------------
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	pending := randomMFA(t, user.Username, false)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: currentTOTP(t, pending.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.EnableMFATxParams, _ db.MFAAudit) (db.UserMfa, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, util.TOTPStep(time.Now()), arg.Step, 1)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						enabled := pending
+						enabled.IsEnabled = true
+						return enabled, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp confirmTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Len(t, resp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "WrongCode",
+			code: "000000",
+			buildStubs: func(store *mockdb.MockStore) {
+				wrong := pending
+				wrong.TotpSecret = randomMFA(t, user.Username, false).TotpSecret
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(wrong, nil)
+				store.EXPECT().EnableMFATx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoPendingEnrollment",
+			code: "123456",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserMfa{}, db.ErrRecordNotFound)
+				store.EXPECT().EnableMFATx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCodeFormat",
+			code: "12ab",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodPost, "/users/mfa/totp/confirm", fiber.Map{"code": tc.code}, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestLoginMFAAPI
+// ---------------------------
+
+func TestLoginMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	mfa := randomMFA(t, user.Username, true)
+	recoveryCode := "abcde-fghjk"
+
+	testCases := []struct {
+		name          string
+		tokenType     string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			tokenType: token.TypeMFAChallenge,
+			code:      currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UseTOTPStepParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, util.TOTPStep(time.Now()), arg.LastUsedStep, 1)
+						return 1, nil
+					})
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.AccessToken)
+			},
+		},
+		{
+			name:      "RecoveryCode",
+			tokenType: token.TypeMFAChallenge,
+			code:      "ABCDE-FGHJK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().
+					UseMFARecoveryCode(gomock.Any(), gomock.Eq(db.UseMFARecoveryCodeParams{Username: user.Username, CodeHash: util.HashSecureToken(recoveryCode)})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "ReplayedCode",
+			tokenType: token.TypeMFAChallenge,
+			code:      currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().RecordLoginIPFailure(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().RecordUserLoginFailure(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(int32(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "LockedUser",
+			tokenType: token.TypeMFAChallenge,
+			code:      currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				locked := user
+				locked.LockedUntil = sql.NullTime{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(locked, nil)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "AccessTokenIsNotAChallenge",
+			tokenType: token.TypeAccess,
+			code:      currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubLoginIPAllowed(store)
+
+			server := newFiberTestServer(t, store)
+			mfaToken, _, err := server.tokenMaker.CreateTypedToken(user.Username, tc.tokenType, time.Minute)
+			require.NoError(t, err)
+
+			recorder := doJSONRequest(t, server, http.MethodPost, "/users/login/mfa", fiber.Map{
+				"mfa_token": mfaToken,
+				"code":      tc.code,
+			}, nil)
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestTransferStepUpAPI
+// ---------------------------
+
+func TestTransferStepUpAPI(t *testing.T) {
+	const threshold = 1000
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+	mfa := randomMFA(t, user1.Username, true)
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "BelowThreshold",
+			amount: threshold,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ValidCode",
+			amount: threshold + 1,
+			code:   currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingCode",
+			amount: threshold + 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeMFARequired)
+			},
+		},
+		{
+			name:   "WrongCode",
+			amount: threshold + 1,
+			code:   "not-a-code",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseMFARecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().RecordLoginIPFailure(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().RecordUserLoginFailure(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(int32(1), nil)
+				store.EXPECT().LockUser(gomock.Any(), gomock.Any()).Times(0)
+				expectAudit(store, user1.Username, auditMFAStepUpFailed, "user:"+user1.Username)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeInvalidMFACode)
+			},
+		},
+		{
+			name:   "WrongCodeLocksUser",
+			amount: threshold + 1,
+			code:   "not-a-code",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseMFARecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().RecordLoginIPFailure(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().RecordUserLoginFailure(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(int32(defaultLoginMaxAttempts), nil)
+				store.EXPECT().LockUser(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ any, arg db.LockUserParams) error {
+						require.Equal(t, user1.Username, arg.Username)
+						require.True(t, arg.LockedUntil.Time.After(time.Now().UTC()))
+						return nil
+					})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeInvalidMFACode)
+			},
+		},
+		{
+			name:   "LockedUser",
+			amount: threshold + 1,
+			code:   currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				locked := user1
+				locked.IsEmailVerified = true
+				locked.LockedUntil = sql.NullTime{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(locked, nil)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeTooManyMFAAttempts)
+				require.NotEmpty(t, recorder.Header().Get(fiber.HeaderRetryAfter))
+			},
+		},
+		{
+			name:   "ValidCodeClearsFailures",
+			amount: threshold + 1,
+			code:   currentTOTP(t, mfa.TotpSecret),
+			buildStubs: func(store *mockdb.MockStore) {
+				failed := user1
+				failed.IsEmailVerified = true
+				failed.FailedLoginAttempts = 2
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(failed, nil)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(failed, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "NotEnrolled",
+			amount: threshold + 1,
+			code:   "123456",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(db.UserMfa{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeMFAEnrollmentRequired)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).AnyTimes().Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).AnyTimes().Return(account2, nil)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthUser(store)
+			stubLoginIPAllowed(store)
+
+			server := newFiberTestServer(t, store)
+			server.config.MFAStepUpAmount = threshold
+
+			recorder := doJSONRequest(t, server, http.MethodPost, "/transfers", fiber.Map{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+				"mfa_code":        tc.code,
+			}, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
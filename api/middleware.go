@@ -1,65 +1,311 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/logging"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 )
 
 // Constants for authorization header and payload keys
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
+	authorizationUserKey    = "authorization_user"
+	authorizationAPIKeyKey  = "authorization_api_key"
+	authorizationActorKey   = "authorization_actor"
 )
 
-// authMiddlewareFiber returns a Fiber middleware function that validates JWT/Paseto tokens.
-// It ensures requests to protected routes include a valid Authorization header.
-func authMiddlewareFiber(tokenMaker token.Maker) fiber.Handler {
+// maxRequestIDLength bounds the client-supplied X-Request-ID headers that are kept
+const maxRequestIDLength = 128
+
+// requestID returns a Fiber middleware giving every request an ID: the client's
+// X-Request-ID when it is a plain token, a new UUID otherwise. The ID is echoed in the
+// response and carried by c.UserContext(), so logs down to the store layer include it.
+func requestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(fiber.HeaderXRequestID, id)
+		c.Locals(logging.RequestIDKey, id)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+// requestLogger returns a Fiber middleware logging each request once it is handled:
+// server errors at error level, everything else at info
+func requestLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		// Let the error handler write the response, so the logged status is the one sent
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(c.UserContext(), level, "request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"ip", clientIP(c),
+		)
+		return nil
+	}
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// authMiddlewareFiber returns a Fiber middleware function that validates JWT/Paseto tokens
+// and personal API keys. It ensures requests to protected routes include a valid
+// Authorization header ("Bearer <token>" or "ApiKey <key>"), and rejects tokens of deleted
+// users or issued before the user's last password change. A banker's impersonation token
+// authorizes as the impersonated user; each of its requests is logged with the banker.
+func authMiddlewareFiber(tokenMaker token.Maker, store db.Store) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Retrieve the Authorization header from the request
 		authorizationHeader := strings.TrimSpace(c.Get(authorizationHeaderKey))
 		if len(authorizationHeader) == 0 {
 			// No header provided
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "authorization header is missing",
-			})
+			return newAPIError(fiber.StatusUnauthorized, errCodeUnauthorized, "authorization header is missing")
 		}
 
-		// Split the header into type and token value
+		// Split the header into type and credential
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			// Header format is invalid
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid authorization header format",
-			})
+			return newAPIError(fiber.StatusUnauthorized, errCodeUnauthorized, "invalid authorization header format")
 		}
 
 		authType := fields[0]
-		accessToken := fields[1]
+		credential := fields[1]
 
-		// Ensure the auth type is "Bearer" (case-insensitive)
-		if strings.ToLower(authType) != authorizationTypeBearer {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "unsupported authorization type",
-			})
+		// Verify the credential for its scheme (case-insensitive)
+		var payload *token.Payload
+		var apiKey *db.ApiKey
+		var err error
+		switch strings.ToLower(authType) {
+		case authorizationTypeBearer:
+			payload, err = tokenMaker.VerifyToken(credential)
+			if err == nil && payload.Type != token.TypeAccess && payload.Type != token.TypeImpersonation {
+				// MFA challenges and other special-purpose tokens don't grant access
+				err = token.ErrInvalidToken
+			}
+		case authorizationTypeAPIKey:
+			apiKey, payload, err = verifyAPIKey(c, store, credential)
+			if err != nil && !errors.Is(err, token.ErrInvalidToken) && !errors.Is(err, token.ErrExpiredToken) {
+				return internalError(err)
+			}
+		default:
+			return newAPIError(fiber.StatusUnauthorized, errCodeUnauthorized, "unsupported authorization type")
 		}
-
-		// Verify the token
-		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			// Token is invalid or expired
+			// Token or key is invalid or expired
 			// Internal error can be logged instead of sent to client in production
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid or expired token",
-			})
+			return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "invalid or expired token")
+		}
+
+		// Tokens of deleted users, or issued before the last password change (e.g., a reset), are revoked
+		user, err := store.GetUser(c.UserContext(), payload.Username)
+		if err == nil && user.DeletedAt.Valid {
+			err = db.ErrRecordNotFound
+		}
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "invalid or expired token")
+			}
+			return internalError(err)
+		}
+		if apiKey == nil && payload.IssuedAt.Before(user.PasswordChangedAt) {
+			return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "token was issued before the last password change")
 		}
 
-		// Store the verified payload in Fiber locals for handlers to access
+		// An impersonation only lasts while its banker is still a banker in good standing
+		if payload.Type == token.TypeImpersonation {
+			actor, err := verifyActor(c.UserContext(), store, payload)
+			if err != nil {
+				if errors.Is(err, token.ErrInvalidToken) {
+					return newAPIError(fiber.StatusUnauthorized, errCodeInvalidToken, "invalid or expired token")
+				}
+				return internalError(err)
+			}
+			slog.InfoContext(c.UserContext(), "impersonated request", "actor", actor.Username, "subject", user.Username, "method", c.Method(), "path", c.Path())
+			c.Locals(authorizationActorKey, actor)
+		}
+
+		// Record when an API key was last used, so stale keys can be spotted and revoked
+		if apiKey != nil {
+			if err := store.TouchAPIKey(c.UserContext(), apiKey.ID); err != nil {
+				return internalError(err)
+			}
+			c.Locals(authorizationAPIKeyKey, *apiKey)
+		}
+
+		// Store the verified payload and its user in Fiber locals for handlers to access
 		c.Locals(authorizationPayloadKey, payload)
+		c.Locals(authorizationUserKey, user)
 
 		// Call the next middleware or route handler
 		return c.Next()
 	}
 }
+
+// verifyAPIKey looks up a personal API key by its prefix and checks its secret and expiry.
+// It returns the key and a payload standing in for an access token of the key's owner;
+// unknown, wrong and expired keys give token.ErrInvalidToken or token.ErrExpiredToken.
+func verifyAPIKey(c *fiber.Ctx, store db.Store, key string) (*db.ApiKey, *token.Payload, error) {
+	prefix, secret, err := util.ParseAPIKey(key)
+	if err != nil {
+		return nil, nil, token.ErrInvalidToken
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(c.UserContext(), prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, nil, token.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(util.HashSecureToken(secret)), []byte(apiKey.SecretHash)) != 1 {
+		return nil, nil, token.ErrInvalidToken
+	}
+	// expires_at is stored in UTC
+	if apiKey.ExpiresAt.Valid && !time.Now().UTC().Before(apiKey.ExpiresAt.Time) {
+		return nil, nil, token.ErrExpiredToken
+	}
+
+	payload := &token.Payload{
+		Type:      token.TypeAPIKey,
+		Username:  apiKey.Owner,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+	}
+	return &apiKey, payload, nil
+}
+
+// verifyActor loads the banker behind an impersonation token. Tokens of bankers who were
+// deleted, demoted or changed their password since give token.ErrInvalidToken.
+func verifyActor(ctx context.Context, store db.Store, payload *token.Payload) (db.User, error) {
+	actor, err := store.GetUser(ctx, payload.Actor)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.User{}, token.ErrInvalidToken
+		}
+		return db.User{}, err
+	}
+
+	if actor.DeletedAt.Valid || actor.Role != util.BankerRole || payload.IssuedAt.Before(actor.PasswordChangedAt) {
+		return db.User{}, token.ErrInvalidToken
+	}
+	return actor, nil
+}
+
+// requireScope returns a Fiber middleware that lets requests made with an API key through
+// only when the key was granted the scope. Access tokens carry every scope. It must run
+// after authMiddlewareFiber.
+func requireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey, ok := c.Locals(authorizationAPIKeyKey).(db.ApiKey)
+		if !ok || slices.Contains(apiKey.Scopes, scope) {
+			return c.Next()
+		}
+
+		return newAPIError(fiber.StatusForbidden, errCodeInsufficientScope, fmt.Sprintf("api key lacks the %q scope", scope))
+	}
+}
+
+// rejectAPIKeys returns a Fiber middleware for routes only a signed-in user may call
+// (credentials, API key and 2FA management), refusing requests made with an API key.
+// It must run after authMiddlewareFiber.
+func rejectAPIKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals(authorizationAPIKeyKey).(db.ApiKey); ok {
+			return newAPIError(fiber.StatusForbidden, errCodeInsufficientScope, "this route can't be called with an api key")
+		}
+		return c.Next()
+	}
+}
+
+// rejectImpersonation returns a Fiber middleware for routes a banker must not call on a
+// customer's behalf, refusing requests made with an impersonation token. It must run
+// after authMiddlewareFiber.
+func rejectImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals(authorizationActorKey).(db.User); ok {
+			return newAPIError(fiber.StatusForbidden, errCodeActAsForbidden, "this route can't be called while impersonating a user")
+		}
+		return c.Next()
+	}
+}
+
+// requireVerifiedEmail returns a Fiber middleware that only lets users with a verified
+// email address through. It must run after authMiddlewareFiber.
+func requireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals(authorizationUserKey).(db.User)
+		if !ok {
+			return unauthorized()
+		}
+
+		if !user.IsEmailVerified {
+			return newAPIError(fiber.StatusForbidden, errCodeEmailNotVerified, "email address must be verified first")
+		}
+
+		return c.Next()
+	}
+}
+
+// requireRole returns a Fiber middleware that only lets users with one of the given
+// roles through. It must run after authMiddlewareFiber.
+func requireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals(authorizationUserKey).(db.User)
+		if !ok {
+			return unauthorized()
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				return c.Next()
+			}
+		}
+
+		return newAPIError(fiber.StatusForbidden, errCodePermissionDenied, "permission denied")
+	}
+}
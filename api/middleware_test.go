@@ -2,12 +2,20 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/logging"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 	"github.com/stretchr/testify/require"
@@ -30,6 +38,17 @@ func addAuthorization(
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
 }
 
+// stubAuthUser lets the auth middleware find any token's user, verified and with a password
+// that has never been changed
+func stubAuthUser(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
+
 // ---------------------------
 // Tests for Auth Middleware
 // ---------------------------
@@ -40,6 +59,7 @@ func TestAuthMiddlewareFiber(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, req *http.Request, maker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, resp *http.Response)
 	}{
 		{
@@ -47,10 +67,67 @@ func TestAuthMiddlewareFiber(t *testing.T) {
 			setupAuth: func(t *testing.T, req *http.Request, maker token.Maker) {
 				addAuthorization(t, req, maker, authorizationTypeBearer, username, time.Minute)
 			},
+			buildStubs: stubAuthUser,
 			checkResponse: func(t *testing.T, resp *http.Response) {
 				require.Equal(t, http.StatusOK, resp.StatusCode)
 			},
 		},
+		{
+			name: "MFAChallengeToken",
+			setupAuth: func(t *testing.T, req *http.Request, maker token.Maker) {
+				challenge, _, err := maker.CreateTypedToken(username, token.TypeMFAChallenge, time.Minute)
+				require.NoError(t, err)
+				req.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, challenge))
+			},
+			checkResponse: func(t *testing.T, resp *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			},
+		},
+		{
+			name: "TokenIssuedBeforePasswordChange",
+			setupAuth: func(t *testing.T, req *http.Request, maker token.Maker) {
+				addAuthorization(t, req, maker, authorizationTypeBearer, username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, PasswordChangedAt: time.Now().Add(time.Second)}, nil)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			},
+		},
+		{
+			name: "UserNotFound",
+			setupAuth: func(t *testing.T, req *http.Request, maker token.Maker) {
+				addAuthorization(t, req, maker, authorizationTypeBearer, username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			},
+		},
+		{
+			name: "DeletedUser",
+			setupAuth: func(t *testing.T, req *http.Request, maker token.Maker) {
+				addAuthorization(t, req, maker, authorizationTypeBearer, username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}}, nil)
+			},
+			checkResponse: func(t *testing.T, resp *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			},
+		},
 		{
 			name:      "NoAuthorization",
 			setupAuth: func(t *testing.T, req *http.Request, maker token.Maker) {},
@@ -89,12 +166,21 @@ func TestAuthMiddlewareFiber(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Only tokens that pass verification reach the user lookup
+			store := mockdb.NewMockStore(ctrl)
+			if tc.buildStubs != nil {
+				tc.buildStubs(store)
+			}
+
 			// Use your full Server struct
-			server := newFiberTestServer(t, nil) // nil store is fine; middleware test doesn't need DB
+			server := newFiberTestServer(t, store)
 
 			// Register a test route with the middleware
 			authPath := "/auth"
-			server.app.Get(authPath, authMiddlewareFiber(server.tokenMaker), func(ctx *fiber.Ctx) error {
+			server.app.Get(authPath, authMiddlewareFiber(server.tokenMaker, store), func(ctx *fiber.Ctx) error {
 				return ctx.JSON(fiber.Map{"status": "ok"})
 			})
 
@@ -112,3 +198,78 @@ func TestAuthMiddlewareFiber(t *testing.T) {
 		})
 	}
 }
+
+// ---------------------------
+// Tests for Request ID Middleware
+// ---------------------------
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		check     func(t *testing.T, id string)
+	}{
+		{
+			name:      "Missing",
+			requestID: "",
+			check: func(t *testing.T, id string) {
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:      "Kept",
+			requestID: "edge-7f3a.42:1",
+			check: func(t *testing.T, id string) {
+				require.Equal(t, "edge-7f3a.42:1", id)
+			},
+		},
+		{
+			name:      "InvalidCharacters",
+			requestID: "id with spaces",
+			check: func(t *testing.T, id string) {
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:      "TooLong",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+			check: func(t *testing.T, id string) {
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// The store sees the same ID through the request's context
+			var storeRequestID string
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(ctx context.Context, username string) (db.User, error) {
+					storeRequestID = logging.RequestID(ctx)
+					return db.User{Username: username, IsEmailVerified: true}, nil
+				})
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodGet, "/users/me", nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), time.Minute)
+				if tc.requestID != "" {
+					request.Header.Set(fiber.HeaderXRequestID, tc.requestID)
+				}
+			})
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			id := recorder.Header().Get(fiber.HeaderXRequestID)
+			tc.check(t, id)
+			require.Equal(t, id, storeRequestID)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"      // Token handling (JWT/Paseto)
+	"github.com/valyala/fasthttp"
+)
+
+// heartbeatInterval is how often an idle stream sends a comment to keep proxies from closing it
+const heartbeatInterval = 15 * time.Second
+
+// streamRecheckInterval is how often an open stream re-checks that its credential hasn't
+// been revoked; a variable so tests can shorten it
+var streamRecheckInterval = time.Minute
+
+// Server-sent event names
+const (
+	sseEventBalance          = "balance"
+	sseEventTransferReceived = "transfer.received"
+)
+
+// ---------------------------
+// Response Structs
+// ---------------------------
+
+// balanceEvent is sent whenever the balance of one of the caller's accounts changes
+// @Description Balance change notification
+type balanceEvent struct {
+	AccountID  int64  `json:"account_id"`
+	Balance    int64  `json:"balance"`
+	Currency   string `json:"currency"`
+	TransferID int64  `json:"transfer_id"`
+}
+
+// transferReceivedEvent is sent when a transfer arrives in one of the caller's accounts
+// @Description Incoming transfer notification
+type transferReceivedEvent struct {
+	TransferID    int64     `json:"transfer_id"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// sseEvent is a single named server-sent event
+type sseEvent struct {
+	name string
+	data any
+}
+
+// ---------------------------
+// Handler: Notification Stream
+// ---------------------------
+
+// streamNotifications godoc
+// @Summary Stream account notifications
+// @Description Streams balance changes and incoming transfers for the caller's accounts as Server-Sent Events. Emits `balance` and `transfer.received` events. The stream ends when the token expires or is revoked (user deleted, password changed, API key deleted).
+// @Tags Notifications
+// @Produce text/event-stream
+// @Success 200 {string} string "event stream"
+// @Failure 401 {object} problemResponse
+// @Security ApiKeyAuth
+// @Router /v1/notifications/stream [get]
+func (server *Server) streamNotifications(c *fiber.Ctx) error {
+	// 1. Subscribe before responding so no transfer committed after this point is missed
+	authPayload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || authPayload == nil {
+		return unauthorized()
+	}
+	var apiKey *db.ApiKey
+	if key, ok := c.Locals(authorizationAPIKeyKey).(db.ApiKey); ok {
+		apiKey = &key
+	}
+	sub := server.hub.Subscribe(authPayload.Username)
+
+	// 2. Set event stream headers
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// 3. Stream until the client goes away (a write or flush fails), the server shuts down,
+	// or the credential expires or is revoked
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		recheck := time.NewTicker(streamRecheckInterval)
+		defer recheck.Stop()
+
+		// API keys without an expiry never time out
+		var expired <-chan time.Time
+		if !authPayload.ExpiredAt.IsZero() {
+			expiry := time.NewTimer(time.Until(authPayload.ExpiredAt))
+			defer expiry.Stop()
+			expired = expiry.C
+		}
+
+		// Let the client know the stream is open
+		if _, err := fmt.Fprint(w, ": connected\n\n"); err != nil || w.Flush() != nil {
+			return
+		}
+
+		for {
+			select {
+			case <-server.done:
+				return
+
+			case <-expired:
+				return
+
+			case <-recheck.C:
+				if err := server.checkStreamAuth(authPayload, apiKey); err != nil {
+					slog.Info("notification stream closed", "username", authPayload.Username, "reason", err)
+					return
+				}
+
+			case notification, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				for _, event := range transferEvents(authPayload.Username, notification) {
+					if err := writeSSE(w, event); err != nil {
+						return
+					}
+				}
+
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+
+	return nil
+}
+
+// ---------------------------
+// Helpers
+// ---------------------------
+
+// transferEvents builds the events a transfer notification produces for username
+func transferEvents(username string, notification db.TransferNotification) []sseEvent {
+	var events []sseEvent
+
+	if notification.FromOwner == username {
+		events = append(events, sseEvent{name: sseEventBalance, data: balanceEvent{
+			AccountID:  notification.FromAccountID,
+			Balance:    notification.FromBalance,
+			Currency:   notification.Currency,
+			TransferID: notification.TransferID,
+		}})
+	}
+
+	if notification.ToOwner == username {
+		events = append(events,
+			sseEvent{name: sseEventBalance, data: balanceEvent{
+				AccountID:  notification.ToAccountID,
+				Balance:    notification.ToBalance,
+				Currency:   notification.Currency,
+				TransferID: notification.TransferID,
+			}},
+			sseEvent{name: sseEventTransferReceived, data: transferReceivedEvent{
+				TransferID:    notification.TransferID,
+				FromAccountID: notification.FromAccountID,
+				ToAccountID:   notification.ToAccountID,
+				Amount:        notification.Amount,
+				Currency:      notification.Currency,
+				CreatedAt:     notification.CreatedAt,
+			}},
+		)
+	}
+
+	return events
+}
+
+// checkStreamAuth re-runs the revocation checks of authMiddlewareFiber for an open stream:
+// the user must not be deleted, access tokens must postdate the last password change, API
+// keys must still exist, and an impersonating banker must still be in good standing
+func (server *Server) checkStreamAuth(payload *token.Payload, apiKey *db.ApiKey) error {
+	ctx := context.Background()
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt.Valid {
+		return token.ErrInvalidToken
+	}
+
+	if apiKey != nil {
+		_, err := server.store.GetAPIKey(ctx, apiKey.ID)
+		return err
+	}
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		return token.ErrInvalidToken
+	}
+
+	if payload.Type == token.TypeImpersonation {
+		_, err := verifyActor(ctx, server.store, payload)
+		return err
+	}
+	return nil
+}
+
+// writeSSE writes one event in text/event-stream format
+func writeSSE(w *bufio.Writer, event sseEvent) error {
+	data, err := json.Marshal(event.data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, data)
+	return err
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bufio"
+	"database/sql"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferEvents(t *testing.T) {
+	sender := util.RandomOwner()
+	receiver := util.RandomOwner()
+	notification := db.TransferNotification{
+		TransferID:    7,
+		FromAccountID: 1,
+		FromOwner:     sender,
+		FromBalance:   90,
+		ToAccountID:   2,
+		ToOwner:       receiver,
+		ToBalance:     110,
+		Amount:        10,
+		Currency:      util.USD,
+	}
+
+	// The sender only sees their balance drop
+	events := transferEvents(sender, notification)
+	require.Len(t, events, 1)
+	require.Equal(t, sseEventBalance, events[0].name)
+	require.Equal(t, balanceEvent{AccountID: 1, Balance: 90, Currency: util.USD, TransferID: 7}, events[0].data)
+
+	// The receiver sees their new balance and the incoming transfer
+	events = transferEvents(receiver, notification)
+	require.Len(t, events, 2)
+	require.Equal(t, sseEventBalance, events[0].name)
+	require.Equal(t, balanceEvent{AccountID: 2, Balance: 110, Currency: util.USD, TransferID: 7}, events[0].data)
+	require.Equal(t, sseEventTransferReceived, events[1].name)
+
+	// Anyone else sees nothing
+	require.Empty(t, transferEvents(util.RandomOwner(), notification))
+}
+
+func TestStreamNotificationsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newFiberTestServer(t, store)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.app.Listener(listener)
+	defer server.app.ShutdownWithTimeout(time.Second)
+
+	// Without a token the stream is refused
+	resp, err := http.Get("http://" + listener.Addr().String() + "/notifications/stream")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/notifications/stream", nil)
+	require.NoError(t, err)
+	addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+
+	server.Notifications().Publish(db.TransferNotification{
+		TransferID:    3,
+		FromAccountID: 1,
+		FromOwner:     util.RandomOwner(),
+		ToAccountID:   2,
+		ToOwner:       user.Username,
+		ToBalance:     50,
+		Amount:        5,
+		Currency:      util.EUR,
+	})
+
+	var lines []string
+	for len(lines) < 4 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	require.Equal(t, "event: balance", lines[0])
+	require.JSONEq(t, `{"account_id":2,"balance":50,"currency":"EUR","transfer_id":3}`, strings.TrimPrefix(lines[1], "data: "))
+	require.Equal(t, "event: transfer.received", lines[2])
+	require.Contains(t, lines[3], `"amount":5`)
+}
+
+func TestShutdownEndsNotificationStreams(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newFiberTestServer(t, store)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.app.Listener(listener)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/notifications/stream", nil)
+	require.NoError(t, err)
+	addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+
+	// An open stream must not hold the shutdown for the whole timeout
+	start := time.Now()
+	require.NoError(t, server.Shutdown(10*time.Second))
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+}
+
+// openNotificationStream starts the server and opens a stream for username, returning
+// a reader positioned after the connected comment
+func openNotificationStream(t *testing.T, server *Server, username string, duration time.Duration) *bufio.Reader {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.app.Listener(listener)
+	t.Cleanup(func() { server.app.ShutdownWithTimeout(time.Second) })
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/notifications/stream", nil)
+	require.NoError(t, err)
+	addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, username, duration)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+	return reader
+}
+
+func TestNotificationStreamEndsAtExpiry(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newFiberTestServer(t, store)
+
+	reader := openNotificationStream(t, server, user.Username, 2*time.Second)
+
+	// The stream closes once the token expires
+	start := time.Now()
+	_, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestNotificationStreamEndsWhenRevoked(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	interval := streamRecheckInterval
+	streamRecheckInterval = 50 * time.Millisecond
+	defer func() { streamRecheckInterval = interval }()
+
+	// The user is fine when the stream opens and deleted by the first re-check
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(db.User{Username: user.Username, IsEmailVerified: true}, nil),
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(db.User{Username: user.Username, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil),
+	)
+	server := newFiberTestServer(t, store)
+
+	reader := openNotificationStream(t, server, user.Username, time.Minute)
+
+	start := time.Now()
+	_, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/sso"        // OpenID Connect single sign-on
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Secure random tokens, username rules
+	"github.com/valyala/fasthttp"
+)
+
+// defaultOIDCLoginDuration is how long a single sign-on may take when OIDC_LOGIN_DURATION is not configured
+const defaultOIDCLoginDuration = 10 * time.Minute
+
+// maxOIDCUsernameLength caps usernames derived from provider claims
+const maxOIDCUsernameLength = 32
+
+// oidcUsernamePrefix is put in front of usernames derived from claims too short to be valid
+const oidcUsernamePrefix = "user"
+
+// oidcStateCookie carries a sign-on's state in the browser that started it, so a
+// callback is only accepted from that browser and not from a link an attacker sent
+const oidcStateCookie = "oidc_state"
+
+// errInvalidOIDCLogin is returned for unknown, expired or replayed single sign-ons
+var errInvalidOIDCLogin = errors.New("invalid or expired single sign-on")
+
+// ---------------------------
+// Request Structs
+// ---------------------------
+
+// oidcCallbackRequest holds the query parameters the provider redirects back with
+type oidcCallbackRequest struct {
+	State            string `query:"state" validate:"required"`
+	Code             string `query:"code"`
+	Error            string `query:"error"` // Set instead of code when the provider refused the sign-in
+	ErrorDescription string `query:"error_description"`
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// startOIDCLogin handles GET /users/login/oidc endpoint
+
+// StartOIDCLogin godoc
+// @Summary      Start a single sign-on
+// @Description  Redirects the browser to the OpenID Connect provider's sign-in page (authorization code flow with PKCE). The provider sends the user back to GET /users/login/oidc/callback.
+// @Tags         Users
+// @Success      302
+// @Failure      404  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/users/login/oidc [get]
+func (server *Server) startOIDCLogin(c *fiber.Ctx) error {
+	if server.sso == nil {
+		return newAPIError(fiber.StatusNotFound, errCodeSSODisabled, "single sign-on is not configured")
+	}
+
+	// 1. Generate the state, nonce and PKCE verifier of this sign-on
+	secrets := make([]string, 3)
+	for i := range secrets {
+		secret, err := util.GenerateSecureToken()
+		if err != nil {
+			return internalError(err)
+		}
+		secrets[i] = secret
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	// 2. Remember them until the provider redirects back
+	duration := server.config.OIDCLoginDuration
+	if duration <= 0 {
+		duration = defaultOIDCLoginDuration
+	}
+	now := time.Now().UTC()
+	_, err := server.store.CreateOIDCLoginRequest(c.UserContext(), db.CreateOIDCLoginRequestParams{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(duration),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// Abandoned sign-ons are never consumed; sweep them while we're here
+	if err := server.store.DeleteExpiredOIDCLoginRequests(c.UserContext(), now); err != nil {
+		slog.WarnContext(c.UserContext(), "cannot delete expired oidc login requests", "error", err)
+	}
+
+	// 3. Tie the sign-on to this browser and send it to the provider
+	c.Cookie(server.oidcStateCookie(state, now.Add(duration)))
+	return c.Redirect(server.sso.AuthCodeURL(state, nonce, verifier), fiber.StatusFound)
+}
+
+// oidcCallback handles GET /users/login/oidc/callback endpoint
+
+// OIDCCallback godoc
+// @Summary      Complete a single sign-on
+// @Description  Redeems the provider's authorization code and returns an access token for the linked user. Unknown identities get a new password-less user when just-in-time provisioning is on; their role follows the provider's groups when a role mapping is configured.
+// @Tags         Users
+// @Produce      json
+// @Param        state  query     string  true   "State from the sign-on request"
+// @Param        code   query     string  false  "Authorization code"
+// @Param        error  query     string  false  "Error from the provider"
+// @Success      200    {object}  loginUserResponse
+// @Failure      400    {object}  problemResponse
+// @Failure      401    {object}  problemResponse
+// @Failure      403    {object}  problemResponse
+// @Failure      404    {object}  problemResponse
+// @Failure      409    {object}  problemResponse
+// @Failure      500    {object}  problemResponse
+// @Router       /v1/users/login/oidc/callback [get]
+func (server *Server) oidcCallback(c *fiber.Ctx) error {
+	if server.sso == nil {
+		return newAPIError(fiber.StatusNotFound, errCodeSSODisabled, "single sign-on is not configured")
+	}
+
+	// 1. Parse and validate query parameters
+	var req oidcCallbackRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Only the browser that started the sign-on may complete it
+	cookie := c.Cookies(oidcStateCookie)
+	c.Cookie(server.oidcStateCookie("", fasthttp.CookieExpireDelete))
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(req.State)) != 1 {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidSSOLogin, errInvalidOIDCLogin.Error())
+	}
+
+	// 3. Look up the sign-on; it is deleted on first use so the callback can't be replayed
+	login, err := server.store.ConsumeOIDCLoginRequest(c.UserContext(), req.State)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusUnauthorized, errCodeInvalidSSOLogin, errInvalidOIDCLogin.Error())
+		}
+		return internalError(err)
+	}
+	if time.Now().UTC().After(login.ExpiresAt) {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidSSOLogin, errInvalidOIDCLogin.Error())
+	}
+
+	// 4. The provider may have refused the sign-in
+	if req.Error != "" {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidSSOLogin, fmt.Sprintf("sign-in refused by provider: %s %s", req.Error, req.ErrorDescription))
+	}
+	if req.Code == "" {
+		return invalidField("code", "required", "is required")
+	}
+
+	// 5. Redeem the code and verify the ID token
+	identity, err := server.sso.Exchange(c.UserContext(), req.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return &apiError{Status: fiber.StatusUnauthorized, Code: errCodeInvalidSSOLogin, Detail: "the provider's sign-in could not be verified", Err: err}
+	}
+	if identity.Email == "" {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidSSOLogin, "provider did not share an email address")
+	}
+
+	// 6. Find or provision the linked user
+	arg := db.OIDCLoginTxParams{
+		Issuer:        identity.Issuer,
+		Subject:       identity.Subject,
+		Username:      oidcUsername(identity),
+		FullName:      identity.Name,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Provision:     server.config.OIDCProvisioning,
+	}
+	if arg.FullName == "" {
+		arg.FullName = arg.Username
+	}
+	if role, ok := server.sso.Role(identity); ok {
+		arg.Role.String, arg.Role.Valid = role, true
+	}
+
+	user, err := server.store.OIDCLoginTx(c.UserContext(), arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrIdentityNotLinked):
+			return newAPIError(fiber.StatusForbidden, errCodeIdentityNotLinked, err.Error())
+		case errors.Is(err, db.ErrEmailInUse):
+			return newAPIError(fiber.StatusConflict, errCodeEmailInUse, err.Error())
+		}
+		return internalError(err)
+	}
+	if user.DeletedAt.Valid {
+		return newAPIError(fiber.StatusUnauthorized, errCodeInvalidCredentials, "user has been deleted")
+	}
+
+	// 7. Issue our own access token
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	if err != nil {
+		return internalError(err)
+	}
+	if err := server.audit(c, user.Username, auditUserLogin, auditTarget("user", user.Username), nil, fiber.Map{"method": "oidc", "issuer": identity.Issuer}); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(loginUserResponse{
+		AccessToken: accessToken,
+		User:        newUserResponse(user),
+	})
+}
+
+// ---------------------------
+// Helper Functions
+// ---------------------------
+
+// oidcStateCookie returns the short-lived cookie holding a sign-on's state. It is
+// scoped to the callback path and sent on the provider's top-level redirect back (Lax).
+func (server *Server) oidcStateCookie(state string, expires time.Time) *fiber.Cookie {
+	path := "/"
+	if redirectURL, err := url.Parse(server.config.OIDCRedirectURL); err == nil && redirectURL.Path != "" {
+		path = redirectURL.Path
+	}
+	return &fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path,
+		Expires:  expires,
+		Secure:   strings.HasPrefix(server.config.OIDCRedirectURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+}
+
+// oidcUsername derives an alphanumeric username from the provider's preferred username
+// or, failing that, the email's local part. Names too short for the username
+// validator get a prefix, so the user can be addressed by /users/:username routes.
+func oidcUsername(identity sso.Identity) string {
+	name := identity.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) && b.Len() < maxOIDCUsernameLength {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() < util.MinUsernameLength {
+		return oidcUsernamePrefix + b.String()
+	}
+	return b.String()
+}
@@ -0,0 +1,388 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/sso"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/sso/ssotest"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+const oidcTestRedirectURL = "http://localhost:8080/users/login/oidc/callback"
+
+// newOIDCTestServer creates a test server signing users in through a local mock provider
+func newOIDCTestServer(t *testing.T, store db.Store) (*Server, *ssotest.IdP) {
+	idp, err := ssotest.NewIdP("simple-bank", util.RandomString(32))
+	require.NoError(t, err)
+	t.Cleanup(idp.Close)
+
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		OIDCIssuerURL:     idp.URL,
+		OIDCClientID:      idp.ClientID,
+		OIDCClientSecret:  idp.ClientSecret,
+		OIDCRedirectURL:   oidcTestRedirectURL,
+		OIDCRoleClaim:     "groups",
+		OIDCRoleMapping:   "bank-staff=banker",
+		OIDCProvisioning:  true,
+	}, store)
+	require.NoError(t, err)
+	return server, idp
+}
+
+// startOIDCTestLogin runs GET /users/login/oidc and returns the stored sign-on, the
+// provider URL the browser was sent to and the state cookie it was given
+func startOIDCTestLogin(t *testing.T, server *Server, store *mockdb.MockStore) (db.OidcLoginRequest, string, *http.Cookie) {
+	var login db.OidcLoginRequest
+	store.EXPECT().
+		CreateOIDCLoginRequest(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateOIDCLoginRequestParams) (db.OidcLoginRequest, error) {
+			login = db.OidcLoginRequest{
+				State:        arg.State,
+				Nonce:        arg.Nonce,
+				CodeVerifier: arg.CodeVerifier,
+				ExpiresAt:    arg.ExpiresAt,
+			}
+			return login, nil
+		})
+	store.EXPECT().DeleteExpiredOIDCLoginRequests(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/users/login/oidc", nil)
+	response, err := server.app.Test(request, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	var stateCookie *http.Cookie
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == oidcStateCookie {
+			stateCookie = cookie
+		}
+	}
+	require.NotNil(t, stateCookie)
+
+	return login, response.Header.Get("Location"), stateCookie
+}
+
+// ---------------------------
+// TestStartOIDCLoginAPI
+// ---------------------------
+
+func TestStartOIDCLoginAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server, idp := newOIDCTestServer(t, store)
+
+	login, location, stateCookie := startOIDCTestLogin(t, server, store)
+	require.NotEmpty(t, login.State)
+	require.NotEmpty(t, login.Nonce)
+	require.WithinDuration(t, time.Now().UTC().Add(defaultOIDCLoginDuration), login.ExpiresAt, time.Second)
+
+	authURL, err := url.Parse(location)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location, idp.URL+"/authorize?"))
+
+	// Only the challenge of the verifier is sent to the provider
+	query := authURL.Query()
+	require.Equal(t, login.State, query.Get("state"))
+	require.Equal(t, login.Nonce, query.Get("nonce"))
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.NotEmpty(t, query.Get("code_challenge"))
+	require.NotContains(t, location, login.CodeVerifier)
+	require.Equal(t, oidcTestRedirectURL, query.Get("redirect_uri"))
+
+	// The browser keeps the state in a cookie only the callback sees
+	require.Equal(t, login.State, stateCookie.Value)
+	require.Equal(t, "/users/login/oidc/callback", stateCookie.Path)
+	require.True(t, stateCookie.HttpOnly)
+	require.Equal(t, http.SameSiteLaxMode, stateCookie.SameSite)
+	require.WithinDuration(t, login.ExpiresAt, stateCookie.Expires, time.Second)
+}
+
+func TestStartOIDCLoginNotConfigured(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newFiberTestServer(t, store)
+	recorder := doJSONRequest(t, server, http.MethodGet, "/users/login/oidc", nil, nil)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+// ---------------------------
+// TestOIDCCallbackAPI
+// ---------------------------
+
+func TestOIDCCallbackAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.HashedPassword = ""
+
+	claims := map[string]any{
+		"sub":                "staff-" + util.RandomString(6),
+		"email":              user.Email,
+		"email_verified":     true,
+		"name":               user.FullName,
+		"preferred_username": "Jane.Doe",
+		"groups":             []string{"bank-staff"},
+	}
+
+	testCases := []struct {
+		name          string
+		claims        map[string]any
+		setupCallback func(query url.Values)
+		setupCookie   func(cookie *http.Cookie) *http.Cookie
+		buildStubs    func(store *mockdb.MockStore, login db.OidcLoginRequest)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Eq(login.State)).Times(1).Return(login, nil)
+				store.EXPECT().
+					OIDCLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.OIDCLoginTxParams) (db.User, error) {
+						require.Equal(t, claims["sub"], arg.Subject)
+						require.Equal(t, "janedoe", arg.Username)
+						require.Equal(t, user.Email, arg.Email)
+						require.True(t, arg.EmailVerified)
+						require.Equal(t, sql.NullString{String: util.BankerRole, Valid: true}, arg.Role)
+						require.True(t, arg.Provision)
+
+						banker := user
+						banker.Role = arg.Role.String
+						return banker, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.AccessToken)
+				require.Equal(t, user.Username, resp.User.Username)
+				require.Equal(t, util.BankerRole, resp.User.Role)
+			},
+		},
+		{
+			name:   "UnknownState",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(db.OidcLoginRequest{}, db.ErrRecordNotFound)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingStateCookie",
+			claims: claims,
+			setupCookie: func(cookie *http.Cookie) *http.Cookie {
+				return nil
+			},
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, errCodeInvalidSSOLogin)
+			},
+		},
+		{
+			name:   "StateCookieMismatch",
+			claims: claims,
+			setupCookie: func(cookie *http.Cookie) *http.Cookie {
+				// A callback link started in another browser
+				return &http.Cookie{Name: oidcStateCookie, Value: util.RandomString(43)}
+			},
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, errCodeInvalidSSOLogin)
+			},
+		},
+		{
+			name:   "Expired",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				login.ExpiresAt = time.Now().UTC().Add(-time.Second)
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ProviderError",
+			claims: claims,
+			setupCallback: func(query url.Values) {
+				query.Del("code")
+				query.Set("error", "access_denied")
+			},
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "access_denied")
+			},
+		},
+		{
+			name:   "InvalidCode",
+			claims: claims,
+			setupCallback: func(query url.Values) {
+				query.Set("code", "forged")
+			},
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "WrongVerifier",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				login.CodeVerifier = util.RandomString(43)
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "NoEmail",
+			claims: map[string]any{"sub": "no-email"},
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "NotLinked",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrIdentityNotLinked)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeIdentityNotLinked)
+			},
+		},
+		{
+			name:   "EmailInUse",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrEmailInUse)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeEmailInUse)
+			},
+		},
+		{
+			name:   "DeletedUser",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				deleted := user
+				deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(login, nil)
+				store.EXPECT().OIDCLoginTx(gomock.Any(), gomock.Any()).Times(1).Return(deleted, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			claims: claims,
+			buildStubs: func(store *mockdb.MockStore, login db.OidcLoginRequest) {
+				store.EXPECT().ConsumeOIDCLoginRequest(gomock.Any(), gomock.Any()).Times(1).Return(db.OidcLoginRequest{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server, idp := newOIDCTestServer(t, store)
+
+			// The user signs in at the provider, which redirects back with a code
+			login, location, stateCookie := startOIDCTestLogin(t, server, store)
+			callback, err := idp.Login(location, tc.claims)
+			require.NoError(t, err)
+
+			query := callback.Query()
+			if tc.setupCallback != nil {
+				tc.setupCallback(query)
+			}
+
+			if tc.setupCookie != nil {
+				stateCookie = tc.setupCookie(stateCookie)
+			}
+
+			tc.buildStubs(store, login)
+			stubAudit(store)
+			recorder := doJSONRequest(t, server, http.MethodGet, callback.Path+"?"+query.Encode(), nil, func(request *http.Request) {
+				if stateCookie != nil {
+					request.AddCookie(&http.Cookie{Name: stateCookie.Name, Value: stateCookie.Value})
+				}
+			})
+			tc.checkResponse(t, recorder)
+
+			// The cookie is single use
+			require.Contains(t, recorder.Header().Get("Set-Cookie"), oidcStateCookie+"=;")
+		})
+	}
+}
+
+// ---------------------------
+// TestOIDCUsername
+// ---------------------------
+
+func TestOIDCUsername(t *testing.T) {
+	require.Equal(t, "janedoe", oidcUsername(sso.Identity{PreferredUsername: "Jane.Doe", Email: "x@example.com"}))
+	require.Equal(t, "jdoe42", oidcUsername(sso.Identity{Email: "j-doe+42@example.com"}))
+	require.Equal(t, "user", oidcUsername(sso.Identity{PreferredUsername: "ë_ß"}))
+	require.Equal(t, "userjd", oidcUsername(sso.Identity{PreferredUsername: "J.D."}))
+	require.Equal(t, "userx", oidcUsername(sso.Identity{Email: "x@example.com"}))
+	for _, name := range []string{"", "a", "ab", "abc", strings.Repeat("a", 100)} {
+		require.True(t, util.IsValidUsername(oidcUsername(sso.Identity{PreferredUsername: name})), name)
+	}
+	require.Len(t, oidcUsername(sso.Identity{PreferredUsername: strings.Repeat("a", 100)}), maxOIDCUsernameLength)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Utility functions (e.g., password hashing)
+)
+
+// ---------------------------
+// Request and Response Structs
+// ---------------------------
+
+// requestPasswordResetRequest represents the expected JSON body for requesting a reset email
+// @Description Password reset request payload
+type requestPasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// confirmPasswordResetRequest represents the expected JSON body for setting a new password
+// @Description Password reset confirmation payload
+type confirmPasswordResetRequest struct {
+	Token       string `json:"token" validate:"required"`        // Token from the reset email
+	NewPassword string `json:"new_password" validate:"required"` // New password, see the password policy
+}
+
+// messageResponse is a JSON body carrying only a human readable message
+// @Description Message response
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// requestPasswordReset handles POST /users/password_reset endpoint
+
+// RequestPasswordReset godoc
+// @Summary      Request a password reset
+// @Description  Queues a single-use password reset link to be emailed if the address belongs to a user. The response is always 202, whether or not it does.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      requestPasswordResetRequest  true  "Account email"
+// @Success      202      {object}  messageResponse
+// @Failure      400      {object}  problemResponse
+// @Router       /v1/users/password_reset [post]
+func (server *Server) requestPasswordReset(c *fiber.Ctx) error {
+	accepted := messageResponse{Message: "if the email belongs to an account, a reset link has been sent"}
+
+	// 1. Parse and validate the request body
+	var req requestPasswordResetRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Queue the reset for the worker to email. Known and unknown addresses cost the
+	// same single statement and get the same answer, so accounts can't be enumerated;
+	// a failure is only logged for the same reason
+	if _, err := server.store.CreatePasswordResetRequest(c.UserContext(), req.Email); err != nil {
+		slog.ErrorContext(c.UserContext(), "cannot queue password reset", "error", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(accepted)
+}
+
+// confirmPasswordReset handles POST /users/password_reset/confirm endpoint
+
+// ConfirmPasswordReset godoc
+// @Summary      Reset a password
+// @Description  Sets a new password using the token from the reset email. Access tokens issued before the change stop working.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      confirmPasswordResetRequest  true  "Reset token and new password"
+// @Success      200      {object}  userResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/users/password_reset/confirm [post]
+func (server *Server) confirmPasswordReset(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req confirmPasswordResetRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Check the new password against the policy for the token's user
+	tokenHash := util.HashSecureToken(req.Token)
+	resetToken, err := server.store.GetPasswordResetToken(c.UserContext(), sql.NullString{String: tokenHash, Valid: true})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return newAPIError(fiber.StatusBadRequest, errCodeInvalidLink, db.ErrInvalidResetToken.Error())
+		}
+		return internalError(err)
+	}
+	if err := server.checkNewPassword(resetToken.Username, req.NewPassword); err != nil {
+		return err
+	}
+
+	// 3. Hash the new password
+	hashedPassword, err := server.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 4. Consume the token and update the password atomically
+	user, err := server.store.ResetPasswordTx(c.UserContext(), db.ResetPasswordTxParams{
+		TokenHash:      tokenHash,
+		HashedPassword: hashedPassword,
+	}, func(user db.User) db.AppendAuditEventTxParams {
+		return auditEvent(c, user.Username, auditUserPasswordReset, auditTarget("user", user.Username), nil, nil)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidResetToken) {
+			return newAPIError(fiber.StatusBadRequest, errCodeInvalidLink, err.Error())
+		}
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserResponse(user))
+}
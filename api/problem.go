@@ -0,0 +1,361 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/logging"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the type URI of every problem, e.g. /problems/account-not-found.
+// It is a relative reference, resolved against the API's own address.
+const problemTypeBase = "/problems/"
+
+// Stable, machine-readable error codes. Clients switch on these, so a code is never
+// renamed or reused for another problem once released.
+const (
+	// Generic problems
+	errCodeValidationFailed = "VALIDATION_FAILED" // Request fields failed validation; see the errors member
+	errCodeMalformedRequest = "MALFORMED_REQUEST" // The body, query or path could not be parsed
+	errCodeUnauthorized     = "UNAUTHORIZED"      // No valid credentials were presented
+	errCodeForbidden        = "FORBIDDEN"         // The caller may not perform the action
+	errCodeNotFound         = "NOT_FOUND"         // No route or resource at this path
+	errCodeRateLimited      = "RATE_LIMITED"      // The client used up its request budget; see Retry-After
+	errCodeInternal         = "INTERNAL_ERROR"    // Something failed on the server; details are only logged
+
+	// Authentication
+	errCodeInvalidToken          = "INVALID_TOKEN"           // The token or API key is invalid, expired or revoked
+	errCodeInvalidCredentials    = "INVALID_CREDENTIALS"     // Wrong username or password, or a locked account
+	errCodeTooManyLoginAttempts  = "TOO_MANY_LOGIN_ATTEMPTS" // The client IP is locked out after failed logins
+	errCodeMFARequired           = "MFA_REQUIRED"            // The action needs a two-factor code
+	errCodeMFAEnrollmentRequired = "MFA_ENROLLMENT_REQUIRED" // The action needs two-factor authentication enabled
+	errCodeInvalidMFACode        = "INVALID_MFA_CODE"        // The two-factor or recovery code is wrong or used
+	errCodeMFAAlreadyEnabled     = "MFA_ALREADY_ENABLED"     // Two-factor authentication is already on
+	errCodeMFANotPending         = "MFA_NOT_PENDING"         // No two-factor enrollment is waiting to be confirmed
+	errCodeTooManyMFAAttempts    = "TOO_MANY_MFA_ATTEMPTS"   // The user is locked out after wrong two-factor codes
+	errCodeIdentityNotLinked     = "IDENTITY_NOT_LINKED"     // The single sign-on identity has no user
+	errCodeInvalidSSOLogin       = "INVALID_SSO_LOGIN"       // The single sign-on is unknown, expired or failed
+	errCodeSSODisabled           = "SSO_DISABLED"            // Single sign-on is not configured
+
+	// Authorization
+	errCodeEmailNotVerified  = "EMAIL_NOT_VERIFIED" // The route needs a verified email address
+	errCodeInsufficientScope = "INSUFFICIENT_SCOPE" // The API key may not call the route
+	errCodeActAsForbidden    = "ACT_AS_FORBIDDEN"   // The route can't be called while impersonating
+	errCodePermissionDenied  = "PERMISSION_DENIED"  // The user's role may not call the route
+
+	// Users
+	errCodeUserNotFound   = "USER_NOT_FOUND"
+	errCodeUsernameTaken  = "USERNAME_TAKEN"
+	errCodeEmailInUse     = "EMAIL_IN_USE"
+	errCodeWeakPassword   = "WEAK_PASSWORD"
+	errCodeInvalidLink    = "INVALID_LINK"     // A password reset or email verification link is invalid or expired
+	errCodeNonZeroBalance = "NON_ZERO_BALANCE" // The user still holds money
+
+	// Accounts, transfers, API keys and webhooks
+	errCodeAccountNotFound  = "ACCOUNT_NOT_FOUND"
+	errCodeAccountNotOwned  = "ACCOUNT_NOT_OWNED" // The account belongs to another user
+	errCodeAccountExists    = "ACCOUNT_EXISTS"    // The user already has an account in the currency
+	errCodeAccountClosed    = "ACCOUNT_CLOSED"    // The account's owner was deleted
+	errCodeAccountInLedger  = "ACCOUNT_IN_LEDGER" // Entries, transfers or interest refer to the account
+	errCodeCurrencyMismatch = "CURRENCY_MISMATCH"
+	errCodeAPIKeyNotFound   = "API_KEY_NOT_FOUND"
+	errCodeWebhookNotFound  = "WEBHOOK_NOT_FOUND"
+	errCodeWebhookNotOwned  = "WEBHOOK_NOT_OWNED" // The webhook belongs to another user
+	errCodeDeliveryNotFound = "DELIVERY_NOT_FOUND"
+)
+
+// problemTitles summarize each code. Like the code, a title is the same for every
+// occurrence of a problem; what went wrong this time is in the detail.
+var problemTitles = map[string]string{
+	errCodeValidationFailed: "Request validation failed",
+	errCodeMalformedRequest: "Malformed request",
+	errCodeUnauthorized:     "Authentication required",
+	errCodeForbidden:        "Forbidden",
+	errCodeNotFound:         "Not found",
+	errCodeRateLimited:      "Too many requests",
+	errCodeInternal:         "Internal server error",
+
+	errCodeInvalidToken:          "Invalid or expired credentials",
+	errCodeInvalidCredentials:    "Invalid credentials",
+	errCodeTooManyLoginAttempts:  "Too many failed login attempts",
+	errCodeMFARequired:           "Two-factor code required",
+	errCodeMFAEnrollmentRequired: "Two-factor authentication must be enabled",
+	errCodeInvalidMFACode:        "Invalid two-factor code",
+	errCodeMFAAlreadyEnabled:     "Two-factor authentication already enabled",
+	errCodeMFANotPending:         "No two-factor enrollment pending",
+	errCodeTooManyMFAAttempts:    "Too many failed two-factor attempts",
+	errCodeIdentityNotLinked:     "Identity not linked to a user",
+	errCodeInvalidSSOLogin:       "Single sign-on failed",
+	errCodeSSODisabled:           "Single sign-on is not enabled",
+
+	errCodeEmailNotVerified:  "Email address not verified",
+	errCodeInsufficientScope: "Insufficient API key scope",
+	errCodeActAsForbidden:    "Not allowed while impersonating",
+	errCodePermissionDenied:  "Permission denied",
+
+	errCodeUserNotFound:   "User not found",
+	errCodeUsernameTaken:  "Username already taken",
+	errCodeEmailInUse:     "Email address already in use",
+	errCodeWeakPassword:   "Password too weak",
+	errCodeInvalidLink:    "Invalid or expired link",
+	errCodeNonZeroBalance: "Accounts still hold money",
+
+	errCodeAccountNotFound:  "Account not found",
+	errCodeAccountNotOwned:  "Account belongs to another user",
+	errCodeAccountExists:    "Account already exists",
+	errCodeAccountClosed:    "Account closed",
+	errCodeAccountInLedger:  "Account is part of the ledger",
+	errCodeCurrencyMismatch: "Currency mismatch",
+	errCodeAPIKeyNotFound:   "API key not found",
+	errCodeWebhookNotFound:  "Webhook not found",
+	errCodeWebhookNotOwned:  "Webhook belongs to another user",
+	errCodeDeliveryNotFound: "Webhook delivery not found",
+}
+
+// apiError is an error handlers and middlewares return to answer a request with an RFC
+// 7807 problem; errorHandler writes it. Only Detail and Fields reach the client, so they
+// must never hold raw database or internal errors: those go in Err, which is logged.
+type apiError struct {
+	Status int          // HTTP status code
+	Code   string       // One of the errCode constants
+	Detail string       // What went wrong this time, safe to show to the client
+	Fields []fieldError // The invalid fields, for VALIDATION_FAILED
+	Err    error        // Underlying cause; logged, never sent
+}
+
+// fieldError describes one invalid request field
+// @Description Invalid request field
+type fieldError struct {
+	Field   string `json:"field"`   // Name of the field as sent (JSON, query or path name)
+	Rule    string `json:"rule"`    // Validation rule it failed, e.g. required, min, currency
+	Message string `json:"message"` // Human-readable explanation
+}
+
+// problemResponse is the application/problem+json body of every error
+// @Description RFC 7807 problem details
+type problemResponse struct {
+	Type      string       `json:"type"`                 // URI reference identifying the problem type
+	Title     string       `json:"title"`                // Summary of the problem type
+	Status    int          `json:"status"`               // HTTP status code
+	Code      string       `json:"code"`                 // Stable machine-readable code, e.g. ACCOUNT_NOT_FOUND
+	Detail    string       `json:"detail,omitempty"`     // What went wrong this time
+	Instance  string       `json:"instance"`             // Path of the request
+	RequestID string       `json:"request_id,omitempty"` // X-Request-ID of the request, for support
+	Errors    []fieldError `json:"errors,omitempty"`     // Invalid fields, for VALIDATION_FAILED
+}
+
+// newAPIError returns a problem with the given status, code and client-facing detail
+func newAPIError(status int, code string, detail string) *apiError {
+	return &apiError{Status: status, Code: code, Detail: detail}
+}
+
+// internalError hides err from the client behind a generic 500; it is logged instead
+func internalError(err error) *apiError {
+	return &apiError{Status: fiber.StatusInternalServerError, Code: errCodeInternal, Err: err}
+}
+
+// unauthorized is returned by handlers reached without the authentication payload
+func unauthorized() *apiError {
+	return newAPIError(fiber.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+}
+
+// invalidField is a validation failure of a single field found by a handler's own checks
+func invalidField(field, rule, message string) *apiError {
+	return &apiError{
+		Status: fiber.StatusBadRequest,
+		Code:   errCodeValidationFailed,
+		Detail: fmt.Sprintf("%s %s", field, message),
+		Fields: []fieldError{{Field: field, Rule: rule, Message: message}},
+	}
+}
+
+// invalidRequest turns an error from parsing or validating a request into a 400,
+// listing the invalid fields when validation failed or a field had the wrong type
+func invalidRequest(err error) *apiError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]fieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			fields = append(fields, fieldError{
+				Field:   fieldErr.Field(),
+				Rule:    fieldErr.Tag(),
+				Message: validationMessage(fieldErr),
+			})
+		}
+		return &apiError{
+			Status: fiber.StatusBadRequest,
+			Code:   errCodeValidationFailed,
+			Detail: "one or more fields are invalid",
+			Fields: fields,
+			Err:    err,
+		}
+	}
+	if fields := parseErrorFields(err); len(fields) > 0 {
+		return &apiError{
+			Status: fiber.StatusBadRequest,
+			Code:   errCodeValidationFailed,
+			Detail: "one or more fields are invalid",
+			Fields: fields,
+			Err:    err,
+		}
+	}
+	return &apiError{
+		Status: fiber.StatusBadRequest,
+		Code:   errCodeMalformedRequest,
+		Detail: "the request could not be parsed",
+		Err:    err,
+	}
+}
+
+func (e *apiError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+// errorHandler is the Fiber ErrorHandler: it answers every error a handler returns with
+// application/problem+json. apiErrors keep their code; Fiber's own errors (unknown
+// routes, oversized bodies, ...) get a code from their status; anything else is a 500
+// whose cause is logged rather than sent.
+func errorHandler(c *fiber.Ctx, err error) error {
+	var apiErr *apiError
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &fiberErr):
+		apiErr = newAPIError(fiberErr.Code, statusCode(fiberErr.Code), fiberErr.Message)
+		if fiberErr.Code >= fiber.StatusInternalServerError {
+			apiErr.Detail = ""
+			apiErr.Err = fiberErr
+		}
+	default:
+		apiErr = internalError(err)
+	}
+
+	if apiErr.Status >= fiber.StatusInternalServerError {
+		slog.ErrorContext(c.UserContext(), "request failed", "code", apiErr.Code, "error", apiErr.Err)
+	}
+
+	title, ok := problemTitles[apiErr.Code]
+	if !ok {
+		title = http.StatusText(apiErr.Status)
+	}
+	requestID, _ := c.Locals(logging.RequestIDKey).(string)
+
+	return c.Status(apiErr.Status).JSON(problemResponse{
+		Type:      problemTypeBase + strings.ToLower(strings.ReplaceAll(apiErr.Code, "_", "-")),
+		Title:     title,
+		Status:    apiErr.Status,
+		Code:      apiErr.Code,
+		Detail:    apiErr.Detail,
+		Instance:  c.OriginalURL(),
+		RequestID: requestID,
+		Errors:    apiErr.Fields,
+	}, problemContentType)
+}
+
+// statusCode names the problem of an HTTP status with no more specific code, e.g.
+// METHOD_NOT_ALLOWED for 405
+func statusCode(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return errCodeMalformedRequest
+	case fiber.StatusUnauthorized:
+		return errCodeUnauthorized
+	case fiber.StatusForbidden:
+		return errCodeForbidden
+	case fiber.StatusNotFound:
+		return errCodeNotFound
+	}
+	if status >= fiber.StatusInternalServerError {
+		return errCodeInternal
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return errCodeInternal
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// validationMessage explains a failed validation rule in plain words
+func validationMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	isString := fieldErr.Kind() == reflect.String
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", param)
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", param)
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters long", param)
+	case "oneof":
+		return "must be one of: " + param
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "numeric":
+		return "must contain only digits"
+	case "datetime":
+		return "must be a date and time in the layout " + param
+	case "currency":
+		return "must be a supported currency"
+	case "account_type":
+		return "must be a supported account type"
+	case "webhook_url":
+		return "must be an https URL"
+	case "webhook_event":
+		return "must be an event webhooks can subscribe to"
+	case "api_scope":
+		return "must be a scope API keys can be granted"
+	case "username":
+		return fmt.Sprintf("must be %d to %d letters and digits", util.MinUsernameLength, util.MaxUsernameLength)
+	case "amount":
+		return fmt.Sprintf("must be greater than 0 and at most %d", util.MaxAmount)
+	}
+	return fmt.Sprintf("failed the %q rule", fieldErr.Tag())
+}
+
+// fieldName makes validation errors name fields as clients send them: by their JSON,
+// query or path parameter name rather than the Go field name
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "params", "uri", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
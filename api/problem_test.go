@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// requireProblem checks resp is an application/problem+json body with the given status
+// and code, and returns it
+func requireProblem(t *testing.T, resp *httptest.ResponseRecorder, status int, code string) problemResponse {
+	result := resp.Result()
+	require.Equal(t, status, result.StatusCode)
+	require.Equal(t, problemContentType, result.Header.Get(fiber.HeaderContentType))
+
+	var problem problemResponse
+	require.NoError(t, json.NewDecoder(result.Body).Decode(&problem))
+	require.Equal(t, status, problem.Status)
+	require.Equal(t, code, problem.Code)
+	require.NotEmpty(t, problem.Title)
+	return problem
+}
+
+func TestProblemResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newFiberTestServer(t, store)
+
+	owner := util.RandomOwner()
+	account := randomAccount(owner)
+	authAs := func(username string) func(*http.Request) {
+		return func(req *http.Request) {
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+		}
+	}
+
+	t.Run("DomainError", func(t *testing.T) {
+		store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(db.Account{}, db.ErrRecordNotFound)
+
+		path := fmt.Sprintf("/accounts/%d", account.ID)
+		resp := doJSONRequest(t, server, fiber.MethodGet, path, nil, authAs(owner))
+		problem := requireProblem(t, resp, http.StatusNotFound, errCodeAccountNotFound)
+		require.Equal(t, "/problems/account-not-found", problem.Type)
+		require.Equal(t, path, problem.Instance)
+		require.NotEmpty(t, problem.RequestID)
+	})
+
+	t.Run("ValidationNamesJSONFields", func(t *testing.T) {
+		resp := doJSONRequest(t, server, fiber.MethodPost, "/accounts", fiber.Map{"currency": "XYZ"}, authAs(owner))
+		problem := requireProblem(t, resp, http.StatusBadRequest, errCodeValidationFailed)
+		require.Equal(t, []fieldError{{Field: "currency", Rule: "currency", Message: "must be a supported currency"}}, problem.Errors)
+	})
+
+	t.Run("InternalErrorHidesCause", func(t *testing.T) {
+		store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(db.Account{}, errors.New("pq: relation \"accounts\" does not exist"))
+
+		resp := doJSONRequest(t, server, fiber.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil, authAs(owner))
+		problem := requireProblem(t, resp, http.StatusInternalServerError, errCodeInternal)
+		require.Empty(t, problem.Detail)
+		require.NotContains(t, resp.Body.String(), "relation")
+	})
+
+	t.Run("UnknownRoute", func(t *testing.T) {
+		resp := doJSONRequest(t, server, fiber.MethodGet, "/no-such-route", nil, authAs(owner))
+		requireProblem(t, resp, http.StatusNotFound, errCodeNotFound)
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		resp := doJSONRequest(t, server, fiber.MethodGet, "/accounts", nil, nil)
+		requireProblem(t, resp, http.StatusUnauthorized, errCodeUnauthorized)
+	})
+}
+
+func TestStatusCode(t *testing.T) {
+	require.Equal(t, errCodeNotFound, statusCode(http.StatusNotFound))
+	require.Equal(t, "METHOD_NOT_ALLOWED", statusCode(http.StatusMethodNotAllowed))
+	require.Equal(t, "REQUEST_ENTITY_TOO_LARGE", statusCode(http.StatusRequestEntityTooLarge))
+	require.Equal(t, errCodeInternal, statusCode(http.StatusBadGateway))
+}
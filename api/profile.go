@@ -0,0 +1,209 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Password checks
+)
+
+// ---------------------------
+// Request Structs
+// ---------------------------
+
+// updateUserRequest represents the expected JSON body for editing the profile; omitted
+// fields are left unchanged
+// @Description Profile update payload
+type updateUserRequest struct {
+	FullName *string `json:"full_name" validate:"omitempty,min=1"`
+	Email    *string `json:"email" validate:"omitempty,email"` // A new email must be verified again
+}
+
+// changePasswordRequest represents the expected JSON body for changing the password
+// @Description Password change payload
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"` // See the password policy
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// getCurrentUser handles GET /users/me endpoint
+
+// GetCurrentUser godoc
+// @Summary      Get my profile
+// @Description  Returns the authenticated user's profile
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  userResponse
+// @Failure      401  {object}  problemResponse
+// @Router       /v1/users/me [get]
+func (server *Server) getCurrentUser(c *fiber.Ctx) error {
+	user, ok := c.Locals(authorizationUserKey).(db.User)
+	if !ok {
+		return unauthorized()
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserResponse(user))
+}
+
+// updateCurrentUser handles PATCH /users/me endpoint
+
+// UpdateCurrentUser godoc
+// @Summary      Edit my profile
+// @Description  Changes the full name and/or email. A new email address is unverified until the link sent to it is followed.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request  body      updateUserRequest  true  "Fields to change"
+// @Success      200      {object}  userResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      401      {object}  problemResponse
+// @Failure      403      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/users/me [patch]
+func (server *Server) updateCurrentUser(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req updateUserRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	user, ok := c.Locals(authorizationUserKey).(db.User)
+	if !ok {
+		return unauthorized()
+	}
+
+	// 2. Apply only the fields that were sent
+	arg := db.UpdateUserTxParams{Username: user.Username}
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+	if req.Email != nil {
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+	}
+
+	// 3. Update the profile, queueing a verification email for a new address
+	before := newUserResponse(user)
+	user, err := server.store.UpdateUserTx(c.UserContext(), arg, func(user db.User) db.AppendAuditEventTxParams {
+		return auditEvent(c, user.Username, auditUserUpdated, auditTarget("user", user.Username), before, newUserResponse(user))
+	})
+	if err != nil {
+		// The email belongs to another user
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return &apiError{Status: fiber.StatusForbidden, Code: errCodeEmailInUse, Detail: "email address is already in use", Err: err}
+		}
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserResponse(user))
+}
+
+// changePassword handles PUT /users/me/password endpoint
+
+// ChangePassword godoc
+// @Summary      Change my password
+// @Description  Sets a new password after checking the current one. Access tokens issued before the change stop working; a fresh one is returned. Wrong current passwords count as failed logins.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request  body      changePasswordRequest  true  "Current and new password"
+// @Success      200      {object}  loginUserResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      401      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/users/me/password [put]
+func (server *Server) changePassword(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req changePasswordRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	user, ok := c.Locals(authorizationUserKey).(db.User)
+	if !ok {
+		return unauthorized()
+	}
+
+	// 2. Check the current password like a login, so a stolen token can't guess it
+	if err := server.checkLoginIP(c); err != nil {
+		return err
+	}
+	if err := util.CheckPassword(req.OldPassword, user.HashedPassword); err != nil || isLocked(user.LockedUntil) {
+		return server.failLogin(c, user.Username, &user)
+	}
+
+	// 3. Check and hash the new password
+	if err := server.checkNewPassword(user.Username, req.NewPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := server.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 4. Store it, bumping password_changed_at to revoke older tokens
+	user, err = server.store.ChangePasswordTx(c.UserContext(), db.UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now().UTC(),
+	}, func(user db.User) db.AppendAuditEventTxParams {
+		return auditEvent(c, user.Username, auditUserPasswordChanged, auditTarget("user", user.Username), nil, nil)
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 5. Keep this client signed in with a token issued after the change
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	if err != nil {
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(loginUserResponse{
+		AccessToken: accessToken,
+		User:        newUserResponse(user),
+	})
+}
+
+// deleteCurrentUser handles DELETE /users/me endpoint
+
+// DeleteCurrentUser godoc
+// @Summary      Delete my profile
+// @Description  Closes the profile: personal data is erased, logins and tokens stop working and webhooks are switched off. Accounts stay in the ledger but can't receive money. Refused with 409 while any account holds a non-zero balance.
+// @Tags         Users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  messageResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      409  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/users/me [delete]
+func (server *Server) deleteCurrentUser(c *fiber.Ctx) error {
+	user, ok := c.Locals(authorizationUserKey).(db.User)
+	if !ok {
+		return unauthorized()
+	}
+
+	// 1. Delete, unless money is left in any account. The profile's personal data is
+	// erased, so none is kept in the audit log either
+	_, err := server.store.DeleteUserTx(c.UserContext(), user.Username, func(db.User) db.AppendAuditEventTxParams {
+		return auditEvent(c, user.Username, auditUserDeleted, auditTarget("user", user.Username), nil, nil)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUserHasBalance) {
+			return newAPIError(fiber.StatusConflict, errCodeNonZeroBalance, err.Error())
+		}
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(messageResponse{Message: "user deleted"})
+}
@@ -0,0 +1,373 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAuthAs lets the auth middleware load the given user for its own tokens
+func stubAuthAs(store *mockdb.MockStore, user db.User) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, _ string) (db.User, error) {
+			return user, nil
+		})
+}
+
+// ---------------------------
+// TestGetCurrentUserAPI
+// ---------------------------
+
+func TestGetCurrentUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthAs(store, user)
+
+	server := newFiberTestServer(t, store)
+
+	recorder := doJSONRequest(t, server, http.MethodGet, "/users/me", nil, func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	requireBodyMatchUser(t, recorder.Body, user)
+	require.NotContains(t, recorder.Body.String(), user.HashedPassword)
+
+	recorder = doJSONRequest(t, server, http.MethodGet, "/users/me", nil, nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+// ---------------------------
+// TestUpdateCurrentUserAPI
+// ---------------------------
+
+func TestUpdateCurrentUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	newName := util.RandomOwner()
+	newEmail := util.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		body          fiber.Map
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: fiber.Map{"full_name": newName, "email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserTxParams{
+					Username: user.Username,
+					FullName: sql.NullString{String: newName, Valid: true},
+					Email:    sql.NullString{String: newEmail, Valid: true},
+				}
+				updated := user
+				updated.FullName = newName
+				updated.Email = newEmail
+				updated.IsEmailVerified = false
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Eq(arg), gomock.Any()).
+					Times(1).
+					Return(updated, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, newName, resp.FullName)
+				require.Equal(t, newEmail, resp.Email)
+				require.False(t, resp.IsEmailVerified)
+			},
+		},
+		{
+			name: "OnlyFullName",
+			body: fiber.Map{"full_name": newName},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserTxParams{
+					Username: user.Username,
+					FullName: sql.NullString{String: newName, Valid: true},
+				}
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Eq(arg), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: fiber.Map{"email": "not-an-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EmailTaken",
+			body: fiber.Map{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: fiber.Map{"full_name": newName},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthAs(store, user)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodPatch, "/users/me", tc.body, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestChangePasswordAPI
+// ---------------------------
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newPassword := util.RandomString(12)
+
+	testCases := []struct {
+		name          string
+		body          fiber.Map
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: fiber.Map{"old_password": password, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateUserPasswordParams, _ db.UserAudit) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+
+						// Stamped by the app clock, like the token handed back, in UTC for the timestamp column
+						require.WithinDuration(t, time.Now(), arg.PasswordChangedAt, time.Second)
+						require.Equal(t, time.UTC, arg.PasswordChangedAt.Location())
+
+						updated := user
+						updated.HashedPassword = arg.HashedPassword
+						updated.PasswordChangedAt = arg.PasswordChangedAt
+						return updated, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.AccessToken)
+				require.Equal(t, user.Username, resp.User.Username)
+			},
+		},
+		{
+			name: "WrongOldPassword",
+			body: fiber.Map{"old_password": "wrongpassword", "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().
+					RecordUserLoginFailure(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(int32(1), nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WeakNewPassword",
+			body: fiber.Map{"old_password": password, "new_password": "short"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeWeakPassword)
+			},
+		},
+		{
+			name: "MissingOldPassword",
+			body: fiber.Map{"new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: fiber.Map{"old_password": password, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthAs(store, user)
+			stubLoginIPAllowed(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodPut, "/users/me/password", tc.body, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestDeleteCurrentUserAPI
+// ---------------------------
+
+func TestDeleteCurrentUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				deleted := user
+				deleted.DeletedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+				store.EXPECT().
+					DeleteUserTx(gomock.Any(), gomock.Eq(user.Username), gomock.Any()).
+					Times(1).
+					Return(deleted, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NonZeroBalance",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteUserTx(gomock.Any(), gomock.Eq(user.Username), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrUserHasBalance)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeNonZeroBalance)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteUserTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAudit(store)
+			stubAuthAs(store, user)
+
+			server := newFiberTestServer(t, store)
+			recorder := doJSONRequest(t, server, http.MethodDelete, "/users/me", nil, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			})
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
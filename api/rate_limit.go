@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/ratelimit"  // Request counting
+)
+
+// Route groups with a budget of their own. A request counts against every group its
+// route belongs to: a transfer uses up both the api and the transfers budget.
+const (
+	rateLimitAPI       = "api"       // Every authenticated route
+	rateLimitAuth      = "auth"      // Sign-up, login, password reset and email verification
+	rateLimitTransfers = "transfers" // POST /transfers
+)
+
+// IETF RateLimit header fields, see draft-ietf-httpapi-ratelimit-headers
+const (
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// rateLimit returns the middleware spending the budget of requests per window that
+// group allows each client: the authenticated user, or the client IP before
+// authentication. Requests over budget are refused with a 429 and Retry-After. When the
+// store can't be reached requests are let through rather than failing the API.
+func (server *Server) rateLimit(group string, requests int) fiber.Handler {
+	if server.limiter == nil || requests <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	limit := ratelimit.Limit{Requests: int64(requests), Window: server.rateLimitWindow()}
+
+	return func(c *fiber.Ctx) error {
+		result, err := server.limiter.Allow(c.UserContext(), group+":"+rateLimitClient(c), limit)
+		if err != nil {
+			slog.WarnContext(c.UserContext(), "cannot check rate limit", "group", group, "error", err)
+			return c.Next()
+		}
+
+		resetAfter := int64(math.Ceil(time.Until(result.Reset).Seconds()))
+		setRateLimitHeaders(c, result, resetAfter)
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(resetAfter, 10))
+			return newAPIError(fiber.StatusTooManyRequests, errCodeRateLimited,
+				fmt.Sprintf("more than %d requests per %s, try again later", limit.Requests, limit.Window))
+		}
+		return c.Next()
+	}
+}
+
+// rateLimitClient identifies who a request's budget belongs to. Behind a load balancer
+// the IP is the one it reports, so clients don't share the balancer's budget.
+func rateLimitClient(c *fiber.Ctx) string {
+	if user, ok := c.Locals(authorizationUserKey).(db.User); ok {
+		return "user:" + user.Username
+	}
+	return "ip:" + clientIP(c)
+}
+
+// setRateLimitHeaders describes the budget in RateLimit headers. A request under several
+// budgets reports the one closest to running out.
+func setRateLimitHeaders(c *fiber.Ctx, result ratelimit.Result, resetAfter int64) {
+	if previous := c.GetRespHeader(headerRateLimitRemaining); previous != "" {
+		if remaining, err := strconv.ParseInt(previous, 10, 64); err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+
+	c.Set(headerRateLimitLimit, strconv.FormatInt(result.Limit.Requests, 10))
+	c.Set(headerRateLimitRemaining, strconv.FormatInt(result.Remaining, 10))
+	c.Set(headerRateLimitReset, strconv.FormatInt(resetAfter, 10))
+	c.Set(headerRateLimitPolicy, fmt.Sprintf("%d;w=%d", result.Limit.Requests, int64(result.Limit.Window.Seconds())))
+}
+
+// rateLimitWindow is the window every budget applies to
+func (server *Server) rateLimitWindow() time.Duration {
+	if server.config.RateLimitWindow <= 0 {
+		return ratelimit.DefaultWindow
+	}
+	return server.config.RateLimitWindow
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No request below reaches the database: each fails validation once let through
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey:  util.RandomString(32),
+		RateLimitWindow:    time.Hour,
+		RateLimitDefault:   4,
+		RateLimitAuth:      2,
+		RateLimitTransfers: 2,
+	}, store)
+	require.NoError(t, err)
+
+	authAs := func(username string) func(*http.Request) {
+		return func(req *http.Request) {
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+		}
+	}
+	requireBudget := func(resp *httptest.ResponseRecorder, limit, remaining int) {
+		require.Equal(t, strconv.Itoa(limit), resp.Header().Get(headerRateLimitLimit))
+		require.Equal(t, strconv.Itoa(remaining), resp.Header().Get(headerRateLimitRemaining))
+		require.Equal(t, strconv.Itoa(limit)+";w=3600", resp.Header().Get(headerRateLimitPolicy))
+
+		reset, err := strconv.Atoi(resp.Header().Get(headerRateLimitReset))
+		require.NoError(t, err)
+		require.True(t, reset > 0 && reset <= 3600)
+	}
+
+	// 1. Anonymous routes draw from the client IP's auth budget
+	for remaining := 1; remaining >= 0; remaining-- {
+		resp := doJSONRequest(t, server, fiber.MethodPost, "/users/password_reset", fiber.Map{"email": "nope"}, nil)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		requireBudget(resp, 2, remaining)
+	}
+	resp := doJSONRequest(t, server, fiber.MethodPost, "/users/password_reset", fiber.Map{"email": "nope"}, nil)
+	requireProblem(t, resp, http.StatusTooManyRequests, errCodeRateLimited)
+	require.NotEmpty(t, resp.Header().Get(fiber.HeaderRetryAfter))
+
+	// 2. Transfers spend both the api and the tighter transfers budget; the headers
+	// report the one closest to running out
+	alice := util.RandomOwner()
+	for remaining := 1; remaining >= 0; remaining-- {
+		resp := doJSONRequest(t, server, fiber.MethodPost, "/transfers", fiber.Map{}, authAs(alice))
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		requireBudget(resp, 2, remaining)
+	}
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/transfers", fiber.Map{}, authAs(alice))
+	requireProblem(t, resp, http.StatusTooManyRequests, errCodeRateLimited)
+
+	// 3. Other routes still have what is left of the api budget
+	resp = doJSONRequest(t, server, fiber.MethodGet, "/accounts", nil, authAs(alice))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	requireBudget(resp, 4, 0)
+	resp = doJSONRequest(t, server, fiber.MethodGet, "/accounts", nil, authAs(alice))
+	requireProblem(t, resp, http.StatusTooManyRequests, errCodeRateLimited)
+
+	// 4. Each user has budgets of their own, whatever their IP
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/transfers", fiber.Map{}, authAs(util.RandomOwner()))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	requireBudget(resp, 2, 1)
+}
+
+func TestRateLimitBehindProxy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		TrustedProxies:    testRemoteIP,
+		RateLimitWindow:   time.Hour,
+		RateLimitAuth:     1,
+	}, mockdb.NewMockStore(ctrl))
+	require.NoError(t, err)
+
+	forwardedFor := func(ip string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		}
+	}
+
+	// Clients behind the same load balancer have budgets of their own
+	resp := doJSONRequest(t, server, fiber.MethodPost, "/users/password_reset", fiber.Map{"email": "nope"}, forwardedFor("203.0.113.7"))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/users/password_reset", fiber.Map{"email": "nope"}, forwardedFor("203.0.113.7"))
+	requireProblem(t, resp, http.StatusTooManyRequests, errCodeRateLimited)
+
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/users/password_reset", fiber.Map{"email": "nope"}, forwardedFor("198.51.100.1"))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newFiberTestServer(t, mockdb.NewMockStore(ctrl))
+
+	resp := doJSONRequest(t, server, fiber.MethodPost, "/users/password_reset", fiber.Map{"email": "nope"}, nil)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Empty(t, resp.Header().Get(headerRateLimitLimit))
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
+)
+
+// recentWriters remembers which users changed data (made a transfer, opened an
+// account, ...) in the last window, so their next reads can skip the replica
+type recentWriters struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	lastWrite map[string]time.Time
+	lastSweep time.Time
+}
+
+func newRecentWriters(window time.Duration) *recentWriters {
+	return &recentWriters{
+		window:    window,
+		lastWrite: make(map[string]time.Time),
+	}
+}
+
+// record notes that username has just written
+func (writers *recentWriters) record(username string, now time.Time) {
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+
+	writers.lastWrite[username] = now
+
+	// Forget users whose window has passed, at most once per window
+	if now.Sub(writers.lastSweep) >= writers.window {
+		for name, at := range writers.lastWrite {
+			if now.Sub(at) >= writers.window {
+				delete(writers.lastWrite, name)
+			}
+		}
+		writers.lastSweep = now
+	}
+}
+
+// recent reports whether username wrote within the window before now
+func (writers *recentWriters) recent(username string, now time.Time) bool {
+	writers.mu.Lock()
+	defer writers.mu.Unlock()
+
+	at, ok := writers.lastWrite[username]
+	return ok && now.Sub(at) < writers.window
+}
+
+// readYourWrites returns the middleware that sends the reads of a user who has just
+// changed data to the primary database for DB_REPLICA_STICKINESS, so a transfer is in
+// the balances and history the user loads next even before it reaches the read
+// replica. Writes are remembered per server instance. Without a replica it does nothing.
+func (server *Server) readYourWrites() fiber.Handler {
+	if server.config.DBReplicaSource == "" || server.config.ReplicaStickiness <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	writers := newRecentWriters(server.config.ReplicaStickiness)
+
+	return func(c *fiber.Ctx) error {
+		payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+		if !ok {
+			return c.Next()
+		}
+
+		// 1. Users who wrote recently read from the primary
+		if writers.recent(payload.Username, time.Now()) {
+			c.SetUserContext(db.WithPrimary(c.UserContext()))
+		}
+
+		err := c.Next()
+
+		// 2. Remember successful writes
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		default:
+			if err == nil && c.Response().StatusCode() < fiber.StatusBadRequest {
+				writers.record(payload.Username, time.Now())
+			}
+		}
+		return err
+	}
+}
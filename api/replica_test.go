@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// primaryContext matches contexts whose reads go to the primary, or to the replica
+type primaryContext bool
+
+func (m primaryContext) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && db.ReadsFromPrimary(ctx) == bool(m)
+}
+
+func (m primaryContext) String() string {
+	return fmt.Sprintf("context reading from primary: %t", bool(m))
+}
+
+func TestReadYourWrites(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		DBReplicaSource:   "postgresql://replica:5432/simple_bank",
+		ReplicaStickiness: time.Minute,
+	}, store)
+	require.NoError(t, err)
+
+	writer := util.RandomOwner()
+	reader := util.RandomOwner()
+	account := randomAccount(writer)
+	authAs := func(username string) func(*http.Request) {
+		return func(req *http.Request) {
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+		}
+	}
+
+	listAccounts := "/accounts?page_id=1&page_size=5"
+
+	// 1. Before writing, reads may be served by the replica
+	store.EXPECT().ListAccounts(primaryContext(false), gomock.Any()).Return([]db.Account{account}, nil)
+	resp := doJSONRequest(t, server, fiber.MethodGet, listAccounts, nil, authAs(writer))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 2. A rejected write doesn't count
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/accounts", fiber.Map{"currency": "XYZ"}, authAs(writer))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	store.EXPECT().ListAccounts(primaryContext(false), gomock.Any()).Return([]db.Account{account}, nil)
+	resp = doJSONRequest(t, server, fiber.MethodGet, listAccounts, nil, authAs(writer))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 3. After opening an account, the writer reads from the primary
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(account, nil)
+	resp = doJSONRequest(t, server, fiber.MethodPost, "/accounts", fiber.Map{"currency": account.Currency}, authAs(writer))
+	require.Equal(t, http.StatusOK, resp.Code)
+	store.EXPECT().ListAccounts(primaryContext(true), gomock.Any()).Return([]db.Account{account}, nil)
+	resp = doJSONRequest(t, server, fiber.MethodGet, listAccounts, nil, authAs(writer))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 4. Other users still read from the replica
+	store.EXPECT().ListAccounts(primaryContext(false), gomock.Any()).Return([]db.Account{}, nil)
+	resp = doJSONRequest(t, server, fiber.MethodGet, listAccounts, nil, authAs(reader))
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRecentWriters(t *testing.T) {
+	writers := newRecentWriters(time.Minute)
+	now := time.Now()
+
+	require.False(t, writers.recent("alice", now))
+
+	writers.record("alice", now)
+	require.True(t, writers.recent("alice", now.Add(59*time.Second)))
+	require.False(t, writers.recent("alice", now.Add(time.Minute)))
+	require.False(t, writers.recent("bob", now))
+
+	// Expired writers are forgotten on a later write
+	writers.record("bob", now.Add(2*time.Minute))
+	require.NotContains(t, writers.lastWrite, "alice")
+	require.Contains(t, writers.lastWrite, "bob")
+}
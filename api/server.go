@@ -1,16 +1,26 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/go-playground/validator/v10"        // For custom request validation
-	"github.com/gofiber/fiber/v2"                   // Fiber web framework
-	"github.com/gofiber/fiber/v2/middleware/cors"   // ✅ CORS middleware
-	"github.com/gofiber/fiber/v2/middleware/logger" // Request logging middleware
+	"github.com/go-playground/validator/v10"      // For custom request validation
+	"github.com/gofiber/fiber/v2"                 // Fiber web framework
+	"github.com/gofiber/fiber/v2/middleware/cors" // ✅ CORS middleware
 
 	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/notify"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/ratelimit"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/sso"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/worker"
 
 	"github.com/gofiber/swagger"                                  // swagger handler
 	_ "github.com/nibir1/go-fiber-postgres-REST-boilerplate/docs" // generated docs
@@ -35,6 +45,19 @@ type Server struct {
 	tokenMaker token.Maker         // Token maker for JWT/Paseto
 	app        *fiber.App          // Fiber app instance (routes + middleware)
 	validate   *validator.Validate // Validator for custom request validations
+	hub        *notify.Hub         // Fan-out of ledger notifications to streaming clients
+	hasher     util.PasswordHasher // Hashes new passwords with the configured algorithm
+	policy     util.PasswordPolicy // Rules new passwords must follow
+	dummyHash  string              // Checked for unknown usernames so they take as long to reject as wrong passwords
+	sso        *sso.Provider       // Single sign-on provider; nil when OIDC_ISSUER_URL is not set
+	limiter    *ratelimit.Limiter  // Spends the per-client request budgets of the route groups
+	resolver   webhook.Resolver    // Resolves webhook hosts to check they are public
+
+	rootAliasSunset time.Time     // When the unversioned aliases of /v1 go away; zero when not announced
+	workers         *worker.Group // Background jobs reported by /readyz; nil when none run in this process
+	draining        atomic.Bool   // Set once a shutdown begins, failing /readyz
+	done            chan struct{} // Closed once a shutdown begins, ending open streams
+	closeDone       sync.Once     // Closes done once, however many times Shutdown is called
 }
 
 // ---------------------------
@@ -49,15 +72,71 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	// Password hashing and the rules new passwords must follow
+	hasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	policy, err := util.NewPasswordPolicy(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load password policy: %w", err)
+	}
+	dummyHash, err := hasher.Hash("not-a-real-password")
+	if err != nil {
+		return nil, err
+	}
+
+	// Single sign-on through an OpenID Connect provider, when one is configured
+	var ssoProvider *sso.Provider
+	if config.OIDCIssuerURL != "" {
+		ssoProvider, err = sso.NewProvider(context.Background(), config)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create sso provider: %w", err)
+		}
+	}
+
+	// Request counts for rate limiting, in memory or shared through Postgres
+	rateLimits, err := ratelimit.NewStore(config, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limit store: %w", err)
+	}
+
+	// The unversioned routes are deprecated in favour of /v1
+	var rootAliasSunset time.Time
+	if config.RootAliasSunset != "" {
+		rootAliasSunset, err = time.Parse(time.DateOnly, config.RootAliasSunset)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse ROOT_ALIAS_SUNSET: %w", err)
+		}
+	}
+
+	// Load balancers allowed to report the address of the clients they forward for
+	proxies, err := newTrustedProxies(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse TRUSTED_PROXIES: %w", err)
+	}
+
 	// Create a new Fiber app
-	app := fiber.New(fiber.Config{})
+	// Errors returned by handlers are answered with RFC 7807 problem details
+	app := fiber.New(fiber.Config{
+		ErrorHandler:            errorHandler,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          proxies.list(),
+		ProxyHeader:             proxies.header,
+	})
 
 	// ---------------------------
 	// Global Middlewares
 	// ---------------------------
 
-	// Request logger (prints each request)
-	app.Use(logger.New())
+	// The client address, seen past trusted proxies
+	app.Use(clientAddress(proxies))
+
+	// Request IDs, then a JSON log line for each request carrying its ID
+	app.Use(requestID())
+	app.Use(traceRequests())
+	app.Use(httpMetrics())
+	app.Use(requestLogger())
 
 	// ✅ Enable CORS for frontend communication
 	allowedOrigins := config.AllowedOrigins
@@ -68,18 +147,39 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		ExposeHeaders:    "Content-Length, Content-Type",
+		ExposeHeaders:    "Content-Length, Content-Type, X-Request-ID, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Deprecation, Sunset, Link",
 		AllowCredentials: true,
 	}))
 
 	// Initialize validator for request validation
 	validate := validator.New()
 
+	// Name invalid fields as clients send them (JSON, query or path names)
+	validate.RegisterTagNameFunc(fieldName)
+
 	// Register custom validation for currency fields
 	validate.RegisterValidation("currency", validCurrency)
 
+	// Register custom validation for account type fields
+	validate.RegisterValidation("account_type", validAccountType)
+
+	// Register custom validation for webhook event types
+	validate.RegisterValidation("webhook_event", validWebhookEvent)
+
+	// Register custom validation for webhook URLs
+	validate.RegisterValidation("webhook_url", validWebhookURL)
+
+	// Register custom validation for API key scopes
+	validate.RegisterValidation("api_scope", validAPIKeyScope)
+
+	// Register custom validation for usernames
+	validate.RegisterValidation("username", validUsername)
+
+	// Register custom validation for amounts of money
+	validate.RegisterValidation("amount", validAmount)
+
 	// Initialize server instance
 	server := &Server{
 		config:     config,
@@ -87,6 +187,16 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 		tokenMaker: tokenMaker,
 		app:        app,
 		validate:   validate,
+		hub:        notify.NewHub(),
+		hasher:     hasher,
+		policy:     policy,
+		dummyHash:  dummyHash,
+		sso:        ssoProvider,
+		limiter:    ratelimit.NewLimiter(rateLimits),
+		resolver:   net.DefaultResolver,
+
+		rootAliasSunset: rootAliasSunset,
+		done:            make(chan struct{}),
 	}
 
 	// Setup all API routes (public and protected)
@@ -99,32 +209,146 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 // Route Setup
 // ---------------------------
 
-// setUpRoutes defines all public and protected routes for the API server
+// setUpRoutes mounts the unversioned operational routes, then the API under /v1 and,
+// deprecated, at the root
 func (server *Server) setUpRoutes() {
 	app := server.app
 
 	// Swagger UI route
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
+	// Liveness and readiness probes
+	app.Get("/healthz", server.healthz)
+	app.Get("/readyz", server.readyz)
+
+	// The API, one group per version. The root aliases come last: their middleware
+	// sees every path, so the versioned routes must be found first.
+	server.mountAPI(app.Group(apiV1.prefix, useVersion(apiV1)))
+	server.mountAPI(app.Group("/", deprecatedAlias(apiV1, server.rootAliasSunset)))
+}
+
+// mountAPI defines all public and protected routes of the API on router. The handlers
+// serve every version, rendering responses in the shape of the request's apiVersion.
+func (server *Server) mountAPI(router fiber.Router) {
 	// ---------------------
 	// PUBLIC ROUTES
 	// ---------------------
-	app.Post("/users", server.createUser)
-	app.Post("/users/login", server.loginUser)
+	// Credential-handling routes share a per-IP budget against guessing and flooding
+	limitAuth := server.rateLimit(rateLimitAuth, server.config.RateLimitAuth)
+
+	router.Post("/users", limitAuth, server.createUser)
+	router.Post("/users/login", limitAuth, server.loginUser)
+	router.Post("/users/login/mfa", limitAuth, server.loginMFA)
+	router.Get("/users/login/oidc", limitAuth, server.startOIDCLogin)
+	router.Get("/users/login/oidc/callback", limitAuth, server.oidcCallback)
+	router.Post("/users/password_reset", limitAuth, server.requestPasswordReset)
+	router.Post("/users/password_reset/confirm", limitAuth, server.confirmPasswordReset)
+	router.Get("/users/verify_email", limitAuth, server.verifyEmail)
 
 	// ---------------------
 	// PROTECTED ROUTES
 	// ---------------------
-	auth := app.Group("/", authMiddlewareFiber(server.tokenMaker))
+	auth := routeGroup{router: router, handlers: []fiber.Handler{
+		authMiddlewareFiber(server.tokenMaker, server.store),
+		server.rateLimit(rateLimitAPI, server.config.RateLimitDefault),
+		server.readYourWrites(),
+	}}
 
 	// Account-related endpoints
-	auth.Post("/accounts", server.createAccount)
-	auth.Get("/accounts/:id", server.getAccount)
-	auth.Get("/accounts", server.listAccount)
-	auth.Delete("/accounts/:id", server.deleteAccount)
+	auth.Post("/accounts", requireScope(util.ScopeAccountsWrite), requireVerifiedEmail(), server.createAccount)
+	auth.Get("/accounts/:id", requireScope(util.ScopeAccountsRead), server.getAccount)
+	auth.Get("/accounts", requireScope(util.ScopeAccountsRead), server.listAccount)
+	auth.Delete("/accounts/:id", requireScope(util.ScopeAccountsWrite), server.moneyMovement(), server.deleteAccount)
 
 	// Transfer-related endpoint
-	auth.Post("/transfers", server.createTransfer)
+	auth.Post("/transfers", requireScope(util.ScopeTransfersWrite), server.rateLimit(rateLimitTransfers, server.config.RateLimitTransfers),
+		server.moneyMovement(), requireVerifiedEmail(), server.createTransfer)
+
+	// Profile of the authenticated user
+	auth.Get("/users/me", requireScope(util.ScopeProfileRead), server.getCurrentUser)
+	auth.Patch("/users/me", rejectAPIKeys(), rejectImpersonation(), server.updateCurrentUser)
+	auth.Put("/users/me/password", rejectAPIKeys(), rejectImpersonation(), server.changePassword)
+	auth.Delete("/users/me", rejectAPIKeys(), rejectImpersonation(), server.deleteCurrentUser)
+
+	// Personal API keys, managed by the signed-in user only
+	auth.Post("/api_keys", rejectAPIKeys(), rejectImpersonation(), server.createAPIKey)
+	auth.Get("/api_keys", rejectAPIKeys(), server.listAPIKeys)
+	auth.Delete("/api_keys/:id", rejectAPIKeys(), rejectImpersonation(), server.deleteAPIKey)
+
+	// Two-factor authentication endpoints
+	auth.Post("/users/mfa/totp", rejectAPIKeys(), rejectImpersonation(), server.enrollTOTP)
+	auth.Post("/users/mfa/totp/confirm", rejectAPIKeys(), rejectImpersonation(), server.confirmTOTP)
+
+	// Banker-only user administration
+	auth.Post("/users/:username/unlock", rejectAPIKeys(), requireRole(util.BankerRole), server.unlockUser)
+	auth.Post("/users/:username/impersonate", rejectAPIKeys(), requireRole(util.BankerRole), server.impersonateUser)
+
+	// Banker-only audit log
+	auth.Get("/audit_events", rejectAPIKeys(), requireRole(util.BankerRole), server.listAuditEvents)
+	auth.Get("/audit_events/export", rejectAPIKeys(), requireRole(util.BankerRole), server.exportAuditEvents)
+	auth.Get("/audit_events/verify", rejectAPIKeys(), requireRole(util.BankerRole), server.verifyAuditChain)
+
+	// Webhook-related endpoints; registering where a user's data is sent is theirs alone
+	auth.Post("/webhooks", rejectAPIKeys(), rejectImpersonation(), server.createWebhook)
+	auth.Get("/webhooks", requireScope(util.ScopeWebhooksRead), server.listWebhooks)
+	auth.Delete("/webhooks/:id", rejectAPIKeys(), rejectImpersonation(), server.deleteWebhook)
+	auth.Get("/webhooks/:id/deliveries", requireScope(util.ScopeWebhooksRead), server.listWebhookDeliveries)
+	auth.Post("/webhooks/:id/deliveries/:delivery_id/redeliver", rejectAPIKeys(), rejectImpersonation(), server.redeliverWebhook)
+
+	// Real-time notification stream
+	auth.Get("/notifications/stream", requireScope(util.ScopeNotificationsRead), server.streamNotifications)
+}
+
+// routeGroup registers routes on a router behind shared middleware. Unlike a Fiber group,
+// whose middleware runs for every path under its prefix, the middleware only runs for
+// requests matching one of the group's routes, so unknown paths get 404 rather than 401.
+type routeGroup struct {
+	router   fiber.Router
+	handlers []fiber.Handler
+}
+
+func (group routeGroup) add(method, path string, handlers ...fiber.Handler) {
+	group.router.Add(method, path, append(slices.Clone(group.handlers), handlers...)...)
+}
+
+// Get registers a GET route behind the group's middleware
+func (group routeGroup) Get(path string, handlers ...fiber.Handler) {
+	group.add(fiber.MethodGet, path, handlers...)
+}
+
+// Post registers a POST route behind the group's middleware
+func (group routeGroup) Post(path string, handlers ...fiber.Handler) {
+	group.add(fiber.MethodPost, path, handlers...)
+}
+
+// Put registers a PUT route behind the group's middleware
+func (group routeGroup) Put(path string, handlers ...fiber.Handler) {
+	group.add(fiber.MethodPut, path, handlers...)
+}
+
+// Patch registers a PATCH route behind the group's middleware
+func (group routeGroup) Patch(path string, handlers ...fiber.Handler) {
+	group.add(fiber.MethodPatch, path, handlers...)
+}
+
+// Delete registers a DELETE route behind the group's middleware
+func (group routeGroup) Delete(path string, handlers ...fiber.Handler) {
+	group.add(fiber.MethodDelete, path, handlers...)
+}
+
+// moneyMovement returns the middleware guarding routes that move money: requests made
+// while impersonating a user are refused unless IMPERSONATION_MONEY_MOVEMENT is set
+func (server *Server) moneyMovement() fiber.Handler {
+	if server.config.ActAsMoneyMovement {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	return rejectImpersonation()
+}
+
+// Notifications returns the hub streaming clients subscribe to, so it can be fed
+// by a notify.Listener
+func (server *Server) Notifications() *notify.Hub {
+	return server.hub
 }
 
 // ---------------------------
@@ -136,13 +360,18 @@ func (server *Server) Start(address string) error {
 	return server.app.Listen(address)
 }
 
-// ---------------------------
-// Helper: Error Response
-// ---------------------------
+// SetWorkers registers the background jobs whose state /readyz reports
+func (server *Server) SetWorkers(workers *worker.Group) {
+	server.workers = workers
+}
 
-// errorResponse standardizes JSON error responses for Fiber
-func errorResponse(err error) fiber.Map {
-	return fiber.Map{
-		"error": err.Error(),
-	}
+// Shutdown fails /readyz, ends the notification streams, stops accepting connections
+// and waits up to timeout for in-flight requests to finish before closing the rest.
+// Streams never finish on their own, so they are ended first or every shutdown would
+// wait out the whole timeout.
+func (server *Server) Shutdown(timeout time.Duration) error {
+	server.draining.Store(true)
+	server.closeDone.Do(func() { close(server.done) })
+	server.hub.Close()
+	return server.app.ShutdownWithTimeout(timeout)
 }
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider keeping finished spans in memory for the
+// duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// ---------------------------
+// TestTraceRequests
+// ---------------------------
+
+func TestTraceRequests(t *testing.T) {
+	spans := recordSpans(t)
+	user, _ := randomUser(t)
+	accountID := randomAccount(user.Username).ID
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(accountID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, _ int64) (db.Account, error) {
+			// The store is handed the request's span, so its query spans nest under it
+			require.True(t, trace.SpanContextFromContext(ctx).IsValid())
+			return db.Account{}, db.ErrRecordNotFound
+		})
+	stubAuthUser(store)
+
+	server := newFiberTestServer(t, store)
+	recorder := doJSONRequest(t, server, http.MethodGet, fmt.Sprintf("/accounts/%d", accountID), nil, func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	})
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// One server span named after the route, continuing the caller's trace
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	span := ended[0]
+	require.Equal(t, "GET /accounts/:id", span.Name())
+	require.Equal(t, trace.SpanKindServer, span.SpanKind())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+}
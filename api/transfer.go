@@ -1,27 +1,47 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"      // Token handling (JWT/Paseto)
-	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 )
 
 // ---------------------------
-// Request Structs
+// Request and Response Structs
 // ---------------------------
 
 // transferRequest represents the expected JSON body for creating a transfer
 // @Description Transfer request payload
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"` // ID of sender account, must be > 0
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`   // ID of receiver account, must be > 0
-	Amount        int64  `json:"amount" binding:"required,gt=0"`           // Transfer amount, must be > 0
-	Currency      string `json:"currency" binding:"required,currency"`     // Currency, validated by custom validator
+	FromAccountID int64  `json:"from_account_id" validate:"required,min=1"` // ID of sender account, must be > 0
+	ToAccountID   int64  `json:"to_account_id" validate:"required,min=1"`   // ID of receiver account, must be > 0
+	Amount        int64  `json:"amount" validate:"required,amount"`         // Transfer amount, validated by custom validator
+	Currency      string `json:"currency" validate:"required,currency"`     // Currency, validated by custom validator
+	MFACode       string `json:"mfa_code"`                                  // TOTP or recovery code, required above the step-up amount
+}
+
+// transferResponse is the result of a transfer as /v1 returns it
+// @Description Transfer response payload
+type transferResponse struct {
+	Transfer    db.Transfer     `json:"transfer"`     // The transfer record
+	FromAccount accountResponse `json:"from_account"` // Sender account after the transfer
+	ToAccount   accountResponse `json:"to_account"`   // Receiver account after the transfer
+	FromEntry   db.Entry        `json:"from_entry"`   // Entry debiting the sender
+	ToEntry     db.Entry        `json:"to_entry"`     // Entry crediting the receiver
+}
+
+// newTransferResponse converts db.TransferTxResult struct into a transferResponse for API response
+func newTransferResponse(result db.TransferTxResult) transferResponse {
+	return transferResponse{
+		Transfer:    result.Transfer,
+		FromAccount: newAccountResponse(result.FromAccount),
+		ToAccount:   newAccountResponse(result.ToAccount),
+		FromEntry:   result.FromEntry,
+		ToEntry:     result.ToEntry,
+	}
 }
 
 // ---------------------------
@@ -29,30 +49,42 @@ type transferRequest struct {
 // ---------------------------
 
 // validAccount validates that an account exists and has the correct currency
-func (server *Server) validAccount(c *fiber.Ctx, accountID int64, currency string) (db.Account, bool) {
+func (server *Server) validAccount(c *fiber.Ctx, accountID int64, currency string) (db.Account, error) {
 	// 1. Fetch account from the database
-	account, err := server.store.GetAccount(c.Context(), accountID)
+	account, err := server.store.GetAccount(c.UserContext(), accountID)
 	if err != nil {
 		// Account not found → 404 Not Found
-		if errors.Is(err, sql.ErrNoRows) {
-			_ = c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
-			return account, false
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, newAPIError(fiber.StatusNotFound, errCodeAccountNotFound, fmt.Sprintf("account [%d] not found", accountID))
 		}
 
 		// Other DB errors → 500 Internal Server Error
-		_ = c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
-		return account, false
+		return account, internalError(err)
 	}
 
 	// 2. Check if account currency matches the requested currency
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
-		_ = c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
-		return account, false
+		detail := fmt.Sprintf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		return account, newAPIError(fiber.StatusBadRequest, errCodeCurrencyMismatch, detail)
 	}
 
 	// 3. Account is valid
-	return account, true
+	return account, nil
+}
+
+// openAccount ensures the account's owner still exists; accounts of deleted users stay in
+// the ledger but can't receive money. TransferTx checks again under the account's row
+// lock, so a deletion racing the transfer is caught too.
+func (server *Server) openAccount(c *fiber.Ctx, account db.Account) error {
+	owner, err := server.store.GetUser(c.UserContext(), account.Owner)
+	if err != nil {
+		return internalError(err)
+	}
+
+	if owner.DeletedAt.Valid {
+		return newAPIError(fiber.StatusBadRequest, errCodeAccountClosed, fmt.Sprintf("account [%d] is closed", account.ID))
+	}
+	return nil
 }
 
 // ---------------------------
@@ -69,51 +101,46 @@ func (server *Server) validAccount(c *fiber.Ctx, accountID int64, currency strin
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Param        transfer  body      transferRequest  true  "Transfer details"
-// @Success      200       {object}  db.TransferTxResult
-// @Router       /transfers [post]
+// @Success      200       {object}  transferResponse
+// @Router       /v1/transfers [post]
 func (server *Server) createTransfer(c *fiber.Ctx) error {
 	// 1. Parse JSON request body into transferRequest struct
 	var req transferRequest
-	if err := c.BodyParser(&req); err != nil {
-		// Invalid request JSON → 400 Bad Request
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	if err := server.bind(c, &req); err != nil {
+		// Invalid JSON or fields → 400 Bad Request, before any DB access
+		return err
 	}
 
-	// 2. Validate input fields before any DB access
-	if req.Amount <= 0 {
-		err := errors.New("amount must be greater than zero")
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
-	}
-
-	if !util.IsSupportedCurrency(req.Currency) {
-		err := fmt.Errorf("unsupported currency: %s", req.Currency)
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
-	}
-
-	// 3. Validate "from" account exists and currency matches
-	fromAccount, valid := server.validAccount(c, req.FromAccountID, req.Currency)
-	if !valid {
-		// Error handled in validAccount → return immediately
-		return nil
+	// 2. Validate "from" account exists and currency matches
+	fromAccount, err := server.validAccount(c, req.FromAccountID, req.Currency)
+	if err != nil {
+		return err
 	}
 
-	// 4. Retrieve authenticated user from payload
+	// 3. Retrieve authenticated user from payload
 	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
 	if !ok || payload == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(fmt.Errorf("unauthorized")))
+		return unauthorized()
 	}
 	username := payload.Username
 
-	// 5. Ensure the "from" account belongs to the authenticated user
+	// 4. Ensure the "from" account belongs to the authenticated user
 	if fromAccount.Owner != username {
-		err := errors.New("from account doesn't belong to the authenticated user")
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(err))
+		return newAPIError(fiber.StatusUnauthorized, errCodeAccountNotOwned, "from account doesn't belong to the authenticated user")
 	}
 
-	// 6. Validate "to" account exists and currency matches
-	_, valid = server.validAccount(c, req.ToAccountID, req.Currency)
-	if !valid {
-		return nil // Error handled in validAccount
+	// 5. Validate "to" account exists, currency matches and its owner hasn't left
+	toAccount, err := server.validAccount(c, req.ToAccountID, req.Currency)
+	if err != nil {
+		return err
+	}
+	if err := server.openAccount(c, toAccount); err != nil {
+		return err
+	}
+
+	// 6. Large transfers need a fresh second factor
+	if err := server.requireStepUp(c, username, req.Amount, req.MFACode); err != nil {
+		return err
 	}
 
 	// 7. Prepare arguments for the transfer transaction
@@ -123,13 +150,20 @@ func (server *Server) createTransfer(c *fiber.Ctx) error {
 		Amount:        req.Amount,
 	}
 
-	// 8. Execute transfer transaction using SQLC
-	result, err := server.store.TransferTx(c.Context(), arg)
+	// 8. Execute transfer transaction using SQLC, recording the audit event with it
+	result, err := server.store.TransferTx(c.UserContext(), arg, func(result db.TransferTxResult) db.AppendAuditEventTxParams {
+		return auditEvent(c, username, auditTransferCreated, auditTarget("transfer", result.Transfer.ID),
+			fiber.Map{"from_account": fromAccount, "to_account": toAccount}, result)
+	})
 	if err != nil {
+		// The receiver was deleted after openAccount looked → 400 Bad Request
+		if errors.Is(err, db.ErrAccountClosed) {
+			return newAPIError(fiber.StatusBadRequest, errCodeAccountClosed, fmt.Sprintf("account [%d] is closed", toAccount.ID))
+		}
 		// Transaction error → 500 Internal Server Error
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return internalError(err)
 	}
 
 	// 9. Return 200 OK with transaction result
-	return c.Status(fiber.StatusOK).JSON(result)
+	return c.Status(fiber.StatusOK).JSON(versionOf(c).transfer(result))
 }
@@ -1,12 +1,12 @@
 package api
 
 import (
-	"database/sql"
+	"context"
 	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/lib/pq"                                               // For Postgres-specific error handling
 	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Utility functions (e.g., password hashing)
 )
@@ -18,10 +18,10 @@ import (
 // createUserRequest represents the expected JSON body for creating a new user
 // @Description Create user request payload
 type createUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"` // Alphanumeric username, required
-	Password string `json:"password" binding:"required,min=6"`    // Password, min 6 chars
-	FullName string `json:"full_name" binding:"required"`         // Full name, required
-	Email    string `json:"email" binding:"required,email"`       // Email, required, must be valid
+	Username string `json:"username" validate:"required,username"` // Alphanumeric username, required
+	Password string `json:"password" validate:"required"`          // Password, see the password policy
+	FullName string `json:"full_name" validate:"required"`         // Full name, required
+	Email    string `json:"email" validate:"required,email"`       // Email, required, must be valid
 }
 
 // userResponse represents the JSON response returned for a user
@@ -30,6 +30,8 @@ type userResponse struct {
 	Username          string    `json:"username"`            // Username of user
 	FullName          string    `json:"full_name"`           // Full name of user
 	Email             string    `json:"email"`               // Email of user
+	Role              string    `json:"role"`                // depositor or banker
+	IsEmailVerified   bool      `json:"is_email_verified"`   // Whether the email address has been confirmed
 	PasswordChangedAt time.Time `json:"password_changed_at"` // Timestamp of last password change
 	CreatedAt         time.Time `json:"created_at"`          // Timestamp of user creation
 }
@@ -40,6 +42,8 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -48,8 +52,8 @@ func newUserResponse(user db.User) userResponse {
 // loginUserRequest represents the expected JSON body for logging in
 // @Description Login request payload
 type loginUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username string `json:"username" validate:"required,max=64"` // Any existing name, including ones made under older rules
+	Password string `json:"password" validate:"required"`        // Length is only checked by the password policy, when a password is set
 }
 
 // loginUserResponse represents the JSON response for login
@@ -67,31 +71,32 @@ type loginUserResponse struct {
 
 // CreateUser godoc
 // @Summary      Register a new user
-// @Description  Creates a new user with username, password, full name, and email
+// @Description  Creates a new user with username, password, full name, and email. A verification link is emailed; accounts and transfers need a verified email.
 // @Tags         Users
 // @Accept       json
 // @Produce      json
 // @Param        user  body      createUserRequest  true  "User info"
 // @Success      200   {object}  userResponse
-// @Router       /users [post]
+// @Failure      400   {object}  problemResponse
+// @Failure      403   {object}  problemResponse
+// @Failure      500   {object}  problemResponse
+// @Router       /v1/users [post]
 func (server *Server) createUser(c *fiber.Ctx) error {
 	// 1. Parse JSON request body into createUserRequest struct
 	var req createUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		// Invalid JSON → return 400 Bad Request
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	if err := server.bind(c, &req); err != nil {
+		// Invalid JSON or fields → return 400 Bad Request
+		return err
 	}
-
-	// Manual validation
-	if req.Username == "" || req.Password == "" || req.FullName == "" || req.Email == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(errors.New("missing required fields")))
+	if err := server.checkNewPassword(req.Username, req.Password); err != nil {
+		return err
 	}
 
 	// 2. Hash password
-	hashedPassword, err := util.HashPassword(req.Password)
+	hashedPassword, err := server.hasher.Hash(req.Password)
 	if err != nil {
 		// Error during hashing → return 500 Internal Server Error
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return internalError(err)
 	}
 
 	// 3. Prepare database arguments
@@ -102,17 +107,18 @@ func (server *Server) createUser(c *fiber.Ctx) error {
 		Email:          req.Email,
 	}
 
-	// 4. Create user in the database
-	user, err := server.store.CreateUser(c.Context(), arg)
+	// 4. Create user in the database, queueing the verification email
+	user, err := server.store.CreateUserTx(c.UserContext(), arg)
 	if err != nil {
 		// Handle Postgres-specific errors (unique constraint violation)
-		if pqErr, ok := err.(*pq.Error); ok {
-			if pqErr.Code.Name() == "unique_violation" {
-				return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+		if db.ErrorCode(err) == db.UniqueViolation {
+			if db.ConstraintName(err) == db.UsersEmailKey {
+				return &apiError{Status: fiber.StatusForbidden, Code: errCodeEmailInUse, Detail: "email address is already in use", Err: err}
 			}
+			return &apiError{Status: fiber.StatusForbidden, Code: errCodeUsernameTaken, Detail: "username is already taken", Err: err}
 		}
 		// Other errors → 500 Internal Server Error
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return internalError(err)
 	}
 
 	// 5. Build response
@@ -126,53 +132,114 @@ func (server *Server) createUser(c *fiber.Ctx) error {
 
 // LoginUser godoc
 // @Summary      Log in a user
-// @Description  Authenticates user credentials and returns a JWT access token
+// @Description  Authenticates user credentials and returns an access token. Users with two-factor authentication get an mfaChallengeResponse instead, to complete with POST /users/login/mfa. Every failure answers 401 "invalid credentials"; repeated failures lock the user and the client IP out with growing delays.
 // @Tags         Users
 // @Accept       json
 // @Produce      json
 // @Param        credentials  body      loginUserRequest  true  "Login credentials"
 // @Success      200  {object}  loginUserResponse
-// @Router       /users/login [post]
+// @Failure      401  {object}  problemResponse
+// @Failure      429  {object}  problemResponse
+// @Router       /v1/users/login [post]
 func (server *Server) loginUser(c *fiber.Ctx) error {
 	// 1. Parse request body into loginUserRequest
 	var req loginUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	if err := server.bind(c, &req); err != nil {
+		return err
 	}
 
-	// Manual validation
-	if req.Username == "" || req.Password == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(errors.New("missing required fields")))
+	// 2. Refuse clients locked out after too many failures
+	if err := server.checkLoginIP(c); err != nil {
+		return err
 	}
 
-	// 2. Retrieve user from DB by username
-	user, err := server.store.GetUser(c.Context(), req.Username)
+	// 3. Retrieve user from DB by username
+	user, err := server.store.GetUser(c.UserContext(), req.Username)
+	if err == nil && user.DeletedAt.Valid {
+		err = db.ErrRecordNotFound
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			// User not found → 404
-			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// Unknown and deleted users fail like wrong passwords, in about the same time
+			_ = util.CheckPassword(req.Password, server.dummyHash)
+			return server.failLogin(c, req.Username, nil)
 		}
 		// Other DB error → 500
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return internalError(err)
+	}
+
+	// 4. Verify password; a locked user is refused even with the right one
+	if err := util.CheckPassword(req.Password, user.HashedPassword); err != nil || isLocked(user.LockedUntil) {
+		return server.failLogin(c, user.Username, &user)
+	}
+
+	// Upgrade hashes made with an older algorithm or parameters while the password is at hand
+	server.rehashPassword(c.UserContext(), user, req.Password)
+
+	// 5. With 2FA enabled, hand out a challenge instead of an access token
+	mfa, err := server.store.GetUserMFA(c.UserContext(), user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return internalError(err)
+	}
+	if err == nil && mfa.IsEnabled {
+		return server.mfaChallenge(c, user.Username)
 	}
 
-	// 3. Verify password
-	if err := util.CheckPassword(req.Password, user.HashedPassword); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(err))
+	// 6. The login succeeded: forget earlier failures
+	if user, err = server.clearLoginFailures(c, user); err != nil {
+		return internalError(err)
 	}
 
-	// 4. Create access token
+	// 7. Create access token
 	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return internalError(err)
 	}
 
-	// 5. Build response
+	if err := server.audit(c, user.Username, auditUserLogin, auditTarget("user", user.Username), nil, fiber.Map{"method": "password"}); err != nil {
+		return err
+	}
+
+	// 8. Build response
 	resp := loginUserResponse{
 		AccessToken: accessToken,
 		User:        newUserResponse(user),
 	}
 
-	// 6. Return 200 OK with access token and user info
+	// 9. Return 200 OK with access token and user info
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
+
+// ---------------------------
+// Helper Functions
+// ---------------------------
+
+// checkNewPassword applies the password policy to a password being set, returning a
+// 400 problem saying which rule it breaks when it is refused
+func (server *Server) checkNewPassword(username, password string) error {
+	if err := server.policy.Validate(username, password); err != nil {
+		return newAPIError(fiber.StatusBadRequest, errCodeWeakPassword, err.Error())
+	}
+	return nil
+}
+
+// rehashPassword re-hashes a just-verified password when its stored hash is outdated.
+// Failures are only logged: the old hash still works, so the login goes ahead.
+func (server *Server) rehashPassword(ctx context.Context, user db.User, password string) {
+	if !server.hasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.Hash(password)
+	if err == nil {
+		// Matching on the old hash leaves the row alone if the password changed meanwhile
+		err = server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+			Username:          user.Username,
+			OldHashedPassword: user.HashedPassword,
+			NewHashedPassword: hashedPassword,
+		})
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "cannot rehash password", "username", user.Username, "error", err)
+	}
+}
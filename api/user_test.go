@@ -6,15 +6,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang/mock/gomock"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgconn"
 	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
 	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // ---------------------------
@@ -69,7 +72,7 @@ func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher
 
 func TestCreateUserAPI(t *testing.T) {
 	user := randomUserStruct()
-	password := util.RandomString(6)
+	password := util.RandomString(10)
 
 	testCases := []struct {
 		name          string
@@ -93,7 +96,7 @@ func TestCreateUserAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUserParams(arg, password)).
 					Times(1).
 					Return(user, nil)
 			},
@@ -112,9 +115,9 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, &pq.Error{Code: "23505"})
+					Return(db.User{}, &pgconn.PgError{Code: db.UniqueViolation})
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusForbidden, recorder.Code)
@@ -130,7 +133,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.User{}, sql.ErrConnDone)
 			},
@@ -138,6 +141,42 @@ func TestCreateUserAPI(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "PasswordTooShort",
+			body: fiber.Map{
+				"username":  user.Username,
+				"password":  "short",
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeWeakPassword)
+			},
+		},
+		{
+			name: "PasswordIsUsername",
+			body: fiber.Map{
+				"username":  "nahasat123",
+				"password":  "Nahasat123",
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), util.ErrPasswordIsUsername.Error())
+			},
+		},
 		{
 			name: "InvalidRequest",
 			body: fiber.Map{
@@ -145,7 +184,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
@@ -212,6 +251,11 @@ func TestLoginUserAPI(t *testing.T) {
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserMfa{}, db.ErrRecordNotFound)
+				expectAudit(store, user.Username, auditUserLogin, "user:"+user.Username)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -222,6 +266,66 @@ func TestLoginUserAPI(t *testing.T) {
 				require.NotEmpty(t, resp.AccessToken)
 			},
 		},
+		{
+			name: "RehashLegacyBcrypt",
+			body: fiber.Map{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				legacy := user
+				legacy.HashedPassword, _ = util.BcryptHasher{Cost: bcrypt.MinCost}.Hash(password)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(legacy, nil)
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RehashUserPasswordParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, legacy.HashedPassword, arg.OldHashedPassword)
+						require.True(t, strings.HasPrefix(arg.NewHashedPassword, "$argon2id$"))
+						require.NoError(t, util.CheckPassword(password, arg.NewHashedPassword))
+						return nil
+					})
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserMfa{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MFAChallenge",
+			body: fiber.Map{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				hashedPassword, _ := util.HashPassword(password)
+				user.HashedPassword = hashedPassword
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserMfa{Username: user.Username, IsEnabled: true}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var resp mfaChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.True(t, resp.MFARequired)
+				require.NotEmpty(t, resp.MFAToken)
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
 		{
 			name: "UserNotFound",
 			body: fiber.Map{
@@ -232,10 +336,45 @@ func TestLoginUserAPI(t *testing.T) {
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
-					Return(db.User{}, sql.ErrNoRows)
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().
+					RecordUserLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAudit(store, "", auditUserLoginFailed, "user:"+user.Username)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// Indistinguishable from a wrong password
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "FailedLoginNotAudited",
+			body: fiber.Map{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().
+					AppendAuditEventTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AuditEvent{}, sql.ErrConnDone)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+				// A failure that can't be recorded doesn't go unnoticed
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
 		{
@@ -251,11 +390,112 @@ func TestLoginUserAPI(t *testing.T) {
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().
+					RecordUserLoginFailure(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(int32(1), nil)
+				store.EXPECT().
+					LockUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "TooManyFailuresLocksUser",
+			body: fiber.Map{
+				"username": user.Username,
+				"password": "wrongpassword",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				hashedPassword, _ := util.HashPassword(password)
+				user.HashedPassword = hashedPassword
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().
+					RecordUserLoginFailure(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(int32(defaultLoginMaxAttempts), nil)
+				store.EXPECT().
+					LockUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.LockUserParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.True(t, arg.LockedUntil.Valid)
+						require.WithinDuration(t, time.Now().UTC().Add(defaultLoginLockoutBase), arg.LockedUntil.Time, time.Second)
+						return nil
+					})
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "LockedUserWithRightPassword",
+			body: fiber.Map{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				hashedPassword, _ := util.HashPassword(password)
+				locked := user
+				locked.HashedPassword = hashedPassword
+				locked.LockedUntil = sql.NullTime{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(locked, nil)
+				store.EXPECT().
+					RecordLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{FailedAttempts: 1}, nil)
+				store.EXPECT().
+					RecordUserLoginFailure(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(int32(1), nil)
+				store.EXPECT().
+					GetUserMFA(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "IPLocked",
+			body: fiber.Map{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginIPFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginIpFailure{
+						FailedAttempts: defaultLoginIPMaxAttempts,
+						LockedUntil:    sql.NullTime{Time: time.Now().UTC().Add(time.Minute), Valid: true},
+					}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
 		{
 			name: "InternalError",
 			body: fiber.Map{
@@ -295,6 +535,8 @@ func TestLoginUserAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			stubAudit(store)
+			stubLoginIPAllowed(store)
 
 			server := newFiberTestServer(t, store)
 			recorder := httptest.NewRecorder()
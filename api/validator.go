@@ -1,8 +1,9 @@
 package api
 
 import (
-	"github.com/go-playground/validator/v10"                    // Import the validator library for custom validations
-	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util" // Import utility functions
+	"github.com/go-playground/validator/v10"                       // Import the validator library for custom validations
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"    // Import utility functions
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook" // Webhook event types
 )
 
 // ---------------------------
@@ -11,7 +12,7 @@ import (
 
 // validCurrency is a custom validator function that checks whether a string
 // represents a supported currency in the system. It will be used in request
-// binding tags like `binding:"required,currency"` or Fiber's equivalent.
+// validation tags like `validate:"required,currency"`.
 var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	// Attempt to get the field's value as a string
 	if currency, ok := fieldLevel.Field().Interface().(string); ok {
@@ -22,3 +23,81 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	// If the field is not a string, validation fails
 	return false
 }
+
+// ---------------------------
+// Custom Account Type Validator
+// ---------------------------
+
+// validAccountType is a custom validator function that checks whether a string
+// is one of the supported account types (checking or savings).
+var validAccountType validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if accountType, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedAccountType(accountType)
+	}
+	return false
+}
+
+// ---------------------------
+// Custom Webhook Event Validator
+// ---------------------------
+
+// validWebhookEvent is a custom validator function that checks whether a string
+// is an event type webhooks can subscribe to (e.g. "transfer.created").
+var validWebhookEvent validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if eventType, ok := fieldLevel.Field().Interface().(string); ok {
+		return webhook.IsSupportedEventType(eventType)
+	}
+	return false
+}
+
+// ---------------------------
+// Custom Webhook URL Validator
+// ---------------------------
+
+// validWebhookURL is a custom validator function that checks whether a string is a
+// URL webhooks can be sent to: absolute, https and without credentials.
+var validWebhookURL validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if rawURL, ok := fieldLevel.Field().Interface().(string); ok {
+		return webhook.IsSecureURL(rawURL)
+	}
+	return false
+}
+
+// ---------------------------
+// Custom API Key Scope Validator
+// ---------------------------
+
+// validAPIKeyScope is a custom validator function that checks whether a string
+// is a scope API keys can be granted (e.g. "accounts:read").
+var validAPIKeyScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedScope(scope)
+	}
+	return false
+}
+
+// ---------------------------
+// Custom Username Validator
+// ---------------------------
+
+// validUsername is a custom validator function that checks whether a string is a
+// well-formed username: 3 to 64 ASCII letters and digits.
+var validUsername validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if username, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsValidUsername(username)
+	}
+	return false
+}
+
+// ---------------------------
+// Custom Amount Validator
+// ---------------------------
+
+// validAmount is a custom validator function that checks whether an integer is an
+// amount of money that can be moved: positive and at most util.MaxAmount.
+var validAmount validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if amount, ok := fieldLevel.Field().Interface().(int64); ok {
+		return util.IsValidAmount(amount)
+	}
+	return false
+}
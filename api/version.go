@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+)
+
+// apiVersionKey is the Fiber locals key of the apiVersion a request was made to
+const apiVersionKey = "api_version"
+
+// rootAliasesDeprecatedAt is when the unversioned routes were deprecated, sent as Deprecation
+var rootAliasesDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// rootAliasPrefixes are the route prefixes the API was served at before /v1. Only paths
+// under them get deprecated root aliases; routes added since exist under /v1 alone.
+var rootAliasPrefixes = []string{"/users", "/accounts", "/transfers", "/api_keys", "/audit_events", "/webhooks", "/notifications"}
+
+// apiVersion is one version of the API. Every version is served by the same handlers
+// and store layer; a version only decides where it is mounted and the JSON shape of the
+// records it returns. A /v2 sending money as {"amount", "currency"} objects would add
+// its own apiVersion with new renderers and mount it next to apiV1 in setUpRoutes.
+type apiVersion struct {
+	prefix   string                                // Path the version is mounted at
+	account  func(db.Account) interface{}          // Renders an account
+	transfer func(db.TransferTxResult) interface{} // Renders the result of a transfer
+}
+
+// apiV1 is the current version of the API
+var apiV1 = apiVersion{
+	prefix: "/v1",
+	account: func(account db.Account) interface{} {
+		return newAccountResponse(account)
+	},
+	transfer: func(result db.TransferTxResult) interface{} {
+		return newTransferResponse(result)
+	},
+}
+
+// useVersion returns the middleware of a version's route group, recording the version
+// for the handlers
+func useVersion(version apiVersion) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(apiVersionKey, version)
+		return c.Next()
+	}
+}
+
+// deprecatedAlias returns the middleware of the unversioned aliases of version's routes.
+// They behave exactly like version, but answer with a Deprecation header (RFC 9745), a
+// Sunset header (RFC 8594) when a removal date is set, and a Link to the versioned route.
+// The aliases are mounted at the root, so the middleware sees every path no earlier route
+// answered; anything outside rootAliasPrefixes, such as an unknown versioned path, is
+// passed on untouched.
+func deprecatedAlias(version apiVersion, sunset time.Time) fiber.Handler {
+	deprecation := fmt.Sprintf("@%d", rootAliasesDeprecatedAt.Unix())
+	return func(c *fiber.Ctx) error {
+		if !isRootAlias(c.Path(), version) {
+			return c.Next()
+		}
+
+		c.Locals(apiVersionKey, version)
+		c.Set("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Append(fiber.HeaderLink, fmt.Sprintf(`<%s%s>; rel="successor-version"`, version.prefix, c.OriginalURL()))
+		return c.Next()
+	}
+}
+
+// isRootAlias reports whether path is the unversioned alias of one of version's legacy routes
+func isRootAlias(path string, version apiVersion) bool {
+	if path == version.prefix || strings.HasPrefix(path, version.prefix+"/") {
+		return false
+	}
+	for _, prefix := range rootAliasPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// versionOf returns the apiVersion a request was made to
+func versionOf(c *fiber.Ctx) apiVersion {
+	if version, ok := c.Locals(apiVersionKey).(apiVersion); ok {
+		return version
+	}
+	return apiV1
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+func TestAPIVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		RootAliasSunset:   "2027-04-30",
+	}, store)
+	require.NoError(t, err)
+
+	owner := util.RandomOwner()
+	account := randomAccount(owner)
+	store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(2).Return(account, nil)
+	authAs := func(req *http.Request) {
+		addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, owner, time.Minute)
+	}
+	path := fmt.Sprintf("/accounts/%d", account.ID)
+
+	// 1. /v1 is current
+	resp := doJSONRequest(t, server, fiber.MethodGet, "/v1"+path, nil, authAs)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Empty(t, resp.Header().Get("Deprecation"))
+	require.Empty(t, resp.Header().Get("Sunset"))
+	requireBodyMatchAccount(t, resp.Body, account)
+
+	// 2. The root alias answers the same, marked as deprecated
+	resp = doJSONRequest(t, server, fiber.MethodGet, path, nil, authAs)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, fmt.Sprintf("@%d", rootAliasesDeprecatedAt.Unix()), resp.Header().Get("Deprecation"))
+	require.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", resp.Header().Get("Sunset"))
+	require.Equal(t, fmt.Sprintf(`</v1%s>; rel="successor-version"`, path), resp.Header().Get(fiber.HeaderLink))
+	requireBodyMatchAccount(t, resp.Body, account)
+
+	// 3. Errors are versioned too
+	resp = doJSONRequest(t, server, fiber.MethodGet, "/v1/accounts/0", nil, authAs)
+	problem := requireProblem(t, resp, http.StatusBadRequest, errCodeValidationFailed)
+	require.Equal(t, "/v1/accounts/0", problem.Instance)
+}
+
+func TestNewAPIVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newFiberTestServer(t, store)
+
+	// A version can change the shape of responses while sharing the handlers
+	type money struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	apiV2 := apiV1
+	apiV2.prefix = "/v2"
+	apiV2.account = func(account db.Account) interface{} {
+		return fiber.Map{"id": account.ID, "balance": money{Amount: account.Balance, Currency: account.Currency}}
+	}
+	server.app = fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	server.mountAPI(server.app.Group(apiV2.prefix, useVersion(apiV2)))
+
+	owner := util.RandomOwner()
+	account := randomAccount(owner)
+	store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+
+	resp := doJSONRequest(t, server, fiber.MethodGet, fmt.Sprintf("/v2/accounts/%d", account.ID), nil, func(req *http.Request) {
+		addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, owner, time.Minute)
+	})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var got struct {
+		ID      int64 `json:"id"`
+		Balance money `json:"balance"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	require.Equal(t, account.ID, got.ID)
+	require.Equal(t, money{Amount: account.Balance, Currency: account.Currency}, got.Balance)
+}
+
+func TestRootAliasesOnlyCoverLegacyRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubAuthUser(store)
+	server := newFiberTestServer(t, store)
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantStatus int
+		deprecated bool
+	}{
+		{name: "UnknownVersionedPath", path: "/v1/foo", wantStatus: http.StatusNotFound},
+		{name: "UnknownVersionedSubpath", path: "/v1/accounts/1/foo", wantStatus: http.StatusNotFound},
+		{name: "UnknownRootPath", path: "/foo", wantStatus: http.StatusNotFound},
+		{name: "LookalikePrefix", path: "/accountsfoo", wantStatus: http.StatusNotFound},
+		{name: "UnknownLegacySubpath", path: "/accounts/1/foo", wantStatus: http.StatusNotFound, deprecated: true},
+		{name: "LegacyRoute", path: "/accounts", wantStatus: http.StatusUnauthorized, deprecated: true},
+		{name: "VersionedRoute", path: "/v1/accounts", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Unauthenticated, so a path caught by the auth middleware would be 401 instead
+			resp := doJSONRequest(t, server, fiber.MethodGet, tc.path, nil, nil)
+			require.Equal(t, tc.wantStatus, resp.Code)
+
+			if tc.deprecated {
+				require.NotEmpty(t, resp.Header().Get("Deprecation"))
+				require.Equal(t, fmt.Sprintf(`</v1%s>; rel="successor-version"`, tc.path), resp.Header().Get(fiber.HeaderLink))
+			} else {
+				require.Empty(t, resp.Header().Get("Deprecation"))
+				require.Empty(t, resp.Header().Get(fiber.HeaderLink))
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC database package
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"      // Token handling (JWT/Paseto)
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook"    // Webhook signing
+)
+
+// ---------------------------
+// Request and Response Structs
+// ---------------------------
+
+// createWebhookRequest represents the expected JSON body for registering a webhook
+// @Description Webhook registration payload
+type createWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,webhook_url"`                      // https endpoint receiving POST callbacks
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,webhook_event"` // e.g. ["transfer.created"]
+}
+
+// webhookIDRequest defines the path parameter for a single webhook
+type webhookIDRequest struct {
+	ID int64 `params:"id" validate:"required,min=1"`
+}
+
+// redeliverWebhookRequest defines the path parameters for redelivering a webhook delivery
+type redeliverWebhookRequest struct {
+	ID         int64 `params:"id" validate:"required,min=1"`
+	DeliveryID int64 `params:"delivery_id" validate:"required,min=1"`
+}
+
+// listWebhookRequest represents query parameters for paginated webhook listings
+type listWebhookRequest struct {
+	PageID   int `query:"page_id" validate:"required,min=1"`          // Current page number, min 1
+	PageSize int `query:"page_size" validate:"required,min=5,max=10"` // Items per page, 5–10
+}
+
+// webhookResponse represents a webhook subscription returned by the API
+// @Description Webhook subscription
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	Secret     string    `json:"secret,omitempty"` // Signing secret, only returned when the webhook is created
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// newWebhookResponse converts a db.WebhookSubscription without its secret
+func newWebhookResponse(subscription db.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		IsActive:   subscription.IsActive,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+// webhookDeliveryResponse represents one entry of a webhook's delivery log
+// @Description Webhook delivery attempt log entry
+type webhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"` // pending, succeeded or dead
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int32     `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// newWebhookDeliveryResponse converts a db.WebhookDelivery for the API
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:            delivery.ID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		CreatedAt:     delivery.CreatedAt,
+	}
+	if delivery.LastStatusCode.Valid {
+		resp.LastStatusCode = &delivery.LastStatusCode.Int32
+	}
+	if delivery.LastError.Valid {
+		resp.LastError = &delivery.LastError.String
+	}
+	if delivery.DeliveredAt.Valid {
+		resp.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return resp
+}
+
+// ---------------------------
+// Helper Functions
+// ---------------------------
+
+// ownedWebhook loads a webhook subscription and checks it belongs to the authenticated user
+func (server *Server) ownedWebhook(c *fiber.Ctx, id int64) (db.WebhookSubscription, error) {
+	// 1. Retrieve authenticated user from payload
+	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || payload == nil {
+		return db.WebhookSubscription{}, unauthorized()
+	}
+
+	// 2. Fetch the subscription
+	subscription, err := server.store.GetWebhookSubscription(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return subscription, newAPIError(fiber.StatusNotFound, errCodeWebhookNotFound, "webhook not found")
+		}
+		return subscription, internalError(err)
+	}
+
+	// 3. Check ownership
+	if subscription.Owner != payload.Username {
+		return subscription, newAPIError(fiber.StatusUnauthorized, errCodeWebhookNotOwned, "webhook doesn't belong to the authenticated user")
+	}
+
+	return subscription, nil
+}
+
+// ---------------------------
+// Handlers
+// ---------------------------
+
+// createWebhook handles POST /webhooks endpoint
+
+// CreateWebhook godoc
+// @Summary      Register a webhook
+// @Description  Registers an https URL that receives signed POST callbacks for the given event types on the user's accounts. Hosts resolving to private, loopback or link-local addresses are refused, and deliveries don't follow redirects. The signing secret is only returned once.
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        webhook  body      createWebhookRequest  true  "Webhook details"
+// @Success      200      {object}  webhookResponse
+// @Failure      400      {object}  problemResponse
+// @Failure      401      {object}  problemResponse
+// @Failure      500      {object}  problemResponse
+// @Router       /v1/webhooks [post]
+func (server *Server) createWebhook(c *fiber.Ctx) error {
+	// 1. Parse and validate the request body
+	var req createWebhookRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Retrieve authenticated user from payload
+	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || payload == nil {
+		return unauthorized()
+	}
+
+	// 3. Refuse hosts on the server's own network; delivery checks again when dialling
+	if err := webhook.CheckURL(c.UserContext(), server.resolver, req.URL); err != nil {
+		if errors.Is(err, webhook.ErrUnresolvedHost) {
+			return invalidField("url", "webhook_url", "must have a host that resolves")
+		}
+		return invalidField("url", "webhook_url", "must not resolve to a private, loopback or link-local address")
+	}
+
+	// 4. Generate the signing secret
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 5. Store the subscription
+	subscription, err := server.store.CreateWebhookSubscription(c.UserContext(), db.CreateWebhookSubscriptionParams{
+		Owner:      payload.Username,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 6. Return the subscription, including the secret this one time
+	resp := newWebhookResponse(subscription)
+	resp.Secret = subscription.Secret
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// listWebhooks handles GET /webhooks endpoint
+
+// ListWebhooks godoc
+// @Summary      List webhooks
+// @Description  Returns a paginated list of the authenticated user's webhooks
+// @Tags         Webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        page_id    query  int  true  "Page number"
+// @Param        page_size  query  int  true  "Items per page"
+// @Success      200  {array}   webhookResponse
+// @Failure      400  {object}  problemResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/webhooks [get]
+func (server *Server) listWebhooks(c *fiber.Ctx) error {
+	// 1. Parse and validate query parameters
+	var req listWebhookRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Retrieve authenticated user from payload
+	payload, ok := c.Locals(authorizationPayloadKey).(*token.Payload)
+	if !ok || payload == nil {
+		return unauthorized()
+	}
+
+	// 3. Fetch the page
+	subscriptions, err := server.store.ListWebhookSubscriptions(c.UserContext(), db.ListWebhookSubscriptionsParams{
+		Owner:  payload.Username,
+		Limit:  int64(req.PageSize),
+		Offset: int64((req.PageID - 1) * req.PageSize),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// 4. Return the webhooks without their secrets
+	resp := make([]webhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resp = append(resp, newWebhookResponse(subscription))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// deleteWebhook handles DELETE /webhooks/:id endpoint
+
+// DeleteWebhook godoc
+// @Summary      Delete a webhook
+// @Description  Deletes a webhook and its delivery log. Must belong to authenticated user.
+// @Tags         Webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  problemResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      404  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/webhooks/{id} [delete]
+func (server *Server) deleteWebhook(c *fiber.Ctx) error {
+	// 1. Parse webhook ID from URL path
+	var req webhookIDRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Check existence and ownership
+	if _, err := server.ownedWebhook(c, req.ID); err != nil {
+		return err
+	}
+
+	// 3. Delete the subscription (deliveries cascade)
+	if err := server.store.DeleteWebhookSubscription(c.UserContext(), req.ID); err != nil {
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":   "Webhook deleted successfully",
+		"webhookID": req.ID,
+	})
+}
+
+// listWebhookDeliveries handles GET /webhooks/:id/deliveries endpoint
+
+// ListWebhookDeliveries godoc
+// @Summary      List webhook deliveries
+// @Description  Returns the delivery log of a webhook, newest first
+// @Tags         Webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id         path   int  true  "Webhook ID"
+// @Param        page_id    query  int  true  "Page number"
+// @Param        page_size  query  int  true  "Items per page"
+// @Success      200  {array}   webhookDeliveryResponse
+// @Failure      400  {object}  problemResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      404  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/webhooks/{id}/deliveries [get]
+func (server *Server) listWebhookDeliveries(c *fiber.Ctx) error {
+	// 1. Parse path and query parameters
+	var uri webhookIDRequest
+	if err := server.bind(c, &uri); err != nil {
+		return err
+	}
+	var req listWebhookRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Check existence and ownership
+	if _, err := server.ownedWebhook(c, uri.ID); err != nil {
+		return err
+	}
+
+	// 3. Fetch the page of the delivery log
+	deliveries, err := server.store.ListWebhookDeliveries(c.UserContext(), db.ListWebhookDeliveriesParams{
+		SubscriptionID: uri.ID,
+		Limit:          int64(req.PageSize),
+		Offset:         int64((req.PageID - 1) * req.PageSize),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(delivery))
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// redeliverWebhook handles POST /webhooks/:id/deliveries/:delivery_id/redeliver endpoint
+
+// RedeliverWebhook godoc
+// @Summary      Redeliver a webhook
+// @Description  Queues a delivery (including a dead-lettered one) to be sent again as soon as possible
+// @Tags         Webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id           path      int  true  "Webhook ID"
+// @Param        delivery_id  path      int  true  "Delivery ID"
+// @Success      200  {object}  webhookDeliveryResponse
+// @Failure      400  {object}  problemResponse
+// @Failure      401  {object}  problemResponse
+// @Failure      404  {object}  problemResponse
+// @Failure      500  {object}  problemResponse
+// @Router       /v1/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (server *Server) redeliverWebhook(c *fiber.Ctx) error {
+	// 1. Parse path parameters
+	var req redeliverWebhookRequest
+	if err := server.bind(c, &req); err != nil {
+		return err
+	}
+
+	// 2. Check existence and ownership of the webhook
+	if _, err := server.ownedWebhook(c, req.ID); err != nil {
+		return err
+	}
+
+	// 3. Ensure the delivery belongs to that webhook
+	delivery, err := server.store.GetWebhookDelivery(c.UserContext(), req.DeliveryID)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return internalError(err)
+	}
+	if err != nil || delivery.SubscriptionID != req.ID {
+		return newAPIError(fiber.StatusNotFound, errCodeDeliveryNotFound, "delivery not found")
+	}
+
+	// 4. Put it back in the queue
+	delivery, err = server.store.ResetWebhookDelivery(c.UserContext(), delivery.ID)
+	if err != nil {
+		return internalError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newWebhookDeliveryResponse(delivery))
+}
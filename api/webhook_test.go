@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/token"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+// randomWebhook creates a fake webhook subscription for testing
+func randomWebhook(owner string) db.WebhookSubscription {
+	return db.WebhookSubscription{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Url:        "https://example.com/hooks",
+		EventTypes: []string{db.EventTransferCreated},
+		Secret:     "whsec_" + util.RandomString(32),
+		IsActive:   true,
+	}
+}
+
+// staticResolver resolves every host to the same addresses, standing in for DNS
+type staticResolver []string
+
+func (resolver staticResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs := make([]netip.Addr, 0, len(resolver))
+	for _, addr := range resolver {
+		addrs = append(addrs, netip.MustParseAddr(addr))
+	}
+	return addrs, nil
+}
+
+// ---------------------------
+// TestCreateWebhookAPI
+// ---------------------------
+
+func TestCreateWebhookAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	subscription := randomWebhook(user.Username)
+
+	testCases := []struct {
+		name          string
+		body          fiber.Map
+		resolvesTo    staticResolver
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: fiber.Map{
+				"url":         subscription.Url,
+				"event_types": subscription.EventTypes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, subscription.Url, arg.Url)
+						require.Equal(t, subscription.EventTypes, arg.EventTypes)
+						require.NotEmpty(t, arg.Secret)
+						return subscription, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp webhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, subscription.ID, resp.ID)
+				require.Equal(t, subscription.Secret, resp.Secret)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: fiber.Map{
+				"url":         subscription.Url,
+				"event_types": subscription.EventTypes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidURL",
+			body: fiber.Map{
+				"url":         "not a url",
+				"event_types": subscription.EventTypes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PlainHTTP",
+			body: fiber.Map{
+				"url":         "http://example.com/hooks",
+				"event_types": subscription.EventTypes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PrivateHost",
+			body: fiber.Map{
+				"url":         subscription.Url,
+				"event_types": subscription.EventTypes,
+			},
+			resolvesTo: staticResolver{"169.254.169.254"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "private, loopback or link-local")
+			},
+		},
+		{
+			name: "InvalidEventType",
+			body: fiber.Map{
+				"url":         subscription.Url,
+				"event_types": []string{"user.created"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: fiber.Map{
+				"url":         subscription.Url,
+				"event_types": subscription.EventTypes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebhookSubscription{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			server.resolver = staticResolver{"93.184.215.14"}
+			if tc.resolvesTo != nil {
+				server.resolver = tc.resolvesTo
+			}
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+
+			tc.setupAuth(t, req, server.tokenMaker)
+
+			resp, err := server.app.Test(req, -1)
+			require.NoError(t, err)
+
+			bodyBytes := new(bytes.Buffer)
+			_, err = bodyBytes.ReadFrom(resp.Body)
+			require.NoError(t, err)
+			recorder.Body = bodyBytes
+			recorder.Code = resp.StatusCode
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// ---------------------------
+// TestRedeliverWebhookAPI
+// ---------------------------
+
+func TestRedeliverWebhookAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	subscription := randomWebhook(user.Username)
+	delivery := db.WebhookDelivery{
+		ID:             util.RandomInt(1, 1000),
+		SubscriptionID: subscription.ID,
+		EventID:        util.RandomInt(1, 1000),
+		EventType:      db.EventTransferCreated,
+		Status:         webhook.StatusDead,
+		Attempts:       8,
+	}
+
+	testCases := []struct {
+		name          string
+		webhookID     int64
+		deliveryID    int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			webhookID:  subscription.ID,
+			deliveryID: delivery.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				reset := delivery
+				reset.Status = webhook.StatusPending
+				reset.Attempts = 0
+
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ResetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(reset, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, webhook.StatusPending, resp.Status)
+			},
+		},
+		{
+			name:       "UnauthorizedUser",
+			webhookID:  subscription.ID,
+			deliveryID: delivery.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().ResetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "DeliveryOfAnotherWebhook",
+			webhookID:  subscription.ID,
+			deliveryID: delivery.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				other := delivery
+				other.SubscriptionID = subscription.ID + 1
+
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(other, nil)
+				store.EXPECT().ResetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "WebhookNotFound",
+			webhookID:  subscription.ID,
+			deliveryID: delivery.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(db.WebhookSubscription{}, db.ErrRecordNotFound)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubAuthUser(store)
+
+			server := newFiberTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/webhooks/%d/deliveries/%d/redeliver", tc.webhookID, tc.deliveryID)
+			req := httptest.NewRequest(http.MethodPost, url, nil)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+
+			resp, err := server.app.Test(req, -1)
+			require.NoError(t, err)
+
+			bodyBytes := new(bytes.Buffer)
+			_, err = bodyBytes.ReadFrom(resp.Body)
+			require.NoError(t, err)
+			recorder.Body = bodyBytes
+			recorder.Code = resp.StatusCode
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "interest_accruals";
+
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "owner_currency_type_key";
+ALTER TABLE IF EXISTS "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "type";
@@ -0,0 +1,25 @@
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT false;
+
+-- Users created before verification existed keep the access they already had
+UPDATE "users" SET "is_email_verified" = true;
+
+CREATE TABLE "email_verifications" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "token_hash" varchar UNIQUE,
+  "attempts" int NOT NULL DEFAULT 0,
+  "last_error" varchar,
+  "expires_at" timestamp,
+  "sent_at" timestamp,
+  "used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "email_verifications" ("username");
+
+CREATE INDEX ON "email_verifications" ("id") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "email_verifications"."token_hash" IS 'hex SHA-256 of the emailed token, set when the email is sent';
+
+ALTER TABLE "email_verifications" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
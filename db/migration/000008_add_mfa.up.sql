@@ -0,0 +1,26 @@
+CREATE TABLE "user_mfa" (
+  "username" varchar PRIMARY KEY,
+  "totp_secret" varchar NOT NULL,
+  "is_enabled" boolean NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "enabled_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "mfa_recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "mfa_recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "user_mfa"."last_used_step" IS 'TOTP time step of the last accepted code, so a code cannot be replayed';
+
+COMMENT ON COLUMN "mfa_recovery_codes"."code_hash" IS 'hex SHA-256 of the normalized recovery code';
+
+ALTER TABLE "user_mfa" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
+
+ALTER TABLE "mfa_recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "login_ip_failures";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "locked_until";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "failed_login_attempts";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,16 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+ALTER TABLE "users" ADD COLUMN "failed_login_attempts" int NOT NULL DEFAULT 0;
+
+ALTER TABLE "users" ADD COLUMN "locked_until" timestamp;
+
+CREATE TABLE "login_ip_failures" (
+  "ip" varchar PRIMARY KEY,
+  "failed_attempts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamp,
+  "updated_at" timestamp NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "users"."role" IS 'depositor or banker';
+
+COMMENT ON COLUMN "users"."failed_login_attempts" IS 'consecutive failed logins, reset on success or by a banker';
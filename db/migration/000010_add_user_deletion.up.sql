@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "deleted_at" timestamp;
+
+COMMENT ON COLUMN "users"."deleted_at" IS 'set when the user closed their profile; the row is kept, anonymized, for the ledger';
@@ -0,0 +1,19 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar UNIQUE NOT NULL,
+  "secret_hash" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expires_at" timestamp,
+  "last_used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("owner");
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'public part of the key, used to look it up';
+
+COMMENT ON COLUMN "api_keys"."secret_hash" IS 'hex SHA-256 of the secret part; the key itself is never stored';
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username") ON DELETE CASCADE;
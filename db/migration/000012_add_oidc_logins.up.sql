@@ -0,0 +1,23 @@
+CREATE TABLE "user_identities" (
+  "issuer" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("issuer", "subject")
+);
+
+CREATE TABLE "oidc_login_requests" (
+  "state" varchar PRIMARY KEY,
+  "nonce" varchar NOT NULL,
+  "code_verifier" varchar NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "user_identities" ("username");
+
+COMMENT ON TABLE "user_identities" IS 'links a subject at an external OpenID Connect provider to a local user';
+
+COMMENT ON COLUMN "oidc_login_requests"."code_verifier" IS 'PKCE verifier; only its S256 challenge is sent to the provider';
+
+ALTER TABLE "user_identities" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
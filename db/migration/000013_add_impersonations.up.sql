@@ -0,0 +1,23 @@
+CREATE TABLE "impersonations" (
+  "id" bigserial PRIMARY KEY,
+  "token_id" uuid UNIQUE NOT NULL,
+  "actor" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "reason" varchar NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "impersonations" ("actor");
+
+CREATE INDEX ON "impersonations" ("subject");
+
+COMMENT ON TABLE "impersonations" IS 'act-as tokens issued to bankers, kept for auditing';
+
+COMMENT ON COLUMN "impersonations"."actor" IS 'banker the token was issued to';
+
+COMMENT ON COLUMN "impersonations"."subject" IS 'customer the banker acts as';
+
+ALTER TABLE "impersonations" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
+
+ALTER TABLE "impersonations" ADD FOREIGN KEY ("subject") REFERENCES "users" ("username");
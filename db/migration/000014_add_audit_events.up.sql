@@ -0,0 +1,46 @@
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL DEFAULT '',
+  "impersonator" varchar NOT NULL DEFAULT '',
+  "action" varchar NOT NULL,
+  "target" varchar NOT NULL,
+  "client_ip" varchar NOT NULL DEFAULT '',
+  "request_id" varchar NOT NULL DEFAULT '',
+  "before" jsonb NOT NULL DEFAULT '{}',
+  "after" jsonb NOT NULL DEFAULT '{}',
+  "prev_hash" varchar NOT NULL,
+  "hash" varchar UNIQUE NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_events" ("actor");
+
+CREATE INDEX ON "audit_events" ("target");
+
+CREATE INDEX ON "audit_events" ("action", "created_at");
+
+COMMENT ON TABLE "audit_events" IS 'append-only, hash-chained record of security and money-moving events';
+
+COMMENT ON COLUMN "audit_events"."actor" IS 'user who did it; empty for anonymous requests such as failed logins';
+
+COMMENT ON COLUMN "audit_events"."impersonator" IS 'banker acting as the actor, if any';
+
+COMMENT ON COLUMN "audit_events"."action" IS 'e.g. user.login, account.created, transfer.created';
+
+COMMENT ON COLUMN "audit_events"."target" IS 'what it was done to, e.g. user:alice, account:42';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 over prev_hash and the event, so edits and deletions break the chain';
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update_or_delete
+  BEFORE UPDATE OR DELETE ON "audit_events"
+  FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+  BEFORE TRUNCATE ON "audit_events"
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
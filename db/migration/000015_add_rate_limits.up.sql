@@ -0,0 +1,11 @@
+CREATE TABLE "rate_limits" (
+  "key" varchar PRIMARY KEY,
+  "window_start" timestamp NOT NULL,
+  "hits" bigint NOT NULL DEFAULT 0
+);
+
+CREATE INDEX ON "rate_limits" ("window_start");
+
+COMMENT ON TABLE "rate_limits" IS 'request counters shared by every API replica, one fixed window per key';
+
+COMMENT ON COLUMN "rate_limits"."key" IS 'route group and client, e.g. transfers:user:alice or auth:ip:203.0.113.7';
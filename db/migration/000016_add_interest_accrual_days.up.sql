@@ -0,0 +1,11 @@
+CREATE TABLE "interest_accrual_days" (
+  "accrual_date" date PRIMARY KEY,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "interest_accrual_days" IS 'days interest was accrued for on every savings account, so days without any accrual are not accrued again';
+
+-- The newest day may have been accrued for some accounts only; it is accrued again
+INSERT INTO "interest_accrual_days" ("accrual_date")
+SELECT DISTINCT "accrual_date" FROM "interest_accruals"
+WHERE "accrual_date" < (SELECT MAX("accrual_date") FROM "interest_accruals");
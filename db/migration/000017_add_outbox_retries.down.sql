@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "outbox_next_attempt_at_idx";
+
+ALTER TABLE IF EXISTS "outbox" DROP COLUMN IF EXISTS "dead_at";
+
+ALTER TABLE IF EXISTS "outbox" DROP COLUMN IF EXISTS "next_attempt_at";
+
+CREATE INDEX IF NOT EXISTS "outbox_id_idx" ON "outbox" ("id") WHERE "published_at" IS NULL;
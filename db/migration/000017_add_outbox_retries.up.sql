@@ -0,0 +1,9 @@
+ALTER TABLE "outbox" ADD COLUMN "next_attempt_at" timestamp NOT NULL DEFAULT (now());
+
+ALTER TABLE "outbox" ADD COLUMN "dead_at" timestamp;
+
+DROP INDEX IF EXISTS "outbox_id_idx";
+
+CREATE INDEX ON "outbox" ("next_attempt_at") WHERE "published_at" IS NULL AND "dead_at" IS NULL;
+
+COMMENT ON COLUMN "outbox"."dead_at" IS 'set when publishing failed too many times; the event is no longer retried';
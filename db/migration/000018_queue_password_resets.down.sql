@@ -0,0 +1,15 @@
+DELETE FROM "password_reset_tokens" WHERE "token_hash" IS NULL OR "expires_at" IS NULL;
+
+DROP INDEX IF EXISTS "password_reset_tokens_id_idx";
+
+ALTER TABLE IF EXISTS "password_reset_tokens" ALTER COLUMN "expires_at" SET NOT NULL;
+
+ALTER TABLE IF EXISTS "password_reset_tokens" ALTER COLUMN "token_hash" SET NOT NULL;
+
+ALTER TABLE IF EXISTS "password_reset_tokens" DROP COLUMN IF EXISTS "sent_at";
+
+ALTER TABLE IF EXISTS "password_reset_tokens" DROP COLUMN IF EXISTS "last_error";
+
+ALTER TABLE IF EXISTS "password_reset_tokens" DROP COLUMN IF EXISTS "attempts";
+
+ALTER TABLE IF EXISTS "password_reset_tokens" DROP COLUMN IF EXISTS "email";
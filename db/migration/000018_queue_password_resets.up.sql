@@ -0,0 +1,23 @@
+ALTER TABLE "password_reset_tokens" ADD COLUMN "email" varchar;
+
+ALTER TABLE "password_reset_tokens" ADD COLUMN "attempts" int NOT NULL DEFAULT 0;
+
+ALTER TABLE "password_reset_tokens" ADD COLUMN "last_error" varchar;
+
+ALTER TABLE "password_reset_tokens" ADD COLUMN "sent_at" timestamp;
+
+-- Tokens issued before the queue existed were emailed when they were created
+UPDATE "password_reset_tokens" AS t
+SET "email" = u."email", "attempts" = 1, "sent_at" = t."created_at"
+FROM "users" AS u
+WHERE u."username" = t."username";
+
+ALTER TABLE "password_reset_tokens" ALTER COLUMN "email" SET NOT NULL;
+
+ALTER TABLE "password_reset_tokens" ALTER COLUMN "token_hash" DROP NOT NULL;
+
+ALTER TABLE "password_reset_tokens" ALTER COLUMN "expires_at" DROP NOT NULL;
+
+CREATE INDEX ON "password_reset_tokens" ("id") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'hex SHA-256 of the emailed token, set when the email is sent; the token itself is never stored';
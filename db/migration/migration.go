@@ -0,0 +1,136 @@
+// Package migration embeds the SQL migrations into the binary and applies them with
+// golang-migrate, so a deploy needs nothing but the executable to set up its schema.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5" // pgx5:// URLs, on the same driver as the store
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// files holds the NNNNNN_name.up.sql / .down.sql pairs of this directory
+//
+//go:embed *.sql
+var files embed.FS
+
+// lockTimeout is how long a migration waits for another process to release the
+// migration lock. Replicas starting together queue on it, and all but the first
+// find nothing left to apply.
+const lockTimeout = 5 * time.Minute
+
+// Migrator applies the embedded migrations to one database
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New opens a dedicated connection to the database at dbSource (a postgresql:// URL).
+// Every change is made holding a Postgres advisory lock, so concurrent migrators
+// never run migrations at the same time. Close the Migrator when done.
+func New(dbSource string) (*Migrator, error) {
+	source, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	databaseURL, err := driverURL(dbSource)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to migrate db: %w", err)
+	}
+	m.LockTimeout = lockTimeout
+	m.Log = logger{}
+	return &Migrator{m: m}, nil
+}
+
+// driverURL points a postgresql:// URL at golang-migrate's pgx/v5 driver
+func driverURL(dbSource string) (string, error) {
+	databaseURL, err := url.Parse(dbSource)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse migrate db source: %w", err)
+	}
+	switch databaseURL.Scheme {
+	case "postgres", "postgresql":
+		databaseURL.Scheme = "pgx5"
+	case "pgx5":
+	default:
+		return "", fmt.Errorf("unsupported migrate db source scheme %q", databaseURL.Scheme)
+	}
+	return databaseURL.String(), nil
+}
+
+// Up applies every pending migration; having none to apply is not an error
+func (migrator *Migrator) Up() error {
+	from, _, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	err = migrator.m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		slog.Info("database schema is up to date", "version", from)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	to, _, err := migrator.Version()
+	slog.Info("database schema migrated", "from_version", from, "to_version", to)
+	return err
+}
+
+// Down rolls back the given number of most recent migrations
+func (migrator *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+	if err := migrator.m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Version returns the current schema version and whether its migration failed halfway.
+// A database that was never migrated is at version 0.
+func (migrator *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = migrator.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Force sets the schema version without running any migration, clearing the dirty
+// flag. Use it after repairing a migration that failed halfway by hand.
+func (migrator *Migrator) Force(version int) error {
+	return migrator.m.Force(version)
+}
+
+// Close releases the Migrator's database connection
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// logger routes golang-migrate's progress messages to slog
+type logger struct{}
+
+func (logger) Printf(format string, v ...interface{}) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)), "component", "migrate")
+}
+
+func (logger) Verbose() bool {
+	return false
+}
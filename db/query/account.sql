@@ -2,9 +2,10 @@
 INSERT INTO accounts (
   owner, 
   balance,
-  currency
+  currency,
+  type
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 )
 RETURNING *;
 
@@ -17,6 +18,11 @@ SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
+-- name: GetAccountByOwner :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 AND type = $3
+LIMIT 1;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -24,6 +30,12 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountsForUpdate :many
+SELECT * FROM accounts
+WHERE owner = $1
+ORDER BY id
+FOR NO KEY UPDATE;
+
 -- name: UpdateAccount :one
 UPDATE accounts
 set balance = $2
@@ -0,0 +1,40 @@
+-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (
+  username,
+  email
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: LockUnsentEmailVerifications :many
+SELECT * FROM email_verifications
+WHERE sent_at IS NULL AND attempts < sqlc.arg(max_attempts)
+ORDER BY id
+LIMIT sqlc.arg('limit')
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkEmailVerificationSent :exec
+UPDATE email_verifications
+SET token_hash = $2,
+    expires_at = $3,
+    sent_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkEmailVerificationFailed :exec
+UPDATE email_verifications
+SET attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1;
+
+-- name: GetEmailVerificationForUpdate :one
+SELECT * FROM email_verifications
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkEmailVerificationUsed :exec
+UPDATE email_verifications
+SET used_at = now()
+WHERE id = $1;
@@ -0,0 +1,12 @@
+-- name: CreateImpersonation :one
+INSERT INTO impersonations (
+  token_id,
+  actor,
+  subject,
+  reason,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
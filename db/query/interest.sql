@@ -0,0 +1,66 @@
+-- name: ListSavingsBalancesAt :many
+SELECT id, currency, balance FROM (
+  SELECT
+    a.id,
+    a.currency,
+    (a.balance - COALESCE((
+      SELECT SUM(e.amount) FROM entries e
+      WHERE e.account_id = a.id AND e.created_at >= sqlc.arg(as_of)
+    ), 0))::bigint AS balance
+  FROM accounts a
+  WHERE a.type = 'savings' AND a.created_at < sqlc.arg(as_of)
+) b
+WHERE balance > 0
+ORDER BY id;
+
+-- name: GetNextAccrualDate :one
+SELECT COALESCE(
+  (SELECT MAX(accrual_date) + 1 FROM interest_accrual_days),
+  (SELECT MIN(a.created_at)::date FROM accounts a
+   WHERE a.type = 'savings' AND a.currency = ANY(sqlc.arg(currencies)::text[])),
+  sqlc.arg(fallback)::date
+)::date AS next_date;
+
+-- name: CreateInterestAccrualDay :exec
+INSERT INTO interest_accrual_days (accrual_date) VALUES ($1)
+ON CONFLICT (accrual_date) DO NOTHING;
+
+-- name: CreateInterestAccrual :execrows
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  rate_bps,
+  amount_micros
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (account_id, accrual_date) DO NOTHING;
+
+-- name: ListInterestAccruals :many
+SELECT * FROM interest_accruals
+WHERE account_id = $1
+ORDER BY accrual_date
+LIMIT $2
+OFFSET $3;
+
+-- name: ListAccountsWithUnpostedInterest :many
+SELECT DISTINCT a.id, a.currency
+FROM interest_accruals i
+JOIN accounts a ON a.id = i.account_id
+WHERE i.posted_at IS NULL AND i.accrual_date < sqlc.arg(before)
+ORDER BY a.id;
+
+-- name: SumUnpostedInterest :one
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS total_micros
+FROM interest_accruals
+WHERE account_id = sqlc.arg(account_id)
+  AND posted_at IS NULL
+  AND accrual_date < sqlc.arg(before);
+
+-- name: MarkInterestPosted :exec
+UPDATE interest_accruals
+SET posted_at = now(), transfer_id = sqlc.narg(transfer_id)
+WHERE account_id = sqlc.arg(account_id)
+  AND posted_at IS NULL
+  AND accrual_date < sqlc.arg(before);
@@ -0,0 +1,23 @@
+-- name: GetLoginIPFailure :one
+SELECT * FROM login_ip_failures
+WHERE ip = $1 LIMIT 1;
+
+-- name: RecordLoginIPFailure :one
+INSERT INTO login_ip_failures (
+  ip,
+  failed_attempts
+) VALUES (
+  sqlc.arg(ip), 1
+)
+ON CONFLICT (ip) DO UPDATE
+SET failed_attempts = CASE
+      WHEN login_ip_failures.updated_at < sqlc.arg(window_start) THEN 1
+      ELSE login_ip_failures.failed_attempts + 1
+    END,
+    updated_at = now()
+RETURNING *;
+
+-- name: LockLoginIP :exec
+UPDATE login_ip_failures
+SET locked_until = $2
+WHERE ip = $1;
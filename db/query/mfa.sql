@@ -0,0 +1,48 @@
+-- name: UpsertPendingUserMFA :one
+INSERT INTO user_mfa (
+  username,
+  totp_secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+    last_used_step = 0,
+    created_at = now()
+WHERE user_mfa.is_enabled = false
+RETURNING *;
+
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE username = $1 LIMIT 1;
+
+-- name: EnableUserMFA :one
+UPDATE user_mfa
+SET is_enabled = true,
+    last_used_step = $2,
+    enabled_at = now()
+WHERE username = $1 AND is_enabled = false AND last_used_step < $2
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE username = $1 AND is_enabled = true AND last_used_step < $2;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1;
+
+-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+);
+
+-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL;
+
@@ -0,0 +1,2 @@
+-- name: NotifyLedger :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
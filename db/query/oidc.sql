@@ -0,0 +1,37 @@
+-- name: CreateOIDCLoginRequest :one
+INSERT INTO oidc_login_requests (
+  state,
+  nonce,
+  code_verifier,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: ConsumeOIDCLoginRequest :one
+DELETE FROM oidc_login_requests
+WHERE state = $1
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLoginRequests :exec
+DELETE FROM oidc_login_requests
+WHERE expires_at < $1;
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+  issuer,
+  subject,
+  username
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE issuer = $1 AND subject = $2 LIMIT 1;
+
+-- name: DeleteUserIdentities :exec
+DELETE FROM user_identities
+WHERE username = $1;
@@ -0,0 +1,29 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_type,
+  aggregate_id,
+  payload
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: LockPendingOutboxEvents :many
+SELECT * FROM outbox
+WHERE published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now(), attempts = attempts + 1, last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = sqlc.narg(last_error),
+    next_attempt_at = now() + sqlc.arg(retry_after_seconds)::bigint * interval '1 second',
+    dead_at = CASE WHEN sqlc.arg(dead)::boolean THEN now() END
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,43 @@
+-- name: CreatePasswordResetRequest :execrows
+INSERT INTO password_reset_tokens (
+  username,
+  email
+)
+SELECT users.username, users.email FROM users
+WHERE users.email = $1;
+
+-- name: LockUnsentPasswordResets :many
+SELECT * FROM password_reset_tokens
+WHERE sent_at IS NULL AND attempts < sqlc.arg(max_attempts)
+ORDER BY id
+LIMIT sqlc.arg('limit')
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkPasswordResetSent :exec
+UPDATE password_reset_tokens
+SET token_hash = $2,
+    expires_at = $3,
+    sent_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkPasswordResetFailed :exec
+UPDATE password_reset_tokens
+SET attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1;
+
+-- name: GetPasswordResetToken :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: GetPasswordResetTokenForUpdate :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL;
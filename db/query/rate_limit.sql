@@ -0,0 +1,19 @@
+-- name: HitRateLimit :one
+INSERT INTO rate_limits (
+  key,
+  window_start,
+  hits
+) VALUES (
+  sqlc.arg(key), sqlc.arg(window_start), 1
+)
+ON CONFLICT (key) DO UPDATE
+SET hits = CASE
+      WHEN rate_limits.window_start = EXCLUDED.window_start THEN rate_limits.hits + 1
+      ELSE 1
+    END,
+    window_start = EXCLUDED.window_start
+RETURNING hits;
+
+-- name: DeleteExpiredRateLimits :exec
+DELETE FROM rate_limits
+WHERE window_start < sqlc.arg(before);
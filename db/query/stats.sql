@@ -0,0 +1,19 @@
+-- name: ListTransferStats :many
+SELECT
+  a.currency,
+  COUNT(*)::bigint AS transfers,
+  COALESCE(SUM(t.amount), 0)::bigint AS amount
+FROM transfers t
+JOIN accounts a ON a.id = t.from_account_id
+GROUP BY a.currency
+ORDER BY a.currency;
+
+-- name: ListAccountStats :many
+SELECT
+  currency,
+  type,
+  COUNT(*)::bigint AS accounts,
+  COALESCE(SUM(balance), 0)::bigint AS balance
+FROM accounts
+GROUP BY currency, type
+ORDER BY currency, type;
@@ -11,4 +11,70 @@ RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUser :one
+UPDATE users
+SET full_name = COALESCE(sqlc.narg(full_name), full_name),
+    email = COALESCE(sqlc.narg(email), email),
+    is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: DeleteUser :one
+UPDATE users
+SET full_name = '',
+    email = username || '@deleted.invalid',
+    hashed_password = '',
+    deleted_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+-- password_changed_at comes from the app clock, which also stamps the tokens it is
+-- compared with, so clock skew or the DB time zone can't revoke fresh tokens
+UPDATE users
+SET hashed_password = sqlc.arg(hashed_password),
+    password_changed_at = sqlc.arg(password_changed_at)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg(new_hashed_password)
+WHERE username = sqlc.arg(username)
+  AND hashed_password = sqlc.arg(old_hashed_password);
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+RETURNING *;
+
+-- name: RecordUserLoginFailure :one
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1
+WHERE username = $1
+RETURNING failed_login_attempts;
+
+-- name: LockUser :exec
+UPDATE users
+SET locked_until = $2
+WHERE username = $1;
+
+-- name: UnlockUser :one
+UPDATE users
+SET failed_login_attempts = 0,
+    locked_until = NULL
+WHERE username = $1
+RETURNING *;
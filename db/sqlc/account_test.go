@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -18,6 +17,7 @@ func CreateRandomAccount(t *testing.T) Account {
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Type:     util.CheckingAccount,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -30,6 +30,7 @@ func CreateRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
 	require.Equal(t, arg.Owner, account.Owner)
+	require.Equal(t, arg.Type, account.Type)
 
 	// Ensure the account has a valid ID and creation timestamp
 	require.NotZero(t, account.ID)
@@ -89,9 +90,9 @@ func TestDeleteAccount(t *testing.T) {
 
 	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
 
-	// After deletion, getting the account should return sql.ErrNoRows
+	// After deletion, getting the account should return ErrRecordNotFound
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.EqualError(t, err, ErrRecordNotFound.Error())
 	require.Empty(t, account2)
 }
 
@@ -0,0 +1,33 @@
+package db
+
+import "context"
+
+// CreateAPIKeyTx stores a new API key and its audit event atomically.
+func (store *SQLStore) CreateAPIKeyTx(ctx context.Context, arg CreateAPIKeyParams, audit APIKeyAudit) (ApiKey, error) {
+	var apiKey ApiKey
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		apiKey, err = q.CreateAPIKey(ctx, arg)
+		return err
+	}, audited(audit, &apiKey))
+
+	return apiKey, err
+}
+
+// DeleteAPIKeyTx revokes an API key and records its audit event atomically. It returns
+// the key as it was just before deletion.
+func (store *SQLStore) DeleteAPIKeyTx(ctx context.Context, id int64, audit APIKeyAudit) (ApiKey, error) {
+	var apiKey ApiKey
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		apiKey, err = q.GetAPIKey(ctx, id)
+		if err != nil {
+			return err
+		}
+		return q.DeleteAPIKey(ctx, id)
+	}, audited(audit, &apiKey))
+
+	return apiKey, err
+}
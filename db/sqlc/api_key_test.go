@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, owner string) ApiKey {
+	_, prefix, secret, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	arg := CreateAPIKeyParams{
+		Owner:      owner,
+		Name:       util.RandomOwner(),
+		Prefix:     prefix,
+		SecretHash: util.HashSecureToken(secret),
+		Scopes:     []string{util.ScopeAccountsRead, util.ScopeTransfersWrite},
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, apiKey.ID)
+	require.Equal(t, arg.Owner, apiKey.Owner)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.SecretHash, apiKey.SecretHash)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.ExpiresAt.Valid)
+	require.False(t, apiKey.LastUsedAt.Valid)
+
+	return apiKey
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	user := CreateRandomUser(t)
+	apiKey := createRandomAPIKey(t, user.Username)
+
+	found, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, found.ID)
+
+	err = testQueries.TouchAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	touched, err := testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.True(t, touched.LastUsedAt.Valid)
+
+	err = testQueries.DeleteAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	user := CreateRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomAPIKey(t, user.Username)
+	}
+	createRandomAPIKey(t, CreateRandomUser(t).Username)
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), ListAPIKeysParams{
+		Owner:  user.Username,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+	for _, apiKey := range apiKeys {
+		require.Equal(t, user.Username, apiKey.Owner)
+	}
+
+	err = testQueries.DeleteAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	apiKeys, err = testQueries.ListAPIKeys(context.Background(), ListAPIKeysParams{
+		Owner:  user.Username,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+}
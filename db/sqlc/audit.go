@@ -0,0 +1,226 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// auditVerifyBatch is how many events VerifyAuditChain reads at a time
+const auditVerifyBatch = 500
+
+// AppendAuditEventTxParams describes an event to add to the audit log. Before and After
+// are snapshots of what changed; they are stored as JSON and nil is stored as {}.
+type AppendAuditEventTxParams struct {
+	Actor        string `json:"actor"`        // User who did it; empty for anonymous requests
+	Impersonator string `json:"impersonator"` // Banker acting as the actor, if any
+	Action       string `json:"action"`
+	Target       string `json:"target"`
+	ClientIP     string `json:"client_ip"`
+	RequestID    string `json:"request_id"`
+	Before       any    `json:"before"`
+	After        any    `json:"after"`
+}
+
+// AuditChainStatus is the outcome of VerifyAuditChain.
+type AuditChainStatus struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`             // Events verified before stopping
+	BrokenAt int64  `json:"broken_at,omitempty"` // First event whose hash or link doesn't match
+	LastHash string `json:"last_hash,omitempty"` // Hash of the newest verified event, to anchor elsewhere
+}
+
+// Audit funcs build the audit event of a transaction's change from the transaction's
+// result. A transaction given one appends the event before it commits, so a change is
+// never stored without its event, nor an event without its change. A nil func records nothing.
+type (
+	TransferAudit func(TransferTxResult) AppendAuditEventTxParams
+	AccountAudit  func(Account) AppendAuditEventTxParams
+	UserAudit     func(User) AppendAuditEventTxParams
+	APIKeyAudit   func(ApiKey) AppendAuditEventTxParams
+	MFAAudit      func(UserMfa) AppendAuditEventTxParams
+)
+
+// auditFunc returns the event execTX appends once a transaction's function succeeded
+type auditFunc func() AppendAuditEventTxParams
+
+// audited adapts an audit func to execTX, reading the transaction's result from result
+func audited[T any](audit func(T) AppendAuditEventTxParams, result *T) auditFunc {
+	if audit == nil {
+		return nil
+	}
+	return func() AppendAuditEventTxParams { return audit(*result) }
+}
+
+// AppendAuditEventTx appends an event that goes with no other change, such as a failed
+// login, to the audit log in a transaction of its own.
+func (store *SQLStore) AppendAuditEventTx(ctx context.Context, arg AppendAuditEventTxParams) (AuditEvent, error) {
+	var event AuditEvent
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		event, err = appendAuditEvent(ctx, q, arg)
+		return err
+	}, nil)
+
+	return event, err
+}
+
+// appendAuditEvent appends an event to the audit log using the given transactional Queries,
+// chaining it to the previous event's hash. Appends are serialized with an advisory lock,
+// held until the transaction ends, so the chain never forks.
+func appendAuditEvent(ctx context.Context, q *Queries, arg AppendAuditEventTxParams) (AuditEvent, error) {
+	before, err := auditSnapshot(arg.Before)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("cannot marshal %s before snapshot: %w", arg.Action, err)
+	}
+	after, err := auditSnapshot(arg.After)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("cannot marshal %s after snapshot: %w", arg.Action, err)
+	}
+
+	// 1. Take the chain's tail for this transaction
+	if err := q.LockAuditChain(ctx); err != nil {
+		return AuditEvent{}, err
+	}
+
+	prevHash := ""
+	last, err := q.GetLastAuditEvent(ctx)
+	if err == nil {
+		prevHash = last.Hash
+	} else if !errors.Is(err, ErrRecordNotFound) {
+		return AuditEvent{}, err
+	}
+
+	// 2. Hash the event together with its predecessor; timestamps are stored to the microsecond
+	params := CreateAuditEventParams{
+		Actor:        arg.Actor,
+		Impersonator: arg.Impersonator,
+		Action:       arg.Action,
+		Target:       arg.Target,
+		ClientIp:     arg.ClientIP,
+		RequestID:    arg.RequestID,
+		Before:       before,
+		After:        after,
+		PrevHash:     prevHash,
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	params.Hash, err = AuditEventHash(AuditEvent{
+		Actor:        params.Actor,
+		Impersonator: params.Impersonator,
+		Action:       params.Action,
+		Target:       params.Target,
+		ClientIp:     params.ClientIp,
+		RequestID:    params.RequestID,
+		Before:       params.Before,
+		After:        params.After,
+		PrevHash:     params.PrevHash,
+		CreatedAt:    params.CreatedAt,
+	})
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	// 3. Append
+	return q.CreateAuditEvent(ctx, params)
+}
+
+// VerifyAuditChain walks the audit log from the start, recomputing every hash. It stops
+// at the first event that was edited, or whose predecessor was removed.
+func (store *SQLStore) VerifyAuditChain(ctx context.Context) (AuditChainStatus, error) {
+	status := AuditChainStatus{Valid: true}
+	var afterID int64
+
+	for {
+		events, err := store.ListAuditEventsAfter(ctx, ListAuditEventsAfterParams{
+			AfterID: afterID,
+			Limit:   auditVerifyBatch,
+		})
+		if err != nil {
+			return status, err
+		}
+
+		for _, event := range events {
+			hash, err := AuditEventHash(event)
+			if err != nil {
+				return status, err
+			}
+			if event.PrevHash != status.LastHash || event.Hash != hash {
+				status.Valid = false
+				status.BrokenAt = event.ID
+				return status, nil
+			}
+
+			status.Checked++
+			status.LastHash = event.Hash
+			afterID = event.ID
+		}
+
+		if len(events) < auditVerifyBatch {
+			return status, nil
+		}
+	}
+}
+
+// AuditEventHash computes an event's chain hash: the hex sha256 of its predecessor's hash
+// and its own fields. The ID and Hash fields are ignored, so exported events can be
+// verified anywhere by recomputing it.
+func AuditEventHash(event AuditEvent) (string, error) {
+	before, err := canonicalJSON(event.Before)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash audit event: %w", err)
+	}
+	after, err := canonicalJSON(event.After)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash audit event: %w", err)
+	}
+
+	// Fixed field order; the JSON encoding keeps field boundaries unambiguous
+	data, err := json.Marshal([]any{
+		event.PrevHash,
+		event.Actor,
+		event.Impersonator,
+		event.Action,
+		event.Target,
+		event.ClientIp,
+		event.RequestID,
+		before,
+		after,
+		event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot hash audit event: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditSnapshot marshals a before or after snapshot, storing nil as an empty object
+func auditSnapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return json.RawMessage(`{}`), nil
+	}
+	return json.Marshal(v)
+}
+
+// canonicalJSON re-encodes a JSON document with sorted keys and no whitespace, since
+// jsonb doesn't keep the text it was given. Numbers are kept as written.
+func canonicalJSON(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return json.RawMessage(`{}`), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
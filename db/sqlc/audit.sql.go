@@ -0,0 +1,239 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: audit.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  actor,
+  impersonator,
+  action,
+  target,
+  client_ip,
+  request_id,
+  before,
+  after,
+  prev_hash,
+  hash,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING id, actor, impersonator, action, target, client_ip, request_id, before, after, prev_hash, hash, created_at
+`
+
+type CreateAuditEventParams struct {
+	Actor        string          `json:"actor"`
+	Impersonator string          `json:"impersonator"`
+	Action       string          `json:"action"`
+	Target       string          `json:"target"`
+	ClientIp     string          `json:"client_ip"`
+	RequestID    string          `json:"request_id"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, createAuditEvent,
+		arg.Actor,
+		arg.Impersonator,
+		arg.Action,
+		arg.Target,
+		arg.ClientIp,
+		arg.RequestID,
+		arg.Before,
+		arg.After,
+		arg.PrevHash,
+		arg.Hash,
+		arg.CreatedAt,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Actor,
+		&i.Impersonator,
+		&i.Action,
+		&i.Target,
+		&i.ClientIp,
+		&i.RequestID,
+		&i.Before,
+		&i.After,
+		&i.PrevHash,
+		&i.Hash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLastAuditEvent = `-- name: GetLastAuditEvent :one
+SELECT id, actor, impersonator, action, target, client_ip, request_id, before, after, prev_hash, hash, created_at FROM audit_events
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastAuditEvent(ctx context.Context) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, getLastAuditEvent)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Actor,
+		&i.Impersonator,
+		&i.Action,
+		&i.Target,
+		&i.ClientIp,
+		&i.RequestID,
+		&i.Before,
+		&i.After,
+		&i.PrevHash,
+		&i.Hash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAuditEvents = `-- name: ListAuditEvents :many
+SELECT id, actor, impersonator, action, target, client_ip, request_id, before, after, prev_hash, hash, created_at FROM audit_events
+WHERE ($1::varchar IS NULL OR actor = $1)
+  AND ($2::varchar IS NULL OR action = $2)
+  AND ($3::varchar IS NULL OR target = $3)
+  AND ($4::timestamp IS NULL OR created_at >= $4)
+  AND ($5::timestamp IS NULL OR created_at < $5)
+ORDER BY id DESC
+LIMIT $7
+OFFSET $6
+`
+
+type ListAuditEventsParams struct {
+	Actor    sql.NullString `json:"actor"`
+	Action   sql.NullString `json:"action"`
+	Target   sql.NullString `json:"target"`
+	FromTime sql.NullTime   `json:"from_time"`
+	ToTime   sql.NullTime   `json:"to_time"`
+	Offset   int64          `json:"offset"`
+	Limit    int64          `json:"limit"`
+}
+
+func (q *Queries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEvents,
+		arg.Actor,
+		arg.Action,
+		arg.Target,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Impersonator,
+			&i.Action,
+			&i.Target,
+			&i.ClientIp,
+			&i.RequestID,
+			&i.Before,
+			&i.After,
+			&i.PrevHash,
+			&i.Hash,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditEventsAfter = `-- name: ListAuditEventsAfter :many
+SELECT id, actor, impersonator, action, target, client_ip, request_id, before, after, prev_hash, hash, created_at FROM audit_events
+WHERE id > $1
+  AND ($2::varchar IS NULL OR actor = $2)
+  AND ($3::varchar IS NULL OR action = $3)
+  AND ($4::varchar IS NULL OR target = $4)
+  AND ($5::timestamp IS NULL OR created_at >= $5)
+  AND ($6::timestamp IS NULL OR created_at < $6)
+ORDER BY id
+LIMIT $7
+`
+
+type ListAuditEventsAfterParams struct {
+	AfterID  int64          `json:"after_id"`
+	Actor    sql.NullString `json:"actor"`
+	Action   sql.NullString `json:"action"`
+	Target   sql.NullString `json:"target"`
+	FromTime sql.NullTime   `json:"from_time"`
+	ToTime   sql.NullTime   `json:"to_time"`
+	Limit    int64          `json:"limit"`
+}
+
+func (q *Queries) ListAuditEventsAfter(ctx context.Context, arg ListAuditEventsAfterParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEventsAfter,
+		arg.AfterID,
+		arg.Actor,
+		arg.Action,
+		arg.Target,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Impersonator,
+			&i.Action,
+			&i.Target,
+			&i.ClientIp,
+			&i.RequestID,
+			&i.Before,
+			&i.After,
+			&i.PrevHash,
+			&i.Hash,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockAuditChain = `-- name: LockAuditChain :exec
+SELECT pg_advisory_xact_lock(hashtext('audit_events'))
+`
+
+func (q *Queries) LockAuditChain(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, lockAuditChain)
+	return err
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func appendRandomAuditEvent(t *testing.T, store Store) AuditEvent {
+	user := CreateRandomUser(t)
+
+	arg := AppendAuditEventTxParams{
+		Actor:     user.Username,
+		Action:    "user.login",
+		Target:    "user:" + user.Username,
+		ClientIP:  "203.0.113.7",
+		RequestID: "req-" + user.Username,
+		After:     map[string]any{"method": "password", "amount": int64(1) << 60},
+	}
+
+	event, err := store.AppendAuditEventTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, event.ID)
+	require.Equal(t, arg.Actor, event.Actor)
+	require.Equal(t, arg.Action, event.Action)
+	require.Equal(t, arg.Target, event.Target)
+	require.Equal(t, arg.ClientIP, event.ClientIp)
+	require.Equal(t, arg.RequestID, event.RequestID)
+	require.JSONEq(t, `{}`, string(event.Before))
+	require.JSONEq(t, `{"method":"password","amount":1152921504606846976}`, string(event.After))
+	require.Len(t, event.Hash, 64)
+
+	return event
+}
+
+func TestAppendAuditEventTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	event1 := appendRandomAuditEvent(t, store)
+	event2 := appendRandomAuditEvent(t, store)
+
+	// Each event links to the one before it, and its hash survives the jsonb round trip
+	require.Equal(t, event1.Hash, event2.PrevHash)
+	hash, err := AuditEventHash(event2)
+	require.NoError(t, err)
+	require.Equal(t, event2.Hash, hash)
+
+	status, err := store.VerifyAuditChain(context.Background())
+	require.NoError(t, err)
+	require.True(t, status.Valid)
+	require.GreaterOrEqual(t, status.Checked, int64(2))
+	require.NotEmpty(t, status.LastHash)
+}
+
+func TestAuditEventsAppendOnly(t *testing.T) {
+	event := appendRandomAuditEvent(t, NewStore(testDB))
+
+	_, err := testDB.Exec(context.Background(), `UPDATE audit_events SET actor = 'mallory' WHERE id = $1`, event.ID)
+	require.ErrorContains(t, err, "append-only")
+
+	_, err = testDB.Exec(context.Background(), `DELETE FROM audit_events WHERE id = $1`, event.ID)
+	require.ErrorContains(t, err, "append-only")
+}
+
+func TestAuditEventHash(t *testing.T) {
+	event := AuditEvent{
+		Actor:    "alice",
+		Action:   "transfer.created",
+		Target:   "transfer:1",
+		Before:   json.RawMessage(`{"b": 1, "a": [1, 2]}`),
+		After:    json.RawMessage(`{}`),
+		PrevHash: "abc",
+	}
+
+	hash, err := AuditEventHash(event)
+	require.NoError(t, err)
+
+	// Key order and whitespace don't matter, the content does
+	event.Before = json.RawMessage(`{"a":[1,2],"b":1}`)
+	same, err := AuditEventHash(event)
+	require.NoError(t, err)
+	require.Equal(t, hash, same)
+
+	event.Target = "transfer:2"
+	changed, err := AuditEventHash(event)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, changed)
+
+	event.Target = "transfer:1"
+	event.PrevHash = "abd"
+	relinked, err := AuditEventHash(event)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, relinked)
+}
+
+func TestTransferTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	arg := TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}
+
+	// The event is built from the result and appended with the transfer
+	result, err := store.TransferTx(context.Background(), arg, func(result TransferTxResult) AppendAuditEventTxParams {
+		return AppendAuditEventTxParams{Actor: account1.Owner, Action: "transfer.created", Target: fmt.Sprintf("transfer:%d", result.Transfer.ID)}
+	})
+	require.NoError(t, err)
+
+	last, err := testQueries.GetLastAuditEvent(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("transfer:%d", result.Transfer.ID), last.Target)
+
+	// An event that can't be appended rolls the transfer back
+	_, err = store.TransferTx(context.Background(), arg, func(TransferTxResult) AppendAuditEventTxParams {
+		return AppendAuditEventTxParams{Action: "transfer.created", After: make(chan int)}
+	})
+	require.Error(t, err)
+
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccount.Balance, account.Balance)
+}
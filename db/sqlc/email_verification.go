@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// MaxEmailVerificationAttempts is how many times sending a verification email is
+// tried before the verification is left for the user to request again.
+const MaxEmailVerificationAttempts = 5
+
+// ErrInvalidVerificationToken is returned by VerifyEmailTx for unknown, used or expired tokens.
+var ErrInvalidVerificationToken = errors.New("email verification token is invalid or has expired")
+
+// SentEmailVerification describes the token that was emailed for a verification.
+type SentEmailVerification struct {
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateUserTx creates a user together with a pending email verification, which the
+// verification worker later picks up and emails.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateEmailVerification(ctx, CreateEmailVerificationParams{
+			Username: user.Username,
+			Email:    user.Email,
+		})
+		return err
+	}, nil)
+
+	return user, err
+}
+
+// SendEmailVerificationsTx locks up to limit unsent verifications and hands each to send,
+// which emails a fresh token and returns its hash. Failures are recorded on the row and
+// retried on a later run; rows locked by another worker are skipped.
+func (store *SQLStore) SendEmailVerificationsTx(ctx context.Context, limit int64, send func(context.Context, EmailVerification) (SentEmailVerification, error)) (int, error) {
+	sent := 0
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Claim unsent verifications for this transaction
+		verifications, err := q.LockUnsentEmailVerifications(ctx, LockUnsentEmailVerificationsParams{
+			MaxAttempts: MaxEmailVerificationAttempts,
+			Limit:       limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, verification := range verifications {
+			// 2. Send; on failure record the error and move on to the next one
+			result, err := send(ctx, verification)
+			if err != nil {
+				if err := q.MarkEmailVerificationFailed(ctx, MarkEmailVerificationFailedParams{
+					ID:        verification.ID,
+					LastError: sql.NullString{String: err.Error(), Valid: true},
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// 3. Store the hash of the token that was sent
+			if err := q.MarkEmailVerificationSent(ctx, MarkEmailVerificationSentParams{
+				ID:        verification.ID,
+				TokenHash: sql.NullString{String: result.TokenHash, Valid: true},
+				ExpiresAt: sql.NullTime{Time: result.ExpiresAt, Valid: true},
+			}); err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return sent, nil
+}
+
+// VerifyEmailTx consumes a verification token and marks its user's email as verified.
+// Tokens sent to an address the user no longer has are rejected.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, tokenHash string) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Lock the verification so the token is consumed at most once
+		verification, err := q.GetEmailVerificationForUpdate(ctx, sql.NullString{String: tokenHash, Valid: true})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrInvalidVerificationToken
+			}
+			return err
+		}
+
+		// 2. Reject used or expired tokens (expires_at is stored in UTC)
+		if verification.UsedAt.Valid || !verification.ExpiresAt.Valid || !time.Now().UTC().Before(verification.ExpiresAt.Time) {
+			return ErrInvalidVerificationToken
+		}
+
+		// 3. The token must be for the user's current address
+		user, err = q.GetUser(ctx, verification.Username)
+		if err != nil {
+			return err
+		}
+		if user.Email != verification.Email {
+			return ErrInvalidVerificationToken
+		}
+
+		// 4. Consume the token and flag the user
+		if err := q.MarkEmailVerificationUsed(ctx, verification.ID); err != nil {
+			return err
+		}
+
+		user, err = q.MarkUserEmailVerified(ctx, user.Username)
+		return err
+	}, nil)
+
+	return user, err
+}
@@ -0,0 +1,161 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: email_verification.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createEmailVerification = `-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (
+  username,
+  email
+) VALUES (
+  $1, $2
+)
+RETURNING id, username, email, token_hash, attempts, last_error, expires_at, sent_at, used_at, created_at
+`
+
+type CreateEmailVerificationParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, createEmailVerification, arg.Username, arg.Email)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.TokenHash,
+		&i.Attempts,
+		&i.LastError,
+		&i.ExpiresAt,
+		&i.SentAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getEmailVerificationForUpdate = `-- name: GetEmailVerificationForUpdate :one
+SELECT id, username, email, token_hash, attempts, last_error, expires_at, sent_at, used_at, created_at FROM email_verifications
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetEmailVerificationForUpdate(ctx context.Context, tokenHash sql.NullString) (EmailVerification, error) {
+	row := q.db.QueryRow(ctx, getEmailVerificationForUpdate, tokenHash)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.TokenHash,
+		&i.Attempts,
+		&i.LastError,
+		&i.ExpiresAt,
+		&i.SentAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const lockUnsentEmailVerifications = `-- name: LockUnsentEmailVerifications :many
+SELECT id, username, email, token_hash, attempts, last_error, expires_at, sent_at, used_at, created_at FROM email_verifications
+WHERE sent_at IS NULL AND attempts < $1
+ORDER BY id
+LIMIT $2
+FOR UPDATE SKIP LOCKED
+`
+
+type LockUnsentEmailVerificationsParams struct {
+	MaxAttempts int32 `json:"max_attempts"`
+	Limit       int64 `json:"limit"`
+}
+
+func (q *Queries) LockUnsentEmailVerifications(ctx context.Context, arg LockUnsentEmailVerificationsParams) ([]EmailVerification, error) {
+	rows, err := q.db.Query(ctx, lockUnsentEmailVerifications, arg.MaxAttempts, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailVerification{}
+	for rows.Next() {
+		var i EmailVerification
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.TokenHash,
+			&i.Attempts,
+			&i.LastError,
+			&i.ExpiresAt,
+			&i.SentAt,
+			&i.UsedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerificationFailed = `-- name: MarkEmailVerificationFailed :exec
+UPDATE email_verifications
+SET attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1
+`
+
+type MarkEmailVerificationFailedParams struct {
+	ID        int64          `json:"id"`
+	LastError sql.NullString `json:"last_error"`
+}
+
+func (q *Queries) MarkEmailVerificationFailed(ctx context.Context, arg MarkEmailVerificationFailedParams) error {
+	_, err := q.db.Exec(ctx, markEmailVerificationFailed, arg.ID, arg.LastError)
+	return err
+}
+
+const markEmailVerificationSent = `-- name: MarkEmailVerificationSent :exec
+UPDATE email_verifications
+SET token_hash = $2,
+    expires_at = $3,
+    sent_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1
+`
+
+type MarkEmailVerificationSentParams struct {
+	ID        int64          `json:"id"`
+	TokenHash sql.NullString `json:"token_hash"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+}
+
+func (q *Queries) MarkEmailVerificationSent(ctx context.Context, arg MarkEmailVerificationSentParams) error {
+	_, err := q.db.Exec(ctx, markEmailVerificationSent, arg.ID, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
+
+const markEmailVerificationUsed = `-- name: MarkEmailVerificationUsed :exec
+UPDATE email_verifications
+SET used_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkEmailVerificationUsed(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markEmailVerificationUsed, id)
+	return err
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationFlow(t *testing.T) {
+	store := NewStore(testDB)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	user, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+
+	// A failed send is recorded and retried on the next run
+	_, err = store.SendEmailVerificationsTx(context.Background(), 1000, func(ctx context.Context, verification EmailVerification) (SentEmailVerification, error) {
+		return SentEmailVerification{}, errors.New("smtp unavailable")
+	})
+	require.NoError(t, err)
+
+	token, err := util.GenerateSecureToken()
+	require.NoError(t, err)
+
+	found := false
+	_, err = store.SendEmailVerificationsTx(context.Background(), 1000, func(ctx context.Context, verification EmailVerification) (SentEmailVerification, error) {
+		if verification.Username != user.Username {
+			return SentEmailVerification{}, errors.New("not this test's user")
+		}
+		found = true
+		require.Equal(t, int32(1), verification.Attempts)
+		require.Equal(t, user.Email, verification.Email)
+		return SentEmailVerification{TokenHash: util.HashSecureToken(token), ExpiresAt: time.Now().UTC().Add(time.Hour)}, nil
+	})
+	require.NoError(t, err)
+	require.True(t, found)
+
+	verified, err := store.VerifyEmailTx(context.Background(), util.HashSecureToken(token))
+	require.NoError(t, err)
+	require.Equal(t, user.Username, verified.Username)
+	require.True(t, verified.IsEmailVerified)
+
+	// Tokens are single-use
+	_, err = store.VerifyEmailTx(context.Background(), util.HashSecureToken(token))
+	require.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrRecordNotFound is returned by queries expecting one row when none matches.
+// Compare with errors.Is rather than a driver's own sentinel.
+var ErrRecordNotFound = pgx.ErrNoRows
+
+// SQLSTATE codes of the Postgres errors callers react to
+const (
+	ForeignKeyViolation = "23503"
+	UniqueViolation     = "23505"
+)
+
+// UsersEmailKey is the unique constraint on users' email addresses
+const UsersEmailKey = "users_email_key"
+
+// ErrorCode returns the SQLSTATE code of the Postgres error in err's chain, or an
+// empty string when err didn't come from Postgres
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// ConstraintName returns the name of the constraint the Postgres error in err's chain
+// violated (e.g. users_email_key), or an empty string
+func ConstraintName(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// isRetryable reports whether a transaction failed only because it conflicted with a
+// concurrent one (deadlock or serialization failure, SQLSTATE class 40), so running it
+// again may succeed
+func isRetryable(err error) bool {
+	return strings.HasPrefix(ErrorCode(err), "40")
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCode(t *testing.T) {
+	require.Equal(t, UniqueViolation, ErrorCode(&pgconn.PgError{Code: "23505"}))
+	require.Equal(t, ForeignKeyViolation, ErrorCode(fmt.Errorf("create account: %w", &pgconn.PgError{Code: "23503"})))
+	require.Empty(t, ErrorCode(errors.New("connection refused")))
+	require.Empty(t, ErrorCode(nil))
+}
+
+func TestConstraintName(t *testing.T) {
+	err := fmt.Errorf("create user: %w", &pgconn.PgError{Code: UniqueViolation, ConstraintName: UsersEmailKey})
+	require.Equal(t, UsersEmailKey, ConstraintName(err))
+	require.Empty(t, ConstraintName(errors.New("connection refused")))
+}
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, isRetryable(&pgconn.PgError{Code: "40P01"}))                           // deadlock_detected
+	require.True(t, isRetryable(fmt.Errorf("tx err: %w", &pgconn.PgError{Code: "40001"}))) // serialization_failure
+	require.False(t, isRetryable(&pgconn.PgError{Code: UniqueViolation}))
+	require.False(t, isRetryable(errors.New("connection refused")))
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoMigrations is returned by MigrationVersion when no migration has been applied.
+var ErrNoMigrations = errors.New("no migrations have been applied")
+
+// Ping checks the database can be reached.
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.Ping(ctx)
+}
+
+// MigrationVersion returns the schema version recorded by the migrate tool, and whether
+// the last migration failed halfway (dirty). The schema_migrations table is owned by the
+// tool rather than the migrations, so it is queried by hand instead of through sqlc.
+func (store *SQLStore) MigrationVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	err = store.db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, ErrRecordNotFound) {
+		return 0, false, ErrNoMigrations
+	}
+	return version, dirty, err
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	store := NewStore(testDB)
+	require.NoError(t, store.Ping(context.Background()))
+}
+
+func TestMigrationVersion(t *testing.T) {
+	store := NewStore(testDB)
+
+	version, dirty, err := store.MigrationVersion(context.Background())
+	require.NoError(t, err)
+	require.Positive(t, version)
+	require.False(t, dirty)
+}
@@ -0,0 +1,55 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: impersonation.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createImpersonation = `-- name: CreateImpersonation :one
+INSERT INTO impersonations (
+  token_id,
+  actor,
+  subject,
+  reason,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, token_id, actor, subject, reason, expires_at, created_at
+`
+
+type CreateImpersonationParams struct {
+	TokenID   uuid.UUID `json:"token_id"`
+	Actor     string    `json:"actor"`
+	Subject   string    `json:"subject"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateImpersonation(ctx context.Context, arg CreateImpersonationParams) (Impersonation, error) {
+	row := q.db.QueryRow(ctx, createImpersonation,
+		arg.TokenID,
+		arg.Actor,
+		arg.Subject,
+		arg.Reason,
+		arg.ExpiresAt,
+	)
+	var i Impersonation
+	err := row.Scan(
+		&i.ID,
+		&i.TokenID,
+		&i.Actor,
+		&i.Subject,
+		&i.Reason,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
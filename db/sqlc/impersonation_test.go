@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateImpersonation(t *testing.T) {
+	banker := CreateRandomUser(t)
+	customer := CreateRandomUser(t)
+
+	arg := CreateImpersonationParams{
+		TokenID:   uuid.New(),
+		Actor:     banker.Username,
+		Subject:   customer.Username,
+		Reason:    "ticket #4242",
+		ExpiresAt: time.Now().UTC().Add(15 * time.Minute),
+	}
+
+	impersonation, err := testQueries.CreateImpersonation(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, impersonation.ID)
+	require.Equal(t, arg.TokenID, impersonation.TokenID)
+	require.Equal(t, arg.Actor, impersonation.Actor)
+	require.Equal(t, arg.Subject, impersonation.Subject)
+	require.Equal(t, arg.Reason, impersonation.Reason)
+	require.WithinDuration(t, arg.ExpiresAt, impersonation.ExpiresAt, time.Second)
+
+	// Each token is recorded once
+	_, err = testQueries.CreateImpersonation(context.Background(), arg)
+	require.Error(t, err)
+}
@@ -0,0 +1,236 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: interest.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createInterestAccrual = `-- name: CreateInterestAccrual :execrows
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  rate_bps,
+  amount_micros
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (account_id, accrual_date) DO NOTHING
+`
+
+type CreateInterestAccrualParams struct {
+	AccountID    int64     `json:"account_id"`
+	AccrualDate  time.Time `json:"accrual_date"`
+	Balance      int64     `json:"balance"`
+	RateBps      int64     `json:"rate_bps"`
+	AmountMicros int64     `json:"amount_micros"`
+}
+
+func (q *Queries) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createInterestAccrual,
+		arg.AccountID,
+		arg.AccrualDate,
+		arg.Balance,
+		arg.RateBps,
+		arg.AmountMicros,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createInterestAccrualDay = `-- name: CreateInterestAccrualDay :exec
+INSERT INTO interest_accrual_days (accrual_date) VALUES ($1)
+ON CONFLICT (accrual_date) DO NOTHING
+`
+
+func (q *Queries) CreateInterestAccrualDay(ctx context.Context, accrualDate time.Time) error {
+	_, err := q.db.Exec(ctx, createInterestAccrualDay, accrualDate)
+	return err
+}
+
+const getNextAccrualDate = `-- name: GetNextAccrualDate :one
+SELECT COALESCE(
+  (SELECT MAX(accrual_date) + 1 FROM interest_accrual_days),
+  (SELECT MIN(a.created_at)::date FROM accounts a
+   WHERE a.type = 'savings' AND a.currency = ANY($1::text[])),
+  $2::date
+)::date AS next_date
+`
+
+type GetNextAccrualDateParams struct {
+	Currencies []string  `json:"currencies"`
+	Fallback   time.Time `json:"fallback"`
+}
+
+func (q *Queries) GetNextAccrualDate(ctx context.Context, arg GetNextAccrualDateParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, getNextAccrualDate, arg.Currencies, arg.Fallback)
+	var next_date time.Time
+	err := row.Scan(&next_date)
+	return next_date, err
+}
+
+const listAccountsWithUnpostedInterest = `-- name: ListAccountsWithUnpostedInterest :many
+SELECT DISTINCT a.id, a.currency
+FROM interest_accruals i
+JOIN accounts a ON a.id = i.account_id
+WHERE i.posted_at IS NULL AND i.accrual_date < $1
+ORDER BY a.id
+`
+
+type ListAccountsWithUnpostedInterestRow struct {
+	ID       int64  `json:"id"`
+	Currency string `json:"currency"`
+}
+
+func (q *Queries) ListAccountsWithUnpostedInterest(ctx context.Context, before time.Time) ([]ListAccountsWithUnpostedInterestRow, error) {
+	rows, err := q.db.Query(ctx, listAccountsWithUnpostedInterest, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountsWithUnpostedInterestRow{}
+	for rows.Next() {
+		var i ListAccountsWithUnpostedInterestRow
+		if err := rows.Scan(&i.ID, &i.Currency); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInterestAccruals = `-- name: ListInterestAccruals :many
+SELECT id, account_id, accrual_date, balance, rate_bps, amount_micros, transfer_id, posted_at, created_at FROM interest_accruals
+WHERE account_id = $1
+ORDER BY accrual_date
+LIMIT $2
+OFFSET $3
+`
+
+type ListInterestAccrualsParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int64 `json:"limit"`
+	Offset    int64 `json:"offset"`
+}
+
+func (q *Queries) ListInterestAccruals(ctx context.Context, arg ListInterestAccrualsParams) ([]InterestAccrual, error) {
+	rows, err := q.db.Query(ctx, listInterestAccruals, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InterestAccrual{}
+	for rows.Next() {
+		var i InterestAccrual
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.AccrualDate,
+			&i.Balance,
+			&i.RateBps,
+			&i.AmountMicros,
+			&i.TransferID,
+			&i.PostedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSavingsBalancesAt = `-- name: ListSavingsBalancesAt :many
+SELECT id, currency, balance FROM (
+  SELECT
+    a.id,
+    a.currency,
+    (a.balance - COALESCE((
+      SELECT SUM(e.amount) FROM entries e
+      WHERE e.account_id = a.id AND e.created_at >= $1
+    ), 0))::bigint AS balance
+  FROM accounts a
+  WHERE a.type = 'savings' AND a.created_at < $1
+) b
+WHERE balance > 0
+ORDER BY id
+`
+
+type ListSavingsBalancesAtRow struct {
+	ID       int64  `json:"id"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+}
+
+func (q *Queries) ListSavingsBalancesAt(ctx context.Context, asOf time.Time) ([]ListSavingsBalancesAtRow, error) {
+	rows, err := q.db.Query(ctx, listSavingsBalancesAt, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSavingsBalancesAtRow{}
+	for rows.Next() {
+		var i ListSavingsBalancesAtRow
+		if err := rows.Scan(&i.ID, &i.Currency, &i.Balance); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markInterestPosted = `-- name: MarkInterestPosted :exec
+UPDATE interest_accruals
+SET posted_at = now(), transfer_id = $1
+WHERE account_id = $2
+  AND posted_at IS NULL
+  AND accrual_date < $3
+`
+
+type MarkInterestPostedParams struct {
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	AccountID  int64         `json:"account_id"`
+	Before     time.Time     `json:"before"`
+}
+
+func (q *Queries) MarkInterestPosted(ctx context.Context, arg MarkInterestPostedParams) error {
+	_, err := q.db.Exec(ctx, markInterestPosted, arg.TransferID, arg.AccountID, arg.Before)
+	return err
+}
+
+const sumUnpostedInterest = `-- name: SumUnpostedInterest :one
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS total_micros
+FROM interest_accruals
+WHERE account_id = $1
+  AND posted_at IS NULL
+  AND accrual_date < $2
+`
+
+type SumUnpostedInterestParams struct {
+	AccountID int64     `json:"account_id"`
+	Before    time.Time `json:"before"`
+}
+
+func (q *Queries) SumUnpostedInterest(ctx context.Context, arg SumUnpostedInterestParams) (int64, error) {
+	row := q.db.QueryRow(ctx, sumUnpostedInterest, arg.AccountID, arg.Before)
+	var total_micros int64
+	err := row.Scan(&total_micros)
+	return total_micros, err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostInterestTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	house := CreateRandomAccount(t)
+	user := CreateRandomUser(t)
+	savings, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  10_000,
+		Currency: house.Currency,
+		Type:     util.SavingsAccount,
+	})
+	require.NoError(t, err)
+
+	// Two days at 1.5 cents each round half-to-even to 3 cents
+	day := time.Date(2026, time.January, 30, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		n, err := testQueries.CreateInterestAccrual(context.Background(), CreateInterestAccrualParams{
+			AccountID:    savings.ID,
+			AccrualDate:  day.AddDate(0, 0, i),
+			Balance:      savings.Balance,
+			RateBps:      548,
+			AmountMicros: 1_500_000,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+	}
+
+	// Accruing the same day again is a no-op
+	n, err := testQueries.CreateInterestAccrual(context.Background(), CreateInterestAccrualParams{
+		AccountID:    savings.ID,
+		AccrualDate:  day,
+		Balance:      savings.Balance,
+		RateBps:      548,
+		AmountMicros: 1_500_000,
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	before := time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	result, err := store.PostInterestTx(context.Background(), PostInterestTxParams{
+		AccountID:      savings.ID,
+		HouseAccountID: house.ID,
+		Before:         before,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), result.Amount)
+	require.Equal(t, house.ID, result.Transfer.Transfer.FromAccountID)
+	require.Equal(t, savings.ID, result.Transfer.Transfer.ToAccountID)
+	require.Equal(t, savings.Balance+3, result.Transfer.ToAccount.Balance)
+	require.Equal(t, house.Balance-3, result.Transfer.FromAccount.Balance)
+
+	// Posting again finds nothing left to pay
+	result, err = store.PostInterestTx(context.Background(), PostInterestTxParams{
+		AccountID:      savings.ID,
+		HouseAccountID: house.ID,
+		Before:         before,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Amount)
+
+	accruals, err := testQueries.ListInterestAccruals(context.Background(), ListInterestAccrualsParams{
+		AccountID: savings.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accruals, 2)
+	for _, accrual := range accruals {
+		require.True(t, accrual.PostedAt.Valid)
+		require.True(t, accrual.TransferID.Valid)
+	}
+}
+
+func TestListSavingsBalancesAtSkipsEmptyAccounts(t *testing.T) {
+	user := CreateRandomUser(t)
+	funded, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  10_000,
+		Currency: util.USD,
+		Type:     util.SavingsAccount,
+	})
+	require.NoError(t, err)
+	empty, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: util.EUR,
+		Type:     util.SavingsAccount,
+	})
+	require.NoError(t, err)
+
+	balances, err := testQueries.ListSavingsBalancesAt(context.Background(), time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+
+	ids := make([]int64, len(balances))
+	for i, balance := range balances {
+		require.Positive(t, balance.Balance)
+		ids[i] = balance.ID
+	}
+	require.Contains(t, ids, funded.ID)
+	require.NotContains(t, ids, empty.ID)
+}
@@ -0,0 +1,78 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login_throttle.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const getLoginIPFailure = `-- name: GetLoginIPFailure :one
+SELECT ip, failed_attempts, locked_until, updated_at FROM login_ip_failures
+WHERE ip = $1 LIMIT 1
+`
+
+func (q *Queries) GetLoginIPFailure(ctx context.Context, ip string) (LoginIpFailure, error) {
+	row := q.db.QueryRow(ctx, getLoginIPFailure, ip)
+	var i LoginIpFailure
+	err := row.Scan(
+		&i.Ip,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const lockLoginIP = `-- name: LockLoginIP :exec
+UPDATE login_ip_failures
+SET locked_until = $2
+WHERE ip = $1
+`
+
+type LockLoginIPParams struct {
+	Ip          string       `json:"ip"`
+	LockedUntil sql.NullTime `json:"locked_until"`
+}
+
+func (q *Queries) LockLoginIP(ctx context.Context, arg LockLoginIPParams) error {
+	_, err := q.db.Exec(ctx, lockLoginIP, arg.Ip, arg.LockedUntil)
+	return err
+}
+
+const recordLoginIPFailure = `-- name: RecordLoginIPFailure :one
+INSERT INTO login_ip_failures (
+  ip,
+  failed_attempts
+) VALUES (
+  $1, 1
+)
+ON CONFLICT (ip) DO UPDATE
+SET failed_attempts = CASE
+      WHEN login_ip_failures.updated_at < $2 THEN 1
+      ELSE login_ip_failures.failed_attempts + 1
+    END,
+    updated_at = now()
+RETURNING ip, failed_attempts, locked_until, updated_at
+`
+
+type RecordLoginIPFailureParams struct {
+	Ip          string    `json:"ip"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+func (q *Queries) RecordLoginIPFailure(ctx context.Context, arg RecordLoginIPFailureParams) (LoginIpFailure, error) {
+	row := q.db.QueryRow(ctx, recordLoginIPFailure, arg.Ip, arg.WindowStart)
+	var i LoginIpFailure
+	err := row.Scan(
+		&i.Ip,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
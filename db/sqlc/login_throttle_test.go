@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserLoginLockout(t *testing.T) {
+	user := CreateRandomUser(t)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.False(t, user.LockedUntil.Valid)
+
+	for i := int32(1); i <= 3; i++ {
+		attempts, err := testQueries.RecordUserLoginFailure(context.Background(), user.Username)
+		require.NoError(t, err)
+		require.Equal(t, i, attempts)
+	}
+
+	lockedUntil := time.Now().UTC().Add(time.Minute)
+	err := testQueries.LockUser(context.Background(), LockUserParams{
+		Username:    user.Username,
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	})
+	require.NoError(t, err)
+
+	locked, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), locked.FailedLoginAttempts)
+	require.WithinDuration(t, lockedUntil, locked.LockedUntil.Time, time.Second)
+
+	unlocked, err := testQueries.UnlockUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Zero(t, unlocked.FailedLoginAttempts)
+	require.False(t, unlocked.LockedUntil.Valid)
+}
+
+func TestLoginIPFailures(t *testing.T) {
+	ip := util.RandomString(12)
+
+	_, err := testQueries.GetLoginIPFailure(context.Background(), ip)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	arg := RecordLoginIPFailureParams{
+		Ip:          ip,
+		WindowStart: time.Now().UTC().Add(-time.Hour),
+	}
+	for i := int32(1); i <= 2; i++ {
+		failure, err := testQueries.RecordLoginIPFailure(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, failure.FailedAttempts)
+	}
+
+	// Failures older than the window start the count over
+	arg.WindowStart = time.Now().UTC().Add(time.Hour)
+	failure, err := testQueries.RecordLoginIPFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failure.FailedAttempts)
+
+	lockedUntil := time.Now().UTC().Add(time.Minute)
+	err = testQueries.LockLoginIP(context.Background(), LockLoginIPParams{
+		Ip:          ip,
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	})
+	require.NoError(t, err)
+
+	failure, err = testQueries.GetLoginIPFailure(context.Background(), ip)
+	require.NoError(t, err)
+	require.WithinDuration(t, lockedUntil, failure.LockedUntil.Time, time.Second)
+}
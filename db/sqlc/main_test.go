@@ -1,20 +1,20 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
 	"testing"
 
-	_ "github.com/lib/pq" // PostgreSQL driver for database/sql
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 )
 
 // testQueries will be used to execute SQL queries in tests
 var testQueries *Queries
 
-// testDB is the database connection used for testing
-var testDB *sql.DB
+// testDB is the database connection pool used for testing
+var testDB *pgxpool.Pool
 
 // TestMain is the entry point for testing in this package.
 // It sets up the test database connection and initializes testQueries before running tests.
@@ -25,8 +25,8 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot load config:", err) // Stop tests if config can't be loaded
 	}
 
-	// Open a connection to the test database using the loaded config
-	testDB, err = sql.Open(config.DBDriver, config.DBSource)
+	// Open a connection pool to the test database using the loaded config
+	testDB, err = NewPool(context.Background(), config.DBSource, config)
 	if err != nil {
 		log.Fatal("Cannot connect to db:", err) // Stop tests if DB can't be connected
 	}
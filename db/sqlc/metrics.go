@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace prefixes every metric the application exports
+const metricsNamespace = "simplebank"
+
+// collectTimeout bounds the ledger queries run on a scrape
+const collectTimeout = 5 * time.Second
+
+// statsRefreshInterval is how long ledger totals are served from cache before a
+// scrape queries them again. The queries aggregate whole tables, so running them on
+// every scrape from every Prometheus replica would load the database for no benefit.
+const statsRefreshInterval = time.Minute
+
+// Transfer outcomes used as the "outcome" label
+const (
+	transferCommitted = "committed"
+	transferFailed    = "failed"
+)
+
+var (
+	transferTxDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "transfer_tx_duration_seconds",
+		Help:      "Time taken by TransferTx, including retries, by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	transferTxTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transfer_tx_total",
+		Help:      "TransferTx calls by outcome.",
+	}, []string{"outcome"})
+
+	transferTxRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transfer_tx_retries_total",
+		Help:      "Money-moving transactions (TransferTx, PostInterestTx) repeated after a deadlock or serialization failure.",
+	})
+)
+
+// dbCollector exports connection pool stats and business gauges computed from the
+// ledger, refreshed at most once per statsRefreshInterval
+type dbCollector struct {
+	pool            prometheus.Collector
+	load            func(context.Context) (ledgerStats, error)
+	refreshInterval time.Duration
+
+	mu       sync.Mutex
+	stats    ledgerStats // Last ledger totals loaded
+	loadedAt time.Time   // When stats were loaded, zero before the first load
+
+	transfers      *prometheus.Desc
+	transferAmount *prometheus.Desc
+	accounts       *prometheus.Desc
+	balance        *prometheus.Desc
+}
+
+// NewCollector returns a Prometheus collector for the connection pools the SQLStore
+// runs on and for ledger totals per currency. replica may be nil; when set, the
+// ledger totals are read from it.
+func NewCollector(primary *pgxpool.Pool, replica *pgxpool.Pool) prometheus.Collector {
+	pools := map[string]*pgxpool.Pool{"primary": primary}
+	var conn DBTX = primary
+	if replica != nil {
+		pools["replica"] = replica
+		conn = &routedDBTX{primary: primary, replica: replica}
+	}
+
+	queries := New(conn)
+	return &dbCollector{
+		pool:            newPoolCollector(pools),
+		load:            queries.loadLedgerStats,
+		refreshInterval: statsRefreshInterval,
+		transfers: prometheus.NewDesc(metricsNamespace+"_transfers",
+			"Transfers made, by currency.", []string{"currency"}, nil),
+		transferAmount: prometheus.NewDesc(metricsNamespace+"_transfer_amount",
+			"Sum of transfer amounts in minor units, by currency.", []string{"currency"}, nil),
+		accounts: prometheus.NewDesc(metricsNamespace+"_accounts",
+			"Open accounts, by currency and type.", []string{"currency", "type"}, nil),
+		balance: prometheus.NewDesc(metricsNamespace+"_account_balance",
+			"Sum of account balances in minor units, by currency and type.", []string{"currency", "type"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (collector *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	collector.pool.Describe(ch)
+	ch <- collector.transfers
+	ch <- collector.transferAmount
+	ch <- collector.accounts
+	ch <- collector.balance
+}
+
+// Collect implements prometheus.Collector. A failed ledger query is logged and the
+// last totals loaded are exported instead, so the rest of the scrape still succeeds.
+func (collector *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.pool.Collect(ch)
+
+	stats := collector.ledgerStats()
+	for _, row := range stats.transfers {
+		ch <- prometheus.MustNewConstMetric(collector.transfers, prometheus.GaugeValue, float64(row.Transfers), row.Currency)
+		ch <- prometheus.MustNewConstMetric(collector.transferAmount, prometheus.GaugeValue, float64(row.Amount), row.Currency)
+	}
+	for _, row := range stats.accounts {
+		ch <- prometheus.MustNewConstMetric(collector.accounts, prometheus.GaugeValue, float64(row.Accounts), row.Currency, row.Type)
+		ch <- prometheus.MustNewConstMetric(collector.balance, prometheus.GaugeValue, float64(row.Balance), row.Currency, row.Type)
+	}
+}
+
+// ledgerStats returns the cached ledger totals, loading them again first when they
+// are older than the refresh interval. Concurrent scrapes share a single load.
+func (collector *dbCollector) ledgerStats() ledgerStats {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	if !collector.loadedAt.IsZero() && time.Since(collector.loadedAt) < collector.refreshInterval {
+		return collector.stats
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	stats, err := collector.load(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "cannot collect ledger stats", "error", err)
+		return collector.stats
+	}
+	collector.stats = stats
+	collector.loadedAt = time.Now()
+	return stats
+}
+
+// ledgerStats are the ledger totals exported as business gauges
+type ledgerStats struct {
+	transfers []ListTransferStatsRow
+	accounts  []ListAccountStatsRow
+}
+
+// loadLedgerStats aggregates the transfer and account totals per currency
+func (q *Queries) loadLedgerStats(ctx context.Context) (ledgerStats, error) {
+	var stats ledgerStats
+	var err error
+
+	stats.transfers, err = q.ListTransferStats(ctx)
+	if err != nil {
+		return ledgerStats{}, fmt.Errorf("transfer stats: %w", err)
+	}
+	stats.accounts, err = q.ListAccountStats(ctx)
+	if err != nil {
+		return ledgerStats{}, fmt.Errorf("account stats: %w", err)
+	}
+	return stats, nil
+}
+
+// poolCollector exports the statistics of pgx connection pools, labelled with their names
+type poolCollector struct {
+	pools map[string]*pgxpool.Pool
+
+	maxConns         *prometheus.Desc
+	totalConns       *prometheus.Desc
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	acquires         *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	lifetimeClosed   *prometheus.Desc
+	idleClosed       *prometheus.Desc
+}
+
+func newPoolCollector(pools map[string]*pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(metricsNamespace+"_db_pool_"+name, help, []string{"pool"}, nil)
+	}
+
+	return &poolCollector{
+		pools:            pools,
+		maxConns:         desc("max_connections", "Most connections the pool may open."),
+		totalConns:       desc("open_connections", "Connections currently open, in use or idle."),
+		acquiredConns:    desc("in_use_connections", "Connections currently in use."),
+		idleConns:        desc("idle_connections", "Connections currently idle."),
+		acquires:         desc("acquires_total", "Connections handed out by the pool."),
+		emptyAcquires:    desc("acquire_waits_total", "Acquires that had to wait for a connection to be freed or opened."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires given up by their context before getting a connection."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Time spent getting connections from the pool."),
+		lifetimeClosed:   desc("lifetime_closed_total", "Connections closed for exceeding DB_MAX_CONN_LIFETIME."),
+		idleClosed:       desc("idle_closed_total", "Connections closed for exceeding DB_MAX_CONN_IDLE_TIME."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (collector *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(collector, ch)
+}
+
+// Collect implements prometheus.Collector
+func (collector *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, pool := range collector.pools {
+		stat := pool.Stat()
+		metric := func(desc *prometheus.Desc, valueType prometheus.ValueType, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, valueType, value, name)
+		}
+
+		metric(collector.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+		metric(collector.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+		metric(collector.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+		metric(collector.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+		metric(collector.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+		metric(collector.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+		metric(collector.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+		metric(collector.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+		metric(collector.lifetimeClosed, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()))
+		metric(collector.idleClosed, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()))
+	}
+}
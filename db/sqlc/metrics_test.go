@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxMetrics(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	committed := testutil.ToFloat64(transferTxTotal.WithLabelValues(transferCommitted))
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, committed+1, testutil.ToFloat64(transferTxTotal.WithLabelValues(transferCommitted)))
+
+	// The pool stats and ledger gauges are all collected
+	collector := NewCollector(testDB, nil)
+	require.NotZero(t, testutil.CollectAndCount(collector, "simplebank_db_pool_open_connections"))
+	require.NotZero(t, testutil.CollectAndCount(collector, "simplebank_transfers"))
+	require.NotZero(t, testutil.CollectAndCount(collector, "simplebank_account_balance"))
+}
+
+func TestCollectorCachesLedgerStats(t *testing.T) {
+	loads := 0
+	var loadErr error
+	collector := NewCollector(testDB, nil).(*dbCollector)
+	collector.load = func(context.Context) (ledgerStats, error) {
+		loads++
+		if loadErr != nil {
+			return ledgerStats{}, loadErr
+		}
+		return ledgerStats{transfers: []ListTransferStatsRow{{Currency: "USD", Transfers: int64(loads), Amount: 100}}}, nil
+	}
+
+	// Scrapes within the refresh interval reuse the first load
+	for i := 0; i < 3; i++ {
+		require.Equal(t, 1, testutil.CollectAndCount(collector, "simplebank_transfers"))
+	}
+	require.Equal(t, 1, loads)
+
+	// Once it has passed, a failed load keeps serving the last totals
+	collector.refreshInterval = 0
+	loadErr = errors.New("connection refused")
+	require.Equal(t, 1, testutil.CollectAndCount(collector, "simplebank_transfers"))
+	require.Equal(t, 2, loads)
+
+	loadErr = nil
+	collector.refreshInterval = time.Hour
+	collector.loadedAt = time.Time{}
+	require.Equal(t, 1, testutil.CollectAndCount(collector, "simplebank_transfers"))
+	require.Equal(t, 3, loads)
+	require.Equal(t, int64(3), collector.stats.transfers[0].Transfers)
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMFANotPending is returned by EnableMFATx when there is no enrollment awaiting
+// confirmation, or the confirming code was already used.
+var ErrMFANotPending = errors.New("no pending two-factor enrollment to confirm")
+
+// EnableMFATxParams contains the input parameters for confirming a TOTP enrollment.
+type EnableMFATxParams struct {
+	Username           string   `json:"username"`
+	Step               int64    `json:"step"`                 // Time step of the confirming TOTP code
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"` // Hashes of the recovery codes shown to the user
+}
+
+// EnableMFATx turns on a pending TOTP enrollment and replaces the user's recovery codes.
+func (store *SQLStore) EnableMFATx(ctx context.Context, arg EnableMFATxParams, audit MFAAudit) (UserMfa, error) {
+	var mfa UserMfa
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+
+		// 1. Enable the enrollment, consuming the confirming code's time step
+		mfa, err = q.EnableUserMFA(ctx, EnableUserMFAParams{
+			Username:     arg.Username,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrMFANotPending
+			}
+			return err
+		}
+
+		// 2. Replace any previous recovery codes
+		if err := q.DeleteMFARecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+
+		for _, hash := range arg.RecoveryCodeHashes {
+			if err := q.CreateMFARecoveryCode(ctx, CreateMFARecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: hash,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, audited(audit, &mfa))
+
+	return mfa, err
+}
@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: mfa.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createMFARecoveryCode = `-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+)
+`
+
+type CreateMFARecoveryCodeParams struct {
+	Username string `json:"username"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) CreateMFARecoveryCode(ctx context.Context, arg CreateMFARecoveryCodeParams) error {
+	_, err := q.db.Exec(ctx, createMFARecoveryCode, arg.Username, arg.CodeHash)
+	return err
+}
+
+const deleteMFARecoveryCodes = `-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteMFARecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteMFARecoveryCodes, username)
+	return err
+}
+
+const enableUserMFA = `-- name: EnableUserMFA :one
+UPDATE user_mfa
+SET is_enabled = true,
+    last_used_step = $2,
+    enabled_at = now()
+WHERE username = $1 AND is_enabled = false AND last_used_step < $2
+RETURNING username, totp_secret, is_enabled, last_used_step, enabled_at, created_at
+`
+
+type EnableUserMFAParams struct {
+	Username     string `json:"username"`
+	LastUsedStep int64  `json:"last_used_step"`
+}
+
+func (q *Queries) EnableUserMFA(ctx context.Context, arg EnableUserMFAParams) (UserMfa, error) {
+	row := q.db.QueryRow(ctx, enableUserMFA, arg.Username, arg.LastUsedStep)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.TotpSecret,
+		&i.IsEnabled,
+		&i.LastUsedStep,
+		&i.EnabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserMFA = `-- name: GetUserMFA :one
+SELECT username, totp_secret, is_enabled, last_used_step, enabled_at, created_at FROM user_mfa
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserMFA(ctx context.Context, username string) (UserMfa, error) {
+	row := q.db.QueryRow(ctx, getUserMFA, username)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.TotpSecret,
+		&i.IsEnabled,
+		&i.LastUsedStep,
+		&i.EnabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertPendingUserMFA = `-- name: UpsertPendingUserMFA :one
+INSERT INTO user_mfa (
+  username,
+  totp_secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+    last_used_step = 0,
+    created_at = now()
+WHERE user_mfa.is_enabled = false
+RETURNING username, totp_secret, is_enabled, last_used_step, enabled_at, created_at
+`
+
+type UpsertPendingUserMFAParams struct {
+	Username   string `json:"username"`
+	TotpSecret string `json:"totp_secret"`
+}
+
+func (q *Queries) UpsertPendingUserMFA(ctx context.Context, arg UpsertPendingUserMFAParams) (UserMfa, error) {
+	row := q.db.QueryRow(ctx, upsertPendingUserMFA, arg.Username, arg.TotpSecret)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.TotpSecret,
+		&i.IsEnabled,
+		&i.LastUsedStep,
+		&i.EnabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useMFARecoveryCode = `-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+type UseMFARecoveryCodeParams struct {
+	Username string `json:"username"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) UseMFARecoveryCode(ctx context.Context, arg UseMFARecoveryCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useMFARecoveryCode, arg.Username, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE username = $1 AND is_enabled = true AND last_used_step < $2
+`
+
+type UseTOTPStepParams struct {
+	Username     string `json:"username"`
+	LastUsedStep int64  `json:"last_used_step"`
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useTOTPStep, arg.Username, arg.LastUsedStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableMFATx(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	pending, err := testQueries.UpsertPendingUserMFA(context.Background(), UpsertPendingUserMFAParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	})
+	require.NoError(t, err)
+	require.False(t, pending.IsEnabled)
+
+	codes := []string{util.HashSecureToken("aaaaa-bbbbb"), util.HashSecureToken("ccccc-ddddd")}
+	mfa, err := store.EnableMFATx(context.Background(), EnableMFATxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: codes,
+	}, nil)
+	require.NoError(t, err)
+	require.True(t, mfa.IsEnabled)
+	require.Equal(t, int64(100), mfa.LastUsedStep)
+
+	// Enabling twice fails and re-enrolling can't overwrite the secret
+	_, err = store.EnableMFATx(context.Background(), EnableMFATxParams{Username: user.Username, Step: 101}, nil)
+	require.ErrorIs(t, err, ErrMFANotPending)
+
+	_, err = testQueries.UpsertPendingUserMFA(context.Background(), UpsertPendingUserMFAParams{
+		Username:   user.Username,
+		TotpSecret: "OTHER",
+	})
+	require.Error(t, err)
+
+	// Time steps and recovery codes are single-use
+	n, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, LastUsedStep: 100})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, LastUsedStep: 101})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	for _, want := range []int64{1, 0} {
+		n, err = testQueries.UseMFARecoveryCode(context.Background(), UseMFARecoveryCodeParams{
+			Username: user.Username,
+			CodeHash: codes[0],
+		})
+		require.NoError(t, err)
+		require.Equal(t, want, n)
+	}
+}
@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +18,56 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	Type      string    `json:"type"`
+}
+
+type ApiKey struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// public part of the key, used to look it up
+	Prefix string `json:"prefix"`
+	// hex SHA-256 of the secret part; the key itself is never stored
+	SecretHash string       `json:"secret_hash"`
+	Scopes     []string     `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// append-only, hash-chained record of security and money-moving events
+type AuditEvent struct {
+	ID int64 `json:"id"`
+	// user who did it; empty for anonymous requests such as failed logins
+	Actor string `json:"actor"`
+	// banker acting as the actor, if any
+	Impersonator string `json:"impersonator"`
+	// e.g. user.login, account.created, transfer.created
+	Action string `json:"action"`
+	// what it was done to, e.g. user:alice, account:42
+	Target    string          `json:"target"`
+	ClientIp  string          `json:"client_ip"`
+	RequestID string          `json:"request_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	PrevHash  string          `json:"prev_hash"`
+	// sha256 over prev_hash and the event, so edits and deletions break the chain
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type EmailVerification struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// hex SHA-256 of the emailed token, set when the email is sent
+	TokenHash sql.NullString `json:"token_hash"`
+	Attempts  int32          `json:"attempts"`
+	LastError sql.NullString `json:"last_error"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+	SentAt    sql.NullTime   `json:"sent_at"`
+	UsedAt    sql.NullTime   `json:"used_at"`
+	CreatedAt time.Time      `json:"created_at"`
 }
 
 type Entry struct {
@@ -24,6 +78,101 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// act-as tokens issued to bankers, kept for auditing
+type Impersonation struct {
+	ID      int64     `json:"id"`
+	TokenID uuid.UUID `json:"token_id"`
+	// banker the token was issued to
+	Actor string `json:"actor"`
+	// customer the banker acts as
+	Subject   string    `json:"subject"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InterestAccrual struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	AccrualDate time.Time `json:"accrual_date"`
+	// end-of-day balance the interest was computed on
+	Balance int64 `json:"balance"`
+	RateBps int64 `json:"rate_bps"`
+	// accrued interest in millionths of the minor currency unit
+	AmountMicros int64         `json:"amount_micros"`
+	TransferID   sql.NullInt64 `json:"transfer_id"`
+	PostedAt     sql.NullTime  `json:"posted_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// days interest was accrued for on every savings account, so days without any accrual are not accrued again
+type InterestAccrualDay struct {
+	AccrualDate time.Time `json:"accrual_date"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LoginIpFailure struct {
+	Ip             string       `json:"ip"`
+	FailedAttempts int32        `json:"failed_attempts"`
+	LockedUntil    sql.NullTime `json:"locked_until"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// hex SHA-256 of the normalized recovery code
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type OidcLoginRequest struct {
+	State string `json:"state"`
+	Nonce string `json:"nonce"`
+	// PKCE verifier; only its S256 challenge is sent to the provider
+	CodeVerifier string    `json:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Outbox struct {
+	ID int64 `json:"id"`
+	// e.g. transfer.created, account.created, account.closed
+	EventType     string          `json:"event_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int32           `json:"attempts"`
+	LastError     sql.NullString  `json:"last_error"`
+	PublishedAt   sql.NullTime    `json:"published_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	// set when publishing failed too many times; the event is no longer retried
+	DeadAt sql.NullTime `json:"dead_at"`
+}
+
+type PasswordResetToken struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// hex SHA-256 of the emailed token, set when the email is sent; the token itself is never stored
+	TokenHash sql.NullString `json:"token_hash"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+	UsedAt    sql.NullTime   `json:"used_at"`
+	CreatedAt time.Time      `json:"created_at"`
+	Email     string         `json:"email"`
+	Attempts  int32          `json:"attempts"`
+	LastError sql.NullString `json:"last_error"`
+	SentAt    sql.NullTime   `json:"sent_at"`
+}
+
+// request counters shared by every API replica, one fixed window per key
+type RateLimit struct {
+	// route group and client, e.g. transfers:user:alice or auth:ip:203.0.113.7
+	Key         string    `json:"key"`
+	WindowStart time.Time `json:"window_start"`
+	Hits        int64     `json:"hits"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
@@ -40,4 +189,56 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	// depositor or banker
+	Role string `json:"role"`
+	// consecutive failed logins, reset on success or by a banker
+	FailedLoginAttempts int32        `json:"failed_login_attempts"`
+	LockedUntil         sql.NullTime `json:"locked_until"`
+	// set when the user closed their profile; the row is kept, anonymized, for the ledger
+	DeletedAt sql.NullTime `json:"deleted_at"`
+}
+
+// links a subject at an external OpenID Connect provider to a local user
+type UserIdentity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserMfa struct {
+	Username   string `json:"username"`
+	TotpSecret string `json:"totp_secret"`
+	IsEnabled  bool   `json:"is_enabled"`
+	// TOTP time step of the last accepted code, so a code cannot be replayed
+	LastUsedStep int64        `json:"last_used_step"`
+	EnabledAt    sql.NullTime `json:"enabled_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	// pending, succeeded or dead
+	Status         string         `json:"status"`
+	Attempts       int32          `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	LastStatusCode sql.NullInt32  `json:"last_status_code"`
+	LastError      sql.NullString `json:"last_error"`
+	DeliveredAt    sql.NullTime   `json:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LedgerChannel is the Postgres LISTEN/NOTIFY channel ledger changes are announced on.
+const LedgerChannel = "ledger_events"
+
+// TransferNotification is the NOTIFY payload sent for every committed transfer.
+// It carries the owners and new balances so listeners can route it without querying.
+type TransferNotification struct {
+	TransferID    int64     `json:"transfer_id"`
+	FromAccountID int64     `json:"from_account_id"`
+	FromOwner     string    `json:"from_owner"`
+	FromBalance   int64     `json:"from_balance"`
+	ToAccountID   int64     `json:"to_account_id"`
+	ToOwner       string    `json:"to_owner"`
+	ToBalance     int64     `json:"to_balance"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// notifyTransfer queues a NOTIFY for a transfer on the transactional Queries.
+// Postgres only delivers it once the surrounding transaction commits.
+func notifyTransfer(ctx context.Context, q *Queries, result TransferTxResult) error {
+	payload, err := json.Marshal(TransferNotification{
+		TransferID:    result.Transfer.ID,
+		FromAccountID: result.FromAccount.ID,
+		FromOwner:     result.FromAccount.Owner,
+		FromBalance:   result.FromAccount.Balance,
+		ToAccountID:   result.ToAccount.ID,
+		ToOwner:       result.ToAccount.Owner,
+		ToBalance:     result.ToAccount.Balance,
+		Amount:        result.Transfer.Amount,
+		Currency:      result.ToAccount.Currency,
+		CreatedAt:     result.Transfer.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal transfer notification: %w", err)
+	}
+
+	return q.NotifyLedger(ctx, NotifyLedgerParams{
+		Channel: LedgerChannel,
+		Payload: string(payload),
+	})
+}
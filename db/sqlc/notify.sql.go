@@ -0,0 +1,24 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: notify.sql
+
+package db
+
+import (
+	"context"
+)
+
+const notifyLedger = `-- name: NotifyLedger :exec
+SELECT pg_notify($1::text, $2::text)
+`
+
+type NotifyLedgerParams struct {
+	Channel string `json:"channel"`
+	Payload string `json:"payload"`
+}
+
+func (q *Queries) NotifyLedger(ctx context.Context, arg NotifyLedgerParams) error {
+	_, err := q.db.Exec(ctx, notifyLedger, arg.Channel, arg.Payload)
+	return err
+}
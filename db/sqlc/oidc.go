@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+var (
+	// ErrIdentityNotLinked is returned by OIDCLoginTx for an unknown subject when provisioning is off.
+	ErrIdentityNotLinked = errors.New("identity is not linked to a user")
+	// ErrEmailInUse is returned by OIDCLoginTx when a new identity's email belongs to a local user.
+	ErrEmailInUse = errors.New("email is already used by another user")
+)
+
+// maxUsernameAttempts bounds the search for a free username when provisioning a user
+const maxUsernameAttempts = 5
+
+// OIDCLoginTxParams describes an identity that signed in at an OpenID Connect provider.
+type OIDCLoginTxParams struct {
+	Issuer        string         `json:"issuer"`
+	Subject       string         `json:"subject"`
+	Username      string         `json:"username"` // Preferred username for a provisioned user; a suffix is added when taken
+	FullName      string         `json:"full_name"`
+	Email         string         `json:"email"`
+	EmailVerified bool           `json:"email_verified"` // Whether the provider vouches for the email
+	Role          sql.NullString `json:"role"`           // Role granted by the provider; the stored role is kept when invalid
+	Provision     bool           `json:"provision"`      // Create a user for an unknown subject
+}
+
+// OIDCLoginTx finds the user linked to a provider subject, creating and linking one on
+// first sign-in when arg.Provision is set, and syncs the role the provider grants.
+// A provisioned user has no password, so they can only sign in through the provider.
+func (store *SQLStore) OIDCLoginTx(ctx context.Context, arg OIDCLoginTxParams) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Find the linked user, or provision one
+		identity, err := q.GetUserIdentity(ctx, GetUserIdentityParams{
+			Issuer:  arg.Issuer,
+			Subject: arg.Subject,
+		})
+		switch {
+		case err == nil:
+			user, err = q.GetUser(ctx, identity.Username)
+		case errors.Is(err, ErrRecordNotFound) && arg.Provision:
+			user, err = provisionUser(ctx, q, arg)
+		case errors.Is(err, ErrRecordNotFound):
+			err = ErrIdentityNotLinked
+		}
+		if err != nil {
+			return err
+		}
+
+		// 2. Keep the role in step with the provider
+		if !arg.Role.Valid || arg.Role.String == user.Role || user.DeletedAt.Valid {
+			return nil
+		}
+		user, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: user.Username,
+			Role:     arg.Role.String,
+		})
+		return err
+	}, nil)
+
+	return user, err
+}
+
+// provisionUser creates a password-less user for a new identity and links the two
+func provisionUser(ctx context.Context, q *Queries, arg OIDCLoginTxParams) (User, error) {
+	// 1. Never take over a local user through a matching email
+	_, err := q.GetUserByEmail(ctx, arg.Email)
+	if err == nil {
+		return User{}, ErrEmailInUse
+	}
+	if !errors.Is(err, ErrRecordNotFound) {
+		return User{}, err
+	}
+
+	// 2. Pick a free username
+	username, err := freeUsername(ctx, q, arg.Username)
+	if err != nil {
+		return User{}, err
+	}
+
+	// 3. Create the user without a password and link the identity
+	user, err := q.CreateUser(ctx, CreateUserParams{
+		Username:       username,
+		HashedPassword: "",
+		FullName:       arg.FullName,
+		Email:          arg.Email,
+	})
+	if err != nil {
+		return User{}, err
+	}
+	_, err = q.CreateUserIdentity(ctx, CreateUserIdentityParams{
+		Issuer:   arg.Issuer,
+		Subject:  arg.Subject,
+		Username: user.Username,
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	// 4. Trust the provider's email verification, or send our own
+	if arg.EmailVerified {
+		return q.MarkUserEmailVerified(ctx, user.Username)
+	}
+	_, err = q.CreateEmailVerification(ctx, CreateEmailVerificationParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	return user, err
+}
+
+// freeUsername returns base if no user has it, otherwise base with a random suffix
+func freeUsername(ctx context.Context, q *Queries, base string) (string, error) {
+	username := base
+	for i := 0; i < maxUsernameAttempts; i++ {
+		_, err := q.GetUser(ctx, username)
+		if errors.Is(err, ErrRecordNotFound) {
+			return username, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		username = base + util.RandomString(4)
+	}
+	return "", fmt.Errorf("no free username for %q", base)
+}
@@ -0,0 +1,138 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: oidc.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const consumeOIDCLoginRequest = `-- name: ConsumeOIDCLoginRequest :one
+DELETE FROM oidc_login_requests
+WHERE state = $1
+RETURNING state, nonce, code_verifier, expires_at, created_at
+`
+
+func (q *Queries) ConsumeOIDCLoginRequest(ctx context.Context, state string) (OidcLoginRequest, error) {
+	row := q.db.QueryRow(ctx, consumeOIDCLoginRequest, state)
+	var i OidcLoginRequest
+	err := row.Scan(
+		&i.State,
+		&i.Nonce,
+		&i.CodeVerifier,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createOIDCLoginRequest = `-- name: CreateOIDCLoginRequest :one
+INSERT INTO oidc_login_requests (
+  state,
+  nonce,
+  code_verifier,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING state, nonce, code_verifier, expires_at, created_at
+`
+
+type CreateOIDCLoginRequestParams struct {
+	State        string    `json:"state"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateOIDCLoginRequest(ctx context.Context, arg CreateOIDCLoginRequestParams) (OidcLoginRequest, error) {
+	row := q.db.QueryRow(ctx, createOIDCLoginRequest,
+		arg.State,
+		arg.Nonce,
+		arg.CodeVerifier,
+		arg.ExpiresAt,
+	)
+	var i OidcLoginRequest
+	err := row.Scan(
+		&i.State,
+		&i.Nonce,
+		&i.CodeVerifier,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createUserIdentity = `-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+  issuer,
+  subject,
+  username
+) VALUES (
+  $1, $2, $3
+)
+RETURNING issuer, subject, username, created_at
+`
+
+type CreateUserIdentityParams struct {
+	Issuer   string `json:"issuer"`
+	Subject  string `json:"subject"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRow(ctx, createUserIdentity, arg.Issuer, arg.Subject, arg.Username)
+	var i UserIdentity
+	err := row.Scan(
+		&i.Issuer,
+		&i.Subject,
+		&i.Username,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredOIDCLoginRequests = `-- name: DeleteExpiredOIDCLoginRequests :exec
+DELETE FROM oidc_login_requests
+WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredOIDCLoginRequests(ctx context.Context, expiresAt time.Time) error {
+	_, err := q.db.Exec(ctx, deleteExpiredOIDCLoginRequests, expiresAt)
+	return err
+}
+
+const deleteUserIdentities = `-- name: DeleteUserIdentities :exec
+DELETE FROM user_identities
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserIdentities(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteUserIdentities, username)
+	return err
+}
+
+const getUserIdentity = `-- name: GetUserIdentity :one
+SELECT issuer, subject, username, created_at FROM user_identities
+WHERE issuer = $1 AND subject = $2 LIMIT 1
+`
+
+type GetUserIdentityParams struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRow(ctx, getUserIdentity, arg.Issuer, arg.Subject)
+	var i UserIdentity
+	err := row.Scan(
+		&i.Issuer,
+		&i.Subject,
+		&i.Username,
+		&i.CreatedAt,
+	)
+	return i, err
+}
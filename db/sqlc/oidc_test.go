@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomOIDCLogin() OIDCLoginTxParams {
+	return OIDCLoginTxParams{
+		Issuer:        "https://idp.example.com",
+		Subject:       util.RandomString(12),
+		Username:      util.RandomOwner(),
+		FullName:      util.RandomOwner(),
+		Email:         util.RandomEmail(),
+		EmailVerified: true,
+		Provision:     true,
+	}
+}
+
+func TestOIDCLoginTxProvision(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomOIDCLogin()
+	arg.Role = sql.NullString{String: util.BankerRole, Valid: true}
+
+	// The first sign-in creates a password-less, verified user
+	user, err := store.OIDCLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, user.Username)
+	require.Equal(t, arg.Email, user.Email)
+	require.Empty(t, user.HashedPassword)
+	require.True(t, user.IsEmailVerified)
+	require.Equal(t, util.BankerRole, user.Role)
+
+	identity, err := testQueries.GetUserIdentity(context.Background(), GetUserIdentityParams{
+		Issuer:  arg.Issuer,
+		Subject: arg.Subject,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, identity.Username)
+
+	// Later sign-ins find the same user and follow the provider's role
+	arg.Role.String = util.DepositorRole
+	again, err := store.OIDCLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, again.Username)
+	require.Equal(t, util.DepositorRole, again.Role)
+}
+
+func TestOIDCLoginTxUsernameTaken(t *testing.T) {
+	store := NewStore(testDB)
+	existing := CreateRandomUser(t)
+
+	arg := randomOIDCLogin()
+	arg.Username = existing.Username
+
+	user, err := store.OIDCLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEqual(t, existing.Username, user.Username)
+	require.Contains(t, user.Username, existing.Username)
+}
+
+func TestOIDCLoginTxEmailInUse(t *testing.T) {
+	store := NewStore(testDB)
+	existing := CreateRandomUser(t)
+
+	arg := randomOIDCLogin()
+	arg.Email = existing.Email
+
+	_, err := store.OIDCLoginTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrEmailInUse)
+}
+
+func TestOIDCLoginTxNotLinked(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomOIDCLogin()
+	arg.Provision = false
+
+	_, err := store.OIDCLoginTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdentityNotLinked)
+}
+
+func TestConsumeOIDCLoginRequest(t *testing.T) {
+	arg := CreateOIDCLoginRequestParams{
+		State:        util.RandomString(32),
+		Nonce:        util.RandomString(32),
+		CodeVerifier: util.RandomString(43),
+		ExpiresAt:    time.Now().UTC().Add(time.Minute),
+	}
+	_, err := testQueries.CreateOIDCLoginRequest(context.Background(), arg)
+	require.NoError(t, err)
+
+	login, err := testQueries.ConsumeOIDCLoginRequest(context.Background(), arg.State)
+	require.NoError(t, err)
+	require.Equal(t, arg.CodeVerifier, login.CodeVerifier)
+
+	// A state can only be used once
+	_, err = testQueries.ConsumeOIDCLoginRequest(context.Background(), arg.State)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// Domain event types written to the outbox table.
+const (
+	EventTransferCreated = "transfer.created"
+	EventAccountCreated  = "account.created"
+	EventAccountClosed   = "account.closed"
+)
+
+// Retry policy for events that fail to publish
+const (
+	outboxMaxAttempts = 10
+	outboxBaseBackoff = time.Second
+	outboxMaxBackoff  = time.Hour
+)
+
+// recordEvent records a domain event using the given transactional Queries,
+// so the event is committed or rolled back together with the change it describes.
+func recordEvent(ctx context.Context, q *Queries, eventType string, aggregateID int64, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s payload: %w", eventType, err)
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:   eventType,
+		AggregateID: strconv.FormatInt(aggregateID, 10),
+		Payload:     data,
+	})
+	return err
+}
+
+// PublishOutboxTx locks up to limit due outbox events, hands them to publish in
+// ID order and marks each one published. Rows locked by another relay are skipped.
+// It stops at the first publish failure, recording the error on that event and
+// retrying it after an exponential backoff, so one event that can't be published
+// doesn't hold up the others; after outboxMaxAttempts failures it is dead-lettered and
+// no longer retried. Events are therefore delivered at least once, unless dead. Ordering
+// is only kept within a batch: concurrent relays publish their batches independently,
+// and a retried event may come after later ones, so consumers must not rely on it.
+func (store *SQLStore) PublishOutboxTx(ctx context.Context, limit int64, publish func(context.Context, Outbox) error) (int, error) {
+	published := 0
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Claim pending events for this transaction
+		events, err := q.LockPendingOutboxEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			// 2. Publish; on failure schedule a retry, or give up, and stop the batch
+			if err := publish(ctx, event); err != nil {
+				dead := event.Attempts+1 >= outboxMaxAttempts
+				if dead {
+					slog.ErrorContext(ctx, "outbox event dead-lettered", "event_id", event.ID, "event_type", event.EventType, "attempts", event.Attempts+1, "error", err)
+				} else {
+					slog.WarnContext(ctx, "outbox event publish failed", "event_id", event.ID, "event_type", event.EventType, "attempts", event.Attempts+1, "error", err)
+				}
+				return q.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+					ID:                event.ID,
+					LastError:         sql.NullString{String: err.Error(), Valid: true},
+					RetryAfterSeconds: int64(outboxBackoff(event.Attempts) / time.Second),
+					Dead:              dead,
+				})
+			}
+
+			// 3. Mark as published
+			if err := q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return published, nil
+}
+
+// outboxBackoff returns the delay before the next publish after `attempts` previous failures
+func outboxBackoff(attempts int32) time.Duration {
+	delay := outboxBaseBackoff
+	for i := int32(0); i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxBackoff)
+}
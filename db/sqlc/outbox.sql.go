@@ -0,0 +1,123 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_type,
+  aggregate_id,
+  payload
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, event_type, aggregate_id, payload, attempts, last_error, published_at, created_at, next_attempt_at, dead_at
+`
+
+type CreateOutboxEventParams struct {
+	EventType   string          `json:"event_type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error) {
+	row := q.db.QueryRow(ctx, createOutboxEvent, arg.EventType, arg.AggregateID, arg.Payload)
+	var i Outbox
+	err := row.Scan(
+		&i.ID,
+		&i.EventType,
+		&i.AggregateID,
+		&i.Payload,
+		&i.Attempts,
+		&i.LastError,
+		&i.PublishedAt,
+		&i.CreatedAt,
+		&i.NextAttemptAt,
+		&i.DeadAt,
+	)
+	return i, err
+}
+
+const lockPendingOutboxEvents = `-- name: LockPendingOutboxEvents :many
+SELECT id, event_type, aggregate_id, payload, attempts, last_error, published_at, created_at, next_attempt_at, dead_at FROM outbox
+WHERE published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) LockPendingOutboxEvents(ctx context.Context, limit int64) ([]Outbox, error) {
+	rows, err := q.db.Query(ctx, lockPendingOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.AggregateID,
+			&i.Payload,
+			&i.Attempts,
+			&i.LastError,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.NextAttemptAt,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventFailed = `-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = $1,
+    next_attempt_at = now() + $2::bigint * interval '1 second',
+    dead_at = CASE WHEN $3::boolean THEN now() END
+WHERE id = $4
+`
+
+type MarkOutboxEventFailedParams struct {
+	LastError         sql.NullString `json:"last_error"`
+	RetryAfterSeconds int64          `json:"retry_after_seconds"`
+	Dead              bool           `json:"dead"`
+	ID                int64          `json:"id"`
+}
+
+func (q *Queries) MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error {
+	_, err := q.db.Exec(ctx, markOutboxEventFailed,
+		arg.LastError,
+		arg.RetryAfterSeconds,
+		arg.Dead,
+		arg.ID,
+	)
+	return err
+}
+
+const markOutboxEventPublished = `-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now(), attempts = attempts + 1, last_error = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markOutboxEventPublished, id)
+	return err
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// publishAll drains the outbox and returns the published events of the given type
+func publishAll(t *testing.T, store Store, eventType string) []Outbox {
+	var events []Outbox
+	for {
+		n, err := store.PublishOutboxTx(context.Background(), 100, func(ctx context.Context, event Outbox) error {
+			if event.EventType == eventType {
+				events = append(events, event)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		if n == 0 {
+			return events
+		}
+	}
+}
+
+func TestAccountOutboxEvents(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: util.RandomCurrency(),
+		Type:     util.CheckingAccount,
+	}, nil)
+	require.NoError(t, err)
+
+	created := publishAll(t, store, EventAccountCreated)
+	require.NotEmpty(t, created)
+	last := created[len(created)-1]
+	require.Equal(t, strconv.FormatInt(account.ID, 10), last.AggregateID)
+
+	var payload Account
+	require.NoError(t, json.Unmarshal(last.Payload, &payload))
+	require.Equal(t, account.Owner, payload.Owner)
+
+	deleted, err := store.DeleteAccountTx(context.Background(), account.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, deleted.ID)
+
+	closed := publishAll(t, store, EventAccountClosed)
+	require.NotEmpty(t, closed)
+	require.Equal(t, strconv.FormatInt(account.ID, 10), closed[len(closed)-1].AggregateID)
+}
+
+func TestTransferOutboxEventRetry(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	// Publish everything that is already pending so only the new event remains
+	publishAll(t, store, "")
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	}, nil)
+	require.NoError(t, err)
+
+	// A failed publish leaves the event pending with the error recorded
+	n, err := store.PublishOutboxTx(context.Background(), 100, func(ctx context.Context, event Outbox) error {
+		return errors.New("broker unavailable")
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// It is retried once its backoff has passed, not before
+	n, err = store.PublishOutboxTx(context.Background(), 100, func(ctx context.Context, event Outbox) error {
+		return errors.New("published too early")
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	_, err = testDB.Exec(context.Background(), `UPDATE outbox SET next_attempt_at = now() WHERE published_at IS NULL`)
+	require.NoError(t, err)
+
+	// The next attempt delivers it
+	var delivered []Outbox
+	n, err = store.PublishOutboxTx(context.Background(), 100, func(ctx context.Context, event Outbox) error {
+		delivered = append(delivered, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, EventTransferCreated, delivered[0].EventType)
+	require.Equal(t, strconv.FormatInt(result.Transfer.ID, 10), delivered[0].AggregateID)
+	require.Equal(t, int32(1), delivered[0].Attempts)
+	require.Equal(t, "broker unavailable", delivered[0].LastError.String)
+}
+
+func TestOutboxDeadLetter(t *testing.T) {
+	store := NewStore(testDB)
+	publishAll(t, store, "")
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    CreateRandomUser(t).Username,
+		Currency: util.RandomCurrency(),
+		Type:     util.CheckingAccount,
+	}, nil)
+	require.NoError(t, err)
+
+	// Every attempt fails until the event is given up on
+	for i := 0; i < outboxMaxAttempts; i++ {
+		_, err = testDB.Exec(context.Background(), `UPDATE outbox SET next_attempt_at = now() WHERE published_at IS NULL`)
+		require.NoError(t, err)
+		_, err := store.PublishOutboxTx(context.Background(), 100, func(ctx context.Context, event Outbox) error {
+			return errors.New("poison")
+		})
+		require.NoError(t, err)
+	}
+
+	// A dead event is never handed out again
+	_, err = testDB.Exec(context.Background(), `UPDATE outbox SET next_attempt_at = now() WHERE published_at IS NULL`)
+	require.NoError(t, err)
+	n, err := store.PublishOutboxTx(context.Background(), 100, func(ctx context.Context, event Outbox) error {
+		require.NotEqual(t, strconv.FormatInt(account.ID, 10), event.AggregateID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	require.Equal(t, outboxBaseBackoff, outboxBackoff(0))
+	require.Equal(t, 4*outboxBaseBackoff, outboxBackoff(2))
+	require.Equal(t, outboxMaxBackoff, outboxBackoff(40))
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// MaxPasswordResetAttempts is how many times sending a reset email is tried before
+// the request is left for the user to make again.
+const MaxPasswordResetAttempts = 5
+
+// ErrInvalidResetToken is returned by ResetPasswordTx for unknown, used or expired tokens.
+var ErrInvalidResetToken = errors.New("password reset token is invalid or has expired")
+
+// ResetPasswordTxParams contains the input parameters for a password reset transaction.
+type ResetPasswordTxParams struct {
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+// SentPasswordReset describes the token that was emailed for a reset request.
+type SentPasswordReset struct {
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SendPasswordResetsTx locks up to limit unsent reset requests and hands each to send,
+// which emails a fresh token and returns its hash. Failures are recorded on the row and
+// retried on a later run; rows locked by another worker are skipped.
+func (store *SQLStore) SendPasswordResetsTx(ctx context.Context, limit int64, send func(context.Context, PasswordResetToken) (SentPasswordReset, error)) (int, error) {
+	sent := 0
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Claim unsent requests for this transaction
+		resets, err := q.LockUnsentPasswordResets(ctx, LockUnsentPasswordResetsParams{
+			MaxAttempts: MaxPasswordResetAttempts,
+			Limit:       limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, reset := range resets {
+			// 2. Send; on failure record the error and move on to the next one
+			result, err := send(ctx, reset)
+			if err != nil {
+				if err := q.MarkPasswordResetFailed(ctx, MarkPasswordResetFailedParams{
+					ID:        reset.ID,
+					LastError: sql.NullString{String: err.Error(), Valid: true},
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// 3. Store the hash of the token that was sent
+			if err := q.MarkPasswordResetSent(ctx, MarkPasswordResetSentParams{
+				ID:        reset.ID,
+				TokenHash: sql.NullString{String: result.TokenHash, Valid: true},
+				ExpiresAt: sql.NullTime{Time: result.ExpiresAt, Valid: true},
+			}); err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return sent, nil
+}
+
+// ResetPasswordTx consumes a reset token and sets the owner's new password, bumping
+// password_changed_at. Every outstanding reset token of the user is invalidated, so
+// each token works at most once even when two confirmations race.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams, audit UserAudit) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Lock the token row so concurrent confirmations serialize
+		resetToken, err := q.GetPasswordResetTokenForUpdate(ctx, sql.NullString{String: arg.TokenHash, Valid: true})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+
+		// 2. Reject used or expired tokens (expires_at is stored in UTC)
+		if resetToken.UsedAt.Valid || !resetToken.ExpiresAt.Valid || !time.Now().UTC().Before(resetToken.ExpiresAt.Time) {
+			return ErrInvalidResetToken
+		}
+
+		// 3. Update the password
+		user, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:          resetToken.Username,
+			HashedPassword:    arg.HashedPassword,
+			PasswordChangedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		// 4. Burn this and any other pending token of the user
+		return q.InvalidatePasswordResetTokens(ctx, resetToken.Username)
+	}, audited(audit, &user))
+
+	return user, err
+}
@@ -0,0 +1,168 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createPasswordResetRequest = `-- name: CreatePasswordResetRequest :execrows
+INSERT INTO password_reset_tokens (
+  username,
+  email
+)
+SELECT users.username, users.email FROM users
+WHERE users.email = $1
+`
+
+func (q *Queries) CreatePasswordResetRequest(ctx context.Context, email string) (int64, error) {
+	result, err := q.db.Exec(ctx, createPasswordResetRequest, email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getPasswordResetToken = `-- name: GetPasswordResetToken :one
+SELECT id, username, token_hash, expires_at, used_at, created_at, email, attempts, last_error, sent_at FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetPasswordResetToken(ctx context.Context, tokenHash sql.NullString) (PasswordResetToken, error) {
+	row := q.db.QueryRow(ctx, getPasswordResetToken, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+		&i.Email,
+		&i.Attempts,
+		&i.LastError,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const getPasswordResetTokenForUpdate = `-- name: GetPasswordResetTokenForUpdate :one
+SELECT id, username, token_hash, expires_at, used_at, created_at, email, attempts, last_error, sent_at FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash sql.NullString) (PasswordResetToken, error) {
+	row := q.db.QueryRow(ctx, getPasswordResetTokenForUpdate, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+		&i.Email,
+		&i.Attempts,
+		&i.LastError,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const invalidatePasswordResetTokens = `-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL
+`
+
+func (q *Queries) InvalidatePasswordResetTokens(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, invalidatePasswordResetTokens, username)
+	return err
+}
+
+const lockUnsentPasswordResets = `-- name: LockUnsentPasswordResets :many
+SELECT id, username, token_hash, expires_at, used_at, created_at, email, attempts, last_error, sent_at FROM password_reset_tokens
+WHERE sent_at IS NULL AND attempts < $1
+ORDER BY id
+LIMIT $2
+FOR UPDATE SKIP LOCKED
+`
+
+type LockUnsentPasswordResetsParams struct {
+	MaxAttempts int32 `json:"max_attempts"`
+	Limit       int64 `json:"limit"`
+}
+
+func (q *Queries) LockUnsentPasswordResets(ctx context.Context, arg LockUnsentPasswordResetsParams) ([]PasswordResetToken, error) {
+	rows, err := q.db.Query(ctx, lockUnsentPasswordResets, arg.MaxAttempts, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PasswordResetToken{}
+	for rows.Next() {
+		var i PasswordResetToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.TokenHash,
+			&i.ExpiresAt,
+			&i.UsedAt,
+			&i.CreatedAt,
+			&i.Email,
+			&i.Attempts,
+			&i.LastError,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPasswordResetFailed = `-- name: MarkPasswordResetFailed :exec
+UPDATE password_reset_tokens
+SET attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1
+`
+
+type MarkPasswordResetFailedParams struct {
+	ID        int64          `json:"id"`
+	LastError sql.NullString `json:"last_error"`
+}
+
+func (q *Queries) MarkPasswordResetFailed(ctx context.Context, arg MarkPasswordResetFailedParams) error {
+	_, err := q.db.Exec(ctx, markPasswordResetFailed, arg.ID, arg.LastError)
+	return err
+}
+
+const markPasswordResetSent = `-- name: MarkPasswordResetSent :exec
+UPDATE password_reset_tokens
+SET token_hash = $2,
+    expires_at = $3,
+    sent_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1
+`
+
+type MarkPasswordResetSentParams struct {
+	ID        int64          `json:"id"`
+	TokenHash sql.NullString `json:"token_hash"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+}
+
+func (q *Queries) MarkPasswordResetSent(ctx context.Context, arg MarkPasswordResetSentParams) error {
+	_, err := q.db.Exec(ctx, markPasswordResetSent, arg.ID, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
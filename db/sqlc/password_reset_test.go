@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomResetToken queues a reset for the user and sends it through SendPasswordResetsTx
+func createRandomResetToken(t *testing.T, username string, expiresAt time.Time) string {
+	token, err := util.GenerateSecureToken()
+	require.NoError(t, err)
+
+	user, err := testQueries.GetUser(context.Background(), username)
+	require.NoError(t, err)
+
+	n, err := testQueries.CreatePasswordResetRequest(context.Background(), user.Email)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	found := false
+	_, err = NewStore(testDB).SendPasswordResetsTx(context.Background(), 1000, func(ctx context.Context, reset PasswordResetToken) (SentPasswordReset, error) {
+		if reset.Username != username {
+			return SentPasswordReset{}, errors.New("not this test's user")
+		}
+		found = true
+		require.Equal(t, user.Email, reset.Email)
+		return SentPasswordReset{TokenHash: util.HashSecureToken(token), ExpiresAt: expiresAt}, nil
+	})
+	require.NoError(t, err)
+	require.True(t, found)
+	return token
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	token := createRandomResetToken(t, user.Username, time.Now().UTC().Add(time.Hour))
+	otherToken := createRandomResetToken(t, user.Username, time.Now().UTC().Add(time.Hour))
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	updated, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: hashedPassword,
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updated.Username)
+	require.Equal(t, hashedPassword, updated.HashedPassword)
+	require.True(t, updated.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	// The token is single-use, and every other pending token was burned with it
+	for _, used := range []string{token, otherToken} {
+		_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+			TokenHash:      util.HashSecureToken(used),
+			HashedPassword: hashedPassword,
+		}, nil)
+		require.ErrorIs(t, err, ErrInvalidResetToken)
+	}
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+
+	token := createRandomResetToken(t, user.Username, time.Now().UTC().Add(-time.Minute))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: "irrelevant",
+	}, nil)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+
+	// Unknown tokens are rejected the same way
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken("unknown"),
+		HashedPassword: "irrelevant",
+	}, nil)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestCreatePasswordResetRequestUnknownEmail(t *testing.T) {
+	n, err := testQueries.CreatePasswordResetRequest(context.Background(), util.RandomEmail())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// NewPool creates a connection pool for the database at source (config.DBSource, or
+// config.DBReplicaSource for the replica), sized by config. Pool settings left at zero
+// keep pgx's defaults. Connections are opened lazily; Ping the store to check the
+// database can be reached.
+func NewPool(ctx context.Context, source string, config util.Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database source: %w", err)
+	}
+
+	if config.DBMaxConns > 0 {
+		poolConfig.MaxConns = int32(config.DBMaxConns)
+	}
+	if config.DBMinConns > 0 {
+		poolConfig.MinConns = int32(config.DBMinConns)
+	}
+	if config.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.DBMaxConnLifetime
+	}
+	if config.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.DBMaxConnIdleTime
+	}
+
+	// Each connection prepares a statement the first time it runs a query and reuses it
+	// afterwards. Without a cache every query is sent unprepared, which poolers like
+	// PgBouncer in transaction mode need.
+	if config.DBStatementCache > 0 {
+		poolConfig.ConnConfig.StatementCacheCapacity = config.DBStatementCache
+	} else {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
@@ -6,24 +6,112 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ConsumeOIDCLoginRequest(ctx context.Context, state string) (OidcLoginRequest, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateImpersonation(ctx context.Context, arg CreateImpersonationParams) (Impersonation, error)
+	CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error)
+	CreateInterestAccrualDay(ctx context.Context, accrualDate time.Time) error
+	CreateMFARecoveryCode(ctx context.Context, arg CreateMFARecoveryCodeParams) error
+	CreateOIDCLoginRequest(ctx context.Context, arg CreateOIDCLoginRequestParams) (OidcLoginRequest, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreatePasswordResetRequest(ctx context.Context, email string) (int64, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (int64, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeactivateWebhookSubscriptions(ctx context.Context, owner string) error
+	DeleteAPIKey(ctx context.Context, id int64) error
+	DeleteAPIKeys(ctx context.Context, owner string) error
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredOIDCLoginRequests(ctx context.Context, expiresAt time.Time) error
+	DeleteExpiredRateLimits(ctx context.Context, before time.Time) error
+	DeleteMFARecoveryCodes(ctx context.Context, username string) error
+	DeleteUser(ctx context.Context, username string) (User, error)
+	DeleteUserIdentities(ctx context.Context, username string) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	EnableUserMFA(ctx context.Context, arg EnableUserMFAParams) (UserMfa, error)
+	GetAPIKey(ctx context.Context, id int64) (ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByOwner(ctx context.Context, arg GetAccountByOwnerParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetEmailVerificationForUpdate(ctx context.Context, tokenHash sql.NullString) (EmailVerification, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetLastAuditEvent(ctx context.Context) (AuditEvent, error)
+	GetLoginIPFailure(ctx context.Context, ip string) (LoginIpFailure, error)
+	GetNextAccrualDate(ctx context.Context, arg GetNextAccrualDateParams) (time.Time, error)
+	GetPasswordResetToken(ctx context.Context, tokenHash sql.NullString) (PasswordResetToken, error)
+	GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash sql.NullString) (PasswordResetToken, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
+	GetUserMFA(ctx context.Context, username string) (UserMfa, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	HitRateLimit(ctx context.Context, arg HitRateLimitParams) (int64, error)
+	InvalidatePasswordResetTokens(ctx context.Context, username string) error
+	ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error)
+	ListAccountStats(ctx context.Context) ([]ListAccountStatsRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsForUpdate(ctx context.Context, owner string) ([]Account, error)
+	ListAccountsWithUnpostedInterest(ctx context.Context, before time.Time) ([]ListAccountsWithUnpostedInterestRow, error)
+	ListActiveWebhookSubscriptionsForEvent(ctx context.Context, arg ListActiveWebhookSubscriptionsForEventParams) ([]WebhookSubscription, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	ListAuditEventsAfter(ctx context.Context, arg ListAuditEventsAfterParams) ([]AuditEvent, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListInterestAccruals(ctx context.Context, arg ListInterestAccrualsParams) ([]InterestAccrual, error)
+	ListSavingsBalancesAt(ctx context.Context, asOf time.Time) ([]ListSavingsBalancesAtRow, error)
+	ListTransferStats(ctx context.Context) ([]ListTransferStatsRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, arg ListWebhookSubscriptionsParams) ([]WebhookSubscription, error)
+	LockAuditChain(ctx context.Context) error
+	LockLoginIP(ctx context.Context, arg LockLoginIPParams) error
+	LockPendingOutboxEvents(ctx context.Context, limit int64) ([]Outbox, error)
+	LockUnsentEmailVerifications(ctx context.Context, arg LockUnsentEmailVerificationsParams) ([]EmailVerification, error)
+	LockUnsentPasswordResets(ctx context.Context, arg LockUnsentPasswordResetsParams) ([]PasswordResetToken, error)
+	LockUser(ctx context.Context, arg LockUserParams) error
+	MarkEmailVerificationFailed(ctx context.Context, arg MarkEmailVerificationFailedParams) error
+	MarkEmailVerificationSent(ctx context.Context, arg MarkEmailVerificationSentParams) error
+	MarkEmailVerificationUsed(ctx context.Context, id int64) error
+	MarkInterestPosted(ctx context.Context, arg MarkInterestPostedParams) error
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkPasswordResetFailed(ctx context.Context, arg MarkPasswordResetFailedParams) error
+	MarkPasswordResetSent(ctx context.Context, arg MarkPasswordResetSentParams) error
+	MarkUserEmailVerified(ctx context.Context, username string) (User, error)
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) error
+	NotifyLedger(ctx context.Context, arg NotifyLedgerParams) error
+	RecordLoginIPFailure(ctx context.Context, arg RecordLoginIPFailureParams) (LoginIpFailure, error)
+	RecordUserLoginFailure(ctx context.Context, username string) (int32, error)
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error
+	ResetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	SumUnpostedInterest(ctx context.Context, arg SumUnpostedInterestParams) (int64, error)
+	TouchAPIKey(ctx context.Context, id int64) error
+	UnlockUser(ctx context.Context, username string) (User, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	// password_changed_at comes from the app clock, which also stamps the tokens it is
+	// compared with, so clock skew or the DB time zone can't revoke fresh tokens
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpsertPendingUserMFA(ctx context.Context, arg UpsertPendingUserMFAParams) (UserMfa, error)
+	UseMFARecoveryCode(ctx context.Context, arg UseMFARecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
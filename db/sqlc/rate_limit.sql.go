@@ -0,0 +1,50 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rate_limit.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteExpiredRateLimits = `-- name: DeleteExpiredRateLimits :exec
+DELETE FROM rate_limits
+WHERE window_start < $1
+`
+
+func (q *Queries) DeleteExpiredRateLimits(ctx context.Context, before time.Time) error {
+	_, err := q.db.Exec(ctx, deleteExpiredRateLimits, before)
+	return err
+}
+
+const hitRateLimit = `-- name: HitRateLimit :one
+INSERT INTO rate_limits (
+  key,
+  window_start,
+  hits
+) VALUES (
+  $1, $2, 1
+)
+ON CONFLICT (key) DO UPDATE
+SET hits = CASE
+      WHEN rate_limits.window_start = EXCLUDED.window_start THEN rate_limits.hits + 1
+      ELSE 1
+    END,
+    window_start = EXCLUDED.window_start
+RETURNING hits
+`
+
+type HitRateLimitParams struct {
+	Key         string    `json:"key"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+func (q *Queries) HitRateLimit(ctx context.Context, arg HitRateLimitParams) (int64, error) {
+	row := q.db.QueryRow(ctx, hitRateLimit, arg.Key, arg.WindowStart)
+	var hits int64
+	err := row.Scan(&hits)
+	return hits, err
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHitRateLimit(t *testing.T) {
+	key := "test:user:" + util.RandomOwner()
+	window := time.Now().UTC().Truncate(time.Minute)
+
+	// 1. Hits within a window are counted
+	for i := int64(1); i <= 3; i++ {
+		hits, err := testQueries.HitRateLimit(context.Background(), HitRateLimitParams{Key: key, WindowStart: window})
+		require.NoError(t, err)
+		require.Equal(t, i, hits)
+	}
+
+	// 2. A new window starts over
+	hits, err := testQueries.HitRateLimit(context.Background(), HitRateLimitParams{Key: key, WindowStart: window.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), hits)
+
+	// 3. Expired counters are swept, so hits in the same window count from scratch
+	err = testQueries.DeleteExpiredRateLimits(context.Background(), window.Add(2*time.Minute))
+	require.NoError(t, err)
+	hits, err = testQueries.HitRateLimit(context.Background(), HitRateLimitParams{Key: key, WindowStart: window.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), hits)
+}
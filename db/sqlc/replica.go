@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// replicaQueries are the read-only queries a read replica may serve. They list and
+// show ledger data, where a moment of replication lag is harmless. Everything else,
+// including reads that authenticate users, lock rows or check what was just written,
+// stays on the primary, as do all queries run inside a transaction. GetAccount is
+// left out: transfers check their accounts' owner and currency with it, and the audit
+// trail records an account's state before a change from it, so it must not be stale.
+var replicaQueries = map[string]bool{
+	"ListAccounts":         true,
+	"GetEntry":             true,
+	"ListEntries":          true,
+	"GetTransfer":          true,
+	"ListTransfers":        true,
+	"ListTransferStats":    true,
+	"ListAccountStats":     true,
+	"ListAuditEvents":      true,
+	"ListAuditEventsAfter": true,
+	"ListInterestAccruals": true,
+}
+
+// replicaRetryInterval is how long reads stay on the primary after the replica could
+// not be reached, before it is tried again
+const replicaRetryInterval = 10 * time.Second
+
+// primaryKey marks contexts whose reads must all go to the primary
+type primaryKey struct{}
+
+// WithPrimary returns a copy of ctx whose reads all go to the primary, so they see
+// writes that may not have reached the replica yet
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsFromPrimary reports whether ctx was made by WithPrimary
+func ReadsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// routedDBTX sends the queries in replicaQueries to the replica and every other
+// statement to the primary. While the replica can't be reached its reads fall back to
+// the primary.
+type routedDBTX struct {
+	primary   DBTX
+	replica   DBTX
+	downUntil atomic.Int64 // Unix nanoseconds until which the replica is skipped
+}
+
+func (r *routedDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, query, args...)
+}
+
+func (r *routedDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	if !r.useReplica(ctx, query) {
+		return r.primary.Query(ctx, query, args...)
+	}
+
+	rows, err := r.replica.Query(ctx, query, args...)
+	if replicaUnavailable(ctx, err) {
+		r.markDown(ctx, err)
+		return r.primary.Query(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (r *routedDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	if !r.useReplica(ctx, query) {
+		return r.primary.QueryRow(ctx, query, args...)
+	}
+	return replicaRow{
+		row:    r.replica.QueryRow(ctx, query, args...),
+		routed: r,
+		ctx:    ctx,
+		query:  query,
+		args:   args,
+	}
+}
+
+// replicaRow reruns its query on the primary when the replica turns out to be
+// unreachable, which pgx only reports once the row is scanned
+type replicaRow struct {
+	row    pgx.Row
+	routed *routedDBTX
+	ctx    context.Context
+	query  string
+	args   []interface{}
+}
+
+func (row replicaRow) Scan(dest ...any) error {
+	err := row.row.Scan(dest...)
+	if replicaUnavailable(row.ctx, err) {
+		row.routed.markDown(row.ctx, err)
+		return row.routed.primary.QueryRow(row.ctx, row.query, row.args...).Scan(dest...)
+	}
+	return err
+}
+
+// useReplica reports whether query should be sent to the replica
+func (r *routedDBTX) useReplica(ctx context.Context, query string) bool {
+	return replicaQueries[queryName(query)] &&
+		!ReadsFromPrimary(ctx) &&
+		time.Now().UnixNano() >= r.downUntil.Load()
+}
+
+// markDown sends reads to the primary for replicaRetryInterval
+func (r *routedDBTX) markDown(ctx context.Context, err error) {
+	now := time.Now()
+	if r.downUntil.Swap(now.Add(replicaRetryInterval).UnixNano()) < now.UnixNano() {
+		slog.WarnContext(ctx, "read replica unavailable, reading from the primary", "error", err, "retry_in", replicaRetryInterval.String())
+	}
+}
+
+// replicaUnavailable reports whether err means the replica could not be reached, as
+// opposed to the query failing there. Nothing reached the server in that case, so the
+// query can safely be run again on the primary.
+func replicaUnavailable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,90 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: stats.sql
+
+package db
+
+import (
+	"context"
+)
+
+const listAccountStats = `-- name: ListAccountStats :many
+SELECT
+  currency,
+  type,
+  COUNT(*)::bigint AS accounts,
+  COALESCE(SUM(balance), 0)::bigint AS balance
+FROM accounts
+GROUP BY currency, type
+ORDER BY currency, type
+`
+
+type ListAccountStatsRow struct {
+	Currency string `json:"currency"`
+	Type     string `json:"type"`
+	Accounts int64  `json:"accounts"`
+	Balance  int64  `json:"balance"`
+}
+
+func (q *Queries) ListAccountStats(ctx context.Context) ([]ListAccountStatsRow, error) {
+	rows, err := q.db.Query(ctx, listAccountStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatsRow{}
+	for rows.Next() {
+		var i ListAccountStatsRow
+		if err := rows.Scan(
+			&i.Currency,
+			&i.Type,
+			&i.Accounts,
+			&i.Balance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferStats = `-- name: ListTransferStats :many
+SELECT
+  a.currency,
+  COUNT(*)::bigint AS transfers,
+  COALESCE(SUM(t.amount), 0)::bigint AS amount
+FROM transfers t
+JOIN accounts a ON a.id = t.from_account_id
+GROUP BY a.currency
+ORDER BY a.currency
+`
+
+type ListTransferStatsRow struct {
+	Currency  string `json:"currency"`
+	Transfers int64  `json:"transfers"`
+	Amount    int64  `json:"amount"`
+}
+
+func (q *Queries) ListTransferStats(ctx context.Context) ([]ListTransferStatsRow, error) {
+	rows, err := q.db.Query(ctx, listTransferStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferStatsRow{}
+	for rows.Next() {
+		var i ListTransferStatsRow
+		if err := rows.Scan(&i.Currency, &i.Transfers, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -3,44 +3,98 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
 )
 
 // Store interface defines all methods for database operations, including queries and transfer transactions.
 type Store interface {
 	Querier
-	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	TransferTx(ctx context.Context, arg TransferTxParams, audit TransferAudit) (TransferTxResult, error)
+	PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams, audit AccountAudit) (Account, error)
+	DeleteAccountTx(ctx context.Context, id int64, audit AccountAudit) (Account, error)
+	PublishOutboxTx(ctx context.Context, limit int64, publish func(context.Context, Outbox) error) (int, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams, audit UserAudit) (User, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	SendEmailVerificationsTx(ctx context.Context, limit int64, send func(context.Context, EmailVerification) (SentEmailVerification, error)) (int, error)
+	SendPasswordResetsTx(ctx context.Context, limit int64, send func(context.Context, PasswordResetToken) (SentPasswordReset, error)) (int, error)
+	VerifyEmailTx(ctx context.Context, tokenHash string) (User, error)
+	EnableMFATx(ctx context.Context, arg EnableMFATxParams, audit MFAAudit) (UserMfa, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams, audit UserAudit) (User, error)
+	DeleteUserTx(ctx context.Context, username string, audit UserAudit) (User, error)
+	ChangePasswordTx(ctx context.Context, arg UpdateUserPasswordParams, audit UserAudit) (User, error)
+	UnlockUserTx(ctx context.Context, username string, audit UserAudit) (User, error)
+	CreateAPIKeyTx(ctx context.Context, arg CreateAPIKeyParams, audit APIKeyAudit) (ApiKey, error)
+	DeleteAPIKeyTx(ctx context.Context, id int64, audit APIKeyAudit) (ApiKey, error)
+	OIDCLoginTx(ctx context.Context, arg OIDCLoginTxParams) (User, error)
+	AppendAuditEventTx(ctx context.Context, arg AppendAuditEventTxParams) (AuditEvent, error)
+	VerifyAuditChain(ctx context.Context) (AuditChainStatus, error)
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version int64, dirty bool, err error)
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions.
-// It embeds Queries (for generated query methods) and holds a reference to the database connection.
+// It embeds Queries (for generated query methods) and holds a reference to the connection pool.
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db *pgxpool.Pool
 }
 
-// NewStore creates a new SQLStore instance with the given database connection.
-func NewStore(db *sql.DB) Store {
+// NewStore creates a new SQLStore instance running on the given connection pool.
+func NewStore(db *pgxpool.Pool) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db), // Initialize Queries with the db connection
+		Queries: New(tracedDBTX{db: db}), // Initialize Queries with the db connection, tracing every query
+	}
+}
+
+// NewStoreWithReplica creates a SQLStore whose lag-tolerant reads (accounts, entries,
+// transfers, stats) go to the replica pool, falling back to the primary while the
+// replica can't be reached. Transactions and all other queries run on the primary, as
+// do reads made with a WithPrimary context.
+func NewStoreWithReplica(primary *pgxpool.Pool, replica *pgxpool.Pool) Store {
+	return &SQLStore{
+		db:      primary,
+		Queries: New(tracedDBTX{db: &routedDBTX{primary: primary, replica: replica}}),
 	}
 }
 
 // execTX executes a function within a database transaction.
 // It begins a transaction, executes the provided function, and commits or rolls back as needed.
-func (store *SQLStore) execTX(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil) // Start a new transaction
+// When audit is set, the event it returns is appended to the audit log after fn succeeds,
+// before the commit. The transaction gets a span, with a child span for every query and
+// for the commit or rollback.
+func (store *SQLStore) execTX(ctx context.Context, fn func(*Queries) error, audit auditFunc) (err error) {
+	ctx, span := tracer.Start(ctx, "transaction")
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	tx, err := store.db.Begin(ctx) // Start a new transaction
 	if err != nil {
 		return err
 	}
 
-	q := New(tx) // Create a new Queries instance using the transaction
-	err = fn(q)  // Execute the function with the transactional Queries
+	q := New(tracedDBTX{db: tx, parent: span}) // Create a new Queries instance using the transaction
+	err = fn(q)                                // Execute the function with the transactional Queries
+	if err == nil && audit != nil {
+		_, err = appendAuditEvent(ctx, q, audit())
+	}
 
 	if err != nil {
 		// If the function returns an error, roll back the transaction
-		if rbErr := tx.Rollback(); rbErr != nil {
+		_, rbSpan := tracer.Start(ctx, "ROLLBACK")
+		rbErr := tx.Rollback(ctx)
+		recordSpanError(rbSpan, rbErr)
+		rbSpan.End()
+		if rbErr != nil {
 			// If rollback also fails, return both errors
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 		}
@@ -48,9 +102,34 @@ func (store *SQLStore) execTX(ctx context.Context, fn func(*Queries) error) erro
 	}
 
 	// Commit the transaction if no error occurred
-	return tx.Commit()
+	_, commitSpan := tracer.Start(ctx, "COMMIT")
+	defer commitSpan.End()
+	err = tx.Commit(ctx)
+	recordSpanError(commitSpan, err)
+	return err
+}
+
+// maxTransferAttempts is how many times TransferTx runs a transfer that keeps
+// conflicting with concurrent transactions before giving up
+const maxTransferAttempts = 3
+
+// retryConflicts calls run until it succeeds, fails with an error other than a
+// deadlock or serialization failure, or has been called attempts times. A transaction
+// that lost such a conflict was rolled back entirely, so it is safe to run again.
+func retryConflicts(ctx context.Context, attempts int, run func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || !isRetryable(err) || attempt == attempts || ctx.Err() != nil {
+			return err
+		}
+		transferTxRetries.Inc()
+		slog.DebugContext(ctx, "transaction conflict: retrying", "attempt", attempt, "error", err)
+	}
 }
 
+// ErrAccountClosed is returned by TransferTx when the receiving account's owner was deleted.
+var ErrAccountClosed = errors.New("account is closed")
+
 // TransferTxParams contains the input parameters for a transfer transaction.
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
@@ -67,60 +146,211 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 }
 
-// txKey is used as a context key for transaction naming/logging (optional, for debugging).
-var txKey = struct{}{}
-
 // TransferTx performs a money transfer from one account to another within a single database transaction.
 // It creates a transfer record, adds account entries, and updates account balances atomically.
-func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+// Transfers to an account whose owner was deleted fail with ErrAccountClosed.
+func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams, audit TransferAudit) (TransferTxResult, error) {
 	var result TransferTxResult
+	var err error
 
-	// Execute all operations within a transaction using execTX
-	err := store.execTX(ctx, func(q *Queries) error {
-		var err error
+	start := time.Now()
+	err = retryConflicts(ctx, maxTransferAttempts, func() error {
+		// Execute all operations within a transaction using execTX
+		return store.execTX(ctx, func(q *Queries) error {
+			var err error
+			result, err = transfer(ctx, q, arg)
+			if err != nil {
+				return err
+			}
 
-		// Optional: Retrieve transaction name from context for logging/debugging
-		txName := ctx.Value(txKey)
+			// The balance update holds the receiving account's row lock, which DeleteUserTx
+			// takes before deleting its owner, so the owner read here can't change until commit
+			owner, err := q.GetUser(ctx, result.ToAccount.Owner)
+			if err != nil {
+				return err
+			}
+			if owner.DeletedAt.Valid {
+				return ErrAccountClosed
+			}
+			return nil
+		}, audited(audit, &result))
+	})
 
-		// 1. Create a transfer record
-		fmt.Println(txName, "create transfer")
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
-		if err != nil {
+	outcome := transferCommitted
+	if err != nil {
+		outcome = transferFailed
+	}
+	transferTxTotal.WithLabelValues(outcome).Inc()
+	transferTxDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		slog.WarnContext(ctx, "transfer failed", "from_account_id", arg.FromAccountID, "to_account_id", arg.ToAccountID, "amount", arg.Amount, "error", err)
+		return result, err
+	}
+
+	slog.InfoContext(ctx, "transfer committed", "transfer_id", result.Transfer.ID, "from_account_id", arg.FromAccountID, "to_account_id", arg.ToAccountID, "amount", arg.Amount)
+	return result, nil
+}
+
+// transfer runs the ledger steps of a transfer using the given transactional Queries.
+// It is shared by every transaction that moves money so they all write the same records.
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
+
+	// 1. Create a transfer record
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
+	if err != nil {
+		return result, err
+	}
+
+	// 2. Create an entry for the sender (negative amount)
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// 3. Create an entry for the receiver (positive amount)
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// 4. Update account balances in a consistent order to avoid deadlocks
+	slog.DebugContext(ctx, "transfer: updating balances", "transfer_id", result.Transfer.ID, "lower_account_first", arg.FromAccountID < arg.ToAccountID)
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// 5. Record the domain event in the same transaction
+	err = recordEvent(ctx, q, EventTransferCreated, result.Transfer.ID, result.Transfer)
+	if err != nil {
+		return result, err
+	}
+
+	// 6. Announce the new balances to real-time listeners once the transaction commits
+	err = notifyTransfer(ctx, q, result)
+	return result, err
+}
+
+// PostInterestTxParams contains the input parameters for posting accrued interest.
+type PostInterestTxParams struct {
+	AccountID      int64     `json:"account_id"`       // Savings account receiving the interest
+	HouseAccountID int64     `json:"house_account_id"` // House account the interest is paid from
+	Before         time.Time `json:"before"`           // Only accruals dated strictly before this day are posted
+}
+
+// PostInterestTxResult is the result of posting interest. Transfer is empty when
+// the accrued amount rounded to zero minor units.
+type PostInterestTxResult struct {
+	Amount   int64            `json:"amount"`
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// PostInterestTx pays all unposted interest accrued on an account before arg.Before
+// as a transfer from the house account, and marks those accruals as posted, in one transaction.
+// The accrued micro-units are rounded half-to-even to minor units; the remainder of at
+// most half a unit is dropped with the posted accruals rather than carried forward.
+func (store *SQLStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	var result PostInterestTxResult
+
+	err := retryConflicts(ctx, maxTransferAttempts, func() error {
+		return store.execTX(ctx, func(q *Queries) error {
+			result = PostInterestTxResult{}
+			return postInterest(ctx, q, arg, &result)
+		}, nil)
+	})
+
+	return result, err
+}
+
+// postInterest runs the steps of PostInterestTx using the given transactional Queries
+func postInterest(ctx context.Context, q *Queries, arg PostInterestTxParams, result *PostInterestTxResult) error {
+	// 1. Lock both accounts in ascending ID order, the order transfer updates them in,
+	// so posters and transfers touching the same accounts can't deadlock
+	for _, id := range []int64{min(arg.AccountID, arg.HouseAccountID), max(arg.AccountID, arg.HouseAccountID)} {
+		if _, err := q.GetAccountForUpdate(ctx, id); err != nil {
 			return err
 		}
+	}
+
+	// 2. Sum the unposted accruals and round them to minor units
+	micros, err := q.SumUnpostedInterest(ctx, SumUnpostedInterestParams{
+		AccountID: arg.AccountID,
+		Before:    arg.Before,
+	})
+	if err != nil {
+		return err
+	}
+	result.Amount = util.MicrosToUnits(micros)
 
-		// 2. Create an entry for the sender (negative amount)
-		fmt.Println(txName, "create entry1")
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+	// 3. Move the money through the same ledger path as a regular transfer
+	var transferID sql.NullInt64
+	if result.Amount > 0 {
+		result.Transfer, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: arg.HouseAccountID,
+			ToAccountID:   arg.AccountID,
+			Amount:        result.Amount,
 		})
 		if err != nil {
 			return err
 		}
+		transferID = sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true}
+	}
 
-		// 3. Create an entry for the receiver (positive amount)
-		fmt.Println(txName, "create entry2")
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+	// 4. Mark the accruals as posted
+	return q.MarkInterestPosted(ctx, MarkInterestPostedParams{
+		TransferID: transferID,
+		AccountID:  arg.AccountID,
+		Before:     arg.Before,
+	})
+}
+
+// CreateAccountTx creates an account and records an account.created event atomically.
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams, audit AccountAudit) (Account, error) {
+	var account Account
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
 		if err != nil {
 			return err
 		}
+		return recordEvent(ctx, q, EventAccountCreated, account.ID, account)
+	}, audited(audit, &account))
 
-		// 4. Update account balances in a consistent order to avoid deadlocks
-		if arg.FromAccountID < arg.ToAccountID {
-			fmt.Println(txName, "From Account 1 -> To Account 2")
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			fmt.Println(txName, "From Account 2 -> To Account 1")
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	return account, err
+}
+
+// DeleteAccountTx deletes an account and records an account.closed event atomically.
+// It returns the account as it was just before deletion.
+func (store *SQLStore) DeleteAccountTx(ctx context.Context, id int64, audit AccountAudit) (Account, error) {
+	var account Account
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return err
 		}
-		return err // Return any error from addMoney (if any)
-	})
+		if err = q.DeleteAccount(ctx, id); err != nil {
+			return err
+		}
+		return recordEvent(ctx, q, EventAccountClosed, account.ID, account)
+	}, audited(audit, &account))
 
-	return result, err
+	return account, err
 }
 
 // addMoney updates the balances of two accounts atomically within a transaction.
@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,7 +35,7 @@ func TestTransferTx(t *testing.T) {
 				FromAccountID: account1.ID,
 				ToAccountID:   account2.ID,
 				Amount:        amount,
-			})
+			}, nil)
 			errs <- err
 			results <- result
 		}(txName)
@@ -144,7 +147,7 @@ func TestTransferTxDeadLock(t *testing.T) {
 					FromAccountID: fromAccountId,
 					ToAccountID:   toAccountId,
 					Amount:        amount,
-				})
+				}, nil)
 				errs <- err
 			}(txName, fromAccountId, toAccountId)
 		}
@@ -167,3 +170,50 @@ func TestTransferTxDeadLock(t *testing.T) {
 		require.Equal(t, account2.Balance, updatedAccount2.Balance)
 	}
 }
+
+func TestRetryConflicts(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	uniqueViolation := &pgconn.PgError{Code: UniqueViolation}
+
+	testCases := []struct {
+		name      string
+		errs      []error // Returned by successive calls, nil once exhausted
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "Success", wantCalls: 1},
+		{name: "RetriedConflict", errs: []error{deadlock, deadlock}, wantCalls: 3},
+		{name: "GivesUp", errs: []error{deadlock, deadlock, deadlock, deadlock}, wantCalls: 3, wantErr: deadlock},
+		{name: "OtherError", errs: []error{uniqueViolation}, wantCalls: 1, wantErr: uniqueViolation},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			retries := testutil.ToFloat64(transferTxRetries)
+
+			calls := 0
+			err := retryConflicts(context.Background(), 3, func() error {
+				calls++
+				if calls <= len(tc.errs) {
+					return tc.errs[calls-1]
+				}
+				return nil
+			})
+			require.Equal(t, tc.wantCalls, calls)
+			require.True(t, errors.Is(err, tc.wantErr))
+			require.Equal(t, retries+float64(calls-1), testutil.ToFloat64(transferTxRetries))
+		})
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := retryConflicts(ctx, 3, func() error {
+			calls++
+			cancel()
+			return deadlock
+		})
+		require.Equal(t, 1, calls)
+		require.ErrorIs(t, err, deadlock)
+	})
+}
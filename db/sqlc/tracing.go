@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of database calls; it follows the global tracer provider
+var tracer = otel.Tracer("github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc")
+
+// sqlcNamePrefix starts every query sqlc generates, followed by the query's name
+const sqlcNamePrefix = "-- name: "
+
+// tracedDBTX runs statements on db, each in a span named after its sqlc query
+// (e.g. GetAccount). Statements run inside a transaction are children of the
+// transaction's span.
+type tracedDBTX struct {
+	db     DBTX
+	parent trace.Span // The transaction's span; nil outside transactions
+}
+
+func (t tracedDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	tag, err := t.db.Exec(ctx, query, args...)
+	recordSpanError(span, err)
+	return tag, err
+}
+
+func (t tracedDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := t.start(ctx, query)
+
+	rows, err := t.db.Query(ctx, query, args...)
+	if err != nil {
+		recordSpanError(span, err)
+		span.End()
+		return rows, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (t tracedDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	ctx, span := t.start(ctx, query)
+	return tracedRow{row: t.db.QueryRow(ctx, query, args...), span: span}
+}
+
+// tracedRows ends its query's span once the rows are closed, as pgx reports most
+// query errors while reading them
+type tracedRows struct {
+	pgx.Rows
+	span trace.Span
+}
+
+func (rows *tracedRows) Close() {
+	rows.Rows.Close()
+	if rows.span != nil {
+		recordSpanError(rows.span, rows.Rows.Err())
+		rows.span.End()
+		rows.span = nil
+	}
+}
+
+// tracedRow ends its query's span once the row is scanned, as pgx only runs the
+// query then
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (row tracedRow) Scan(dest ...any) error {
+	defer row.span.End()
+
+	err := row.row.Scan(dest...)
+	// No rows isn't a failure of the query
+	if !errors.Is(err, pgx.ErrNoRows) {
+		recordSpanError(row.span, err)
+	}
+	return err
+}
+
+// start opens the span of one statement. Query arguments are left out of it, as they
+// may hold personal data or secrets.
+func (t tracedDBTX) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	if t.parent != nil {
+		ctx = trace.ContextWithSpan(ctx, t.parent)
+	}
+
+	name := queryName(query)
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// queryName returns the sqlc name of a query, or its first keyword for hand-written SQL
+func queryName(query string) string {
+	query = strings.TrimSpace(query)
+	if rest, ok := strings.CutPrefix(query, sqlcNamePrefix); ok {
+		query = rest
+	}
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return fields[0]
+	}
+	return "query"
+}
+
+// recordSpanError marks span as failed when err is set
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "SELECT", queryName(" SELECT version, dirty FROM schema_migrations"))
+	require.Equal(t, "query", queryName(""))
+}
+
+func TestTransferTxSpans(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	defer otel.SetTracerProvider(previous)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	store := NewStore(testDB)
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	}, nil)
+	require.NoError(t, err)
+
+	// Every query of the transaction, and its commit, is a child of the transaction span
+	var tx sdktrace.ReadOnlySpan
+	children := map[string]int{}
+	for _, span := range spans.Ended() {
+		if span.Name() == "transaction" {
+			tx = span
+		}
+	}
+	require.NotNil(t, tx)
+	for _, span := range spans.Ended() {
+		if span.Parent().SpanID() == tx.SpanContext().SpanID() {
+			children[span.Name()]++
+		}
+	}
+	require.Equal(t, 1, children["CreateTransfer"])
+	require.Equal(t, 2, children["CreateEntry"])
+	require.Equal(t, 1, children["COMMIT"])
+}
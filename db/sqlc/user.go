@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrUserHasBalance is returned by DeleteUserTx while any of the user's accounts holds money.
+var ErrUserHasBalance = errors.New("user still holds a non-zero balance")
+
+// UpdateUserTxParams contains the profile fields to change; invalid fields are left as they are.
+type UpdateUserTxParams struct {
+	Username string         `json:"username"`
+	FullName sql.NullString `json:"full_name"`
+	Email    sql.NullString `json:"email"`
+}
+
+// UpdateUserTx updates a user's profile. A new email address is unverified until the
+// user follows the link in a fresh verification email, queued in the same transaction.
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams, audit UserAudit) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Find out whether the email actually changes
+		current, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		emailChanged := arg.Email.Valid && arg.Email.String != current.Email
+
+		// 2. Update the profile, dropping the verified flag with the old address
+		user, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username:        arg.Username,
+			FullName:        arg.FullName,
+			Email:           arg.Email,
+			IsEmailVerified: sql.NullBool{Bool: false, Valid: emailChanged},
+		})
+		if err != nil || !emailChanged {
+			return err
+		}
+
+		// 3. Queue the verification email for the new address
+		_, err = q.CreateEmailVerification(ctx, CreateEmailVerificationParams{
+			Username: user.Username,
+			Email:    user.Email,
+		})
+		return err
+	}, audited(audit, &user))
+
+	return user, err
+}
+
+// DeleteUserTx closes a user's profile. The row is kept, anonymized and marked deleted,
+// because the user's accounts stay in the ledger; it is refused with ErrUserHasBalance
+// while any account holds money. Webhooks are switched off, API keys revoked, single
+// sign-on identities unlinked and pending password resets voided.
+func (store *SQLStore) DeleteUserTx(ctx context.Context, username string, audit UserAudit) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		// 1. Lock the user's accounts so no transfer moves money in while we check
+		accounts, err := q.ListAccountsForUpdate(ctx, username)
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			if account.Balance != 0 {
+				return ErrUserHasBalance
+			}
+		}
+
+		// 2. Anonymize and mark the user deleted
+		user, err = q.DeleteUser(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		// 3. Stop everything still acting on the user's behalf
+		if err := q.DeactivateWebhookSubscriptions(ctx, username); err != nil {
+			return err
+		}
+		if err := q.DeleteAPIKeys(ctx, username); err != nil {
+			return err
+		}
+		if err := q.DeleteUserIdentities(ctx, username); err != nil {
+			return err
+		}
+		return q.InvalidatePasswordResetTokens(ctx, username)
+	}, audited(audit, &user))
+
+	return user, err
+}
+
+// ChangePasswordTx sets a user's new password, bumping password_changed_at, and records
+// its audit event atomically.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg UpdateUserPasswordParams, audit UserAudit) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.UpdateUserPassword(ctx, arg)
+		return err
+	}, audited(audit, &user))
+
+	return user, err
+}
+
+// UnlockUserTx clears a user's failed logins and lockout and records its audit event atomically.
+func (store *SQLStore) UnlockUserTx(ctx context.Context, username string, audit UserAudit) (User, error) {
+	var user User
+
+	err := store.execTX(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.UnlockUser(ctx, username)
+		return err
+	}, audited(audit, &user))
+
+	return user, err
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -55,3 +56,116 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 
 }
+
+func TestRehashUserPassword(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	newHash, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	// A stale old hash (the password changed meanwhile) leaves the row alone
+	err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		Username:          user.Username,
+		OldHashedPassword: "stale",
+		NewHashedPassword: newHash,
+	})
+	require.NoError(t, err)
+
+	unchanged, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, unchanged.HashedPassword)
+
+	err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+		NewHashedPassword: newHash,
+	})
+	require.NoError(t, err)
+
+	// The hash changes but, being the same password, existing tokens stay valid
+	rehashed, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, newHash, rehashed.HashedPassword)
+	require.Equal(t, user.PasswordChangedAt, rehashed.PasswordChangedAt)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	user, err := testQueries.MarkUserEmailVerified(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	// Changing only the name keeps the email verified
+	newName := util.RandomOwner()
+	updated, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		Username: user.Username,
+		FullName: sql.NullString{String: newName, Valid: true},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, newName, updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+	require.True(t, updated.IsEmailVerified)
+
+	// A new email must be verified again
+	newEmail := util.RandomEmail()
+	updated, err = store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		Username: user.Username,
+		Email:    sql.NullString{String: newEmail, Valid: true},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, newName, updated.FullName)
+	require.Equal(t, newEmail, updated.Email)
+	require.False(t, updated.IsEmailVerified)
+}
+
+func TestDeleteUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := CreateRandomAccount(t)
+	require.NotZero(t, account.Balance)
+
+	// Money left in an account blocks the deletion
+	_, err := store.DeleteUserTx(context.Background(), account.Owner, nil)
+	require.ErrorIs(t, err, ErrUserHasBalance)
+
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID})
+	require.NoError(t, err)
+	createRandomResetToken(t, account.Owner, time.Now().UTC().Add(time.Hour))
+
+	deleted, err := store.DeleteUserTx(context.Background(), account.Owner, nil)
+	require.NoError(t, err)
+	require.True(t, deleted.DeletedAt.Valid)
+	require.Empty(t, deleted.FullName)
+	require.Empty(t, deleted.HashedPassword)
+	require.Equal(t, account.Owner+"@deleted.invalid", deleted.Email)
+
+	// The account stays in the ledger
+	_, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	// Deleting twice finds no user left to delete
+	_, err = store.DeleteUserTx(context.Background(), account.Owner, nil)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTransferTxToDeletedUser(t *testing.T) {
+	store := NewStore(testDB)
+	from := CreateRandomAccount(t)
+	to := CreateRandomAccount(t)
+
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: to.ID})
+	require.NoError(t, err)
+	_, err = store.DeleteUserTx(context.Background(), to.Owner, nil)
+	require.NoError(t, err)
+
+	// The deleted owner is seen under the account's lock and nothing is written
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        10,
+	}, nil)
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	account, err := testQueries.GetAccount(context.Background(), to.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.Balance)
+}
@@ -18,7 +18,53 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/accounts": {
+        "/healthz": {
+            "get": {
+                "description": "Answers 200 while the process is able to serve requests. It checks no dependencies, so a database outage doesn't get the process restarted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.healthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Answers 200 when the database answers, its migrations applied cleanly and every background worker is running; 503 otherwise, and from the moment a shutdown begins.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.readyResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.readyResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/accounts": {
             "get": {
                 "security": [
                     {
@@ -58,44 +104,32 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/db.Account"
+                                "$ref": "#/definitions/api.accountResponse"
                             }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
@@ -132,49 +166,37 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.Account"
+                            "$ref": "#/definitions/api.accountResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "403": {
                         "description": "Forbidden",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
             }
         },
-        "/accounts/{id}": {
+        "/v1/accounts/{id}": {
             "get": {
                 "security": [
                     {
@@ -205,43 +227,31 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.Account"
+                            "$ref": "#/definitions/api.accountResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
@@ -252,7 +262,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes an account by ID. Must belong to authenticated user.",
+                "description": "Deletes an account by ID. Must belong to authenticated user. Refused with 409 once entries, transfers or interest refer to the account.",
                 "consumes": [
                     "application/json"
                 ],
@@ -283,84 +293,110 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
             }
         },
-        "/transfers": {
-            "post": {
+        "/v1/api_keys": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Transfers a specified amount between two accounts if they belong to the authenticated user and share the same currency.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns a paginated list of the authenticated user's API keys, without their secrets",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Transfers"
+                    "API Keys"
                 ],
-                "summary": "Create a new money transfer",
+                "summary": "List API keys",
                 "parameters": [
                     {
-                        "description": "Transfer details",
-                        "name": "transfer",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.transferRequest"
-                        }
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.TransferTxResult"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.apiKeyResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
-            }
-        },
-        "/users": {
+            },
             "post": {
-                "description": "Creates a new user with username, password, full name, and email",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a personal API key for machine clients, sent as \"Authorization: ApiKey \u003ckey\u003e\". It can only call routes covered by its scopes. The key is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -368,17 +404,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "API Keys"
                 ],
-                "summary": "Register a new user",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "User info",
-                        "name": "user",
+                        "description": "Key name, scopes and expiry",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.createUserRequest"
+                            "$ref": "#/definitions/api.createAPIKeyRequest"
                         }
                     }
                 ],
@@ -386,105 +422,1920 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.userResponse"
+                            "$ref": "#/definitions/api.apiKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
             }
         },
-        "/users/login": {
-            "post": {
-                "description": "Authenticates user credentials and returns a JWT access token",
-                "consumes": [
-                    "application/json"
+        "/v1/api_keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Deletes an API key; requests made with it fail from then on. Must belong to authenticated user.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "API Keys"
                 ],
-                "summary": "Log in a user",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.loginUserRequest"
-                        }
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.loginUserResponse"
+                            "$ref": "#/definitions/api.messageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.createAccountRequest": {
-            "type": "object",
-            "required": [
-                "currency"
-            ],
-            "properties": {
-                "currency": {
-                    "description": "This field is required and must be a valid currency code.",
-                    "type": "string"
-                }
-            }
-        },
-        "api.createUserRequest": {
-            "description": "Create user request payload",
-            "type": "object",
-            "required": [
-                "email",
-                "full_name",
-                "password",
-                "username"
-            ],
-            "properties": {
-                "email": {
-                    "description": "Email, required, must be valid",
-                    "type": "string"
-                },
-                "full_name": {
-                    "description": "Full name, required",
-                    "type": "string"
-                },
-                "password": {
-                    "description": "Password, min 6 chars",
-                    "type": "string",
-                    "minLength": 6
-                },
-                "username": {
-                    "description": "Alphanumeric username, required",
-                    "type": "string"
-                }
-            }
-        },
-        "api.loginUserRequest": {
-            "description": "Login request payload",
-            "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
-            "properties": {
-                "password": {
-                    "type": "string",
-                    "minLength": 6
-                },
-                "username": {
-                    "type": "string"
-                }
-            }
+        },
+        "/v1/audit_events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists audit events, newest first, optionally filtered by actor, action, target and time range. Bankers only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List audit events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username of the actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Action, e.g. transfer.created",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target, e.g. account:42",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest time (RFC 3339, inclusive)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest time (RFC 3339, exclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (min 1)",
+                        "name": "page_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (5–10)",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.auditEventResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/audit_events/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Downloads every matching audit event, oldest first, as newline-delimited JSON or CSV, hashes included. An unfiltered export can be checked offline by recomputing the hash chain. Bankers only.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "Export audit events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username of the actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Action, e.g. transfer.created",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target, e.g. account:42",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest time (RFC 3339, inclusive)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest time (RFC 3339, exclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ndjson (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/audit_events/verify": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Recomputes the audit log's hash chain and reports the first event that was altered or whose predecessor was removed. Bankers only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "Verify the audit log",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.AuditChainStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/notifications/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams balance changes and incoming transfers for the caller's accounts as Server-Sent Events. Emits ` + "`" + `balance` + "`" + ` and ` + "`" + `transfer.received` + "`" + ` events. The stream ends when the token expires or is revoked (user deleted, password changed, API key deleted).",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Notifications"
+                ],
+                "summary": "Stream account notifications",
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transfers": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Transfers a specified amount between two accounts if they belong to the authenticated user and share the same currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transfers"
+                ],
+                "summary": "Create a new money transfer",
+                "parameters": [
+                    {
+                        "description": "Transfer details",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.transferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.transferResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users": {
+            "post": {
+                "description": "Creates a new user with username, password, full name, and email. A verification link is emailed; accounts and transfers need a verified email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User info",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/login": {
+            "post": {
+                "description": "Authenticates user credentials and returns an access token. Users with two-factor authentication get an mfaChallengeResponse instead, to complete with POST /users/login/mfa. Every failure answers 401 \"invalid credentials\"; repeated failures lock the user and the client IP out with growing delays.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Log in a user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.loginUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginUserResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/login/mfa": {
+            "post": {
+                "description": "Exchanges the MFA challenge token from POST /users/login and a TOTP or recovery code for an access token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Complete a two-factor login",
+                "parameters": [
+                    {
+                        "description": "Challenge token and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.loginMFARequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/login/oidc": {
+            "get": {
+                "description": "Redirects the browser to the OpenID Connect provider's sign-in page (authorization code flow with PKCE). The provider sends the user back to GET /users/login/oidc/callback.",
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Start a single sign-on",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/login/oidc/callback": {
+            "get": {
+                "description": "Redeems the provider's authorization code and returns an access token for the linked user. Unknown identities get a new password-less user when just-in-time provisioning is on; their role follows the provider's groups when a role mapping is configured.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Complete a single sign-on",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "State from the sign-on request",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Error from the provider",
+                        "name": "error",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the authenticated user's profile",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get my profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Closes the profile: personal data is erased, logins and tokens stop working and webhooks are switched off. Accounts stay in the ledger but can't receive money. Refused with 409 while any account holds a non-zero balance.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Delete my profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.messageResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes the full name and/or email. A new email address is unverified until the link sent to it is followed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Edit my profile",
+                "parameters": [
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.updateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets a new password after checking the current one. Access tokens issued before the change stop working; a fresh one is returned. Wrong current passwords count as failed logins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Change my password",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.changePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/mfa/totp": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates a TOTP secret for the authenticated user. Two-factor authentication is only enabled once a code is confirmed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MFA"
+                ],
+                "summary": "Start TOTP enrollment",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.enrollTOTPResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Enables two-factor authentication with a code from the authenticator app and returns single-use recovery codes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MFA"
+                ],
+                "summary": "Confirm TOTP enrollment",
+                "parameters": [
+                    {
+                        "description": "Current TOTP code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.confirmTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.confirmTOTPResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/password_reset": {
+            "post": {
+                "description": "Queues a single-use password reset link to be emailed if the address belongs to a user. The response is always 202, whether or not it does.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "Account email",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.requestPasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.messageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/password_reset/confirm": {
+            "post": {
+                "description": "Sets a new password using the token from the reset email. Access tokens issued before the change stop working.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Reset a password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.confirmPasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/verify_email": {
+            "get": {
+                "description": "Confirms the user's email address with the token from the verification email",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Verify an email address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/{username}/impersonate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issues a short-lived token that acts as the user, so support staff see exactly what they see. Requests made with it are logged with the banker as the actor. Credential management is refused with it, and so are transfers and account closures unless IMPERSONATION_MONEY_MOVEMENT is set. Bankers only; other bankers can't be impersonated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Act as a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the impersonation",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.impersonateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.impersonateUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/{username}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Clears a user's failed login attempts and lockout. Bankers only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Unlock a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a paginated list of the authenticated user's webhooks",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List webhooks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.webhookResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Registers an https URL that receives signed POST callbacks for the given event types on the user's accounts. Hosts resolving to private, loopback or link-local addresses are refused, and deliveries don't follow redirects. The signing secret is only returned once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook details",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a webhook and its delivery log. Must belong to authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the delivery log of a webhook, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.webhookDeliveryResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queues a delivery (including a dead-lettered one) to be sent again as soon as possible",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Redeliver a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookDeliveryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.accountResponse": {
+            "description": "Account response payload",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "description": "Balance in the currency's smallest unit (e.g. cents)",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "Timestamp of account creation",
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "Currency code, e.g. USD",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Account ID",
+                    "type": "integer"
+                },
+                "owner": {
+                    "description": "Username of the owner",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "checking or savings",
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyResponse": {
+            "description": "Personal API key",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "The full key, only returned when it is created",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Public part of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.auditEventResponse": {
+            "description": "Audit event",
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "e.g. transfer.created",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Empty for anonymous requests such as failed logins",
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "client_ip": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "impersonator": {
+                    "description": "Banker acting as the actor, if any",
+                    "type": "string"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "target": {
+                    "description": "e.g. account:42",
+                    "type": "string"
+                }
+            }
+        },
+        "api.changePasswordRequest": {
+            "description": "Password change payload",
+            "type": "object",
+            "required": [
+                "new_password",
+                "old_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "description": "See the password policy",
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.checkResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the check failed; the cause itself is only logged",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ok or error",
+                    "type": "string"
+                }
+            }
+        },
+        "api.confirmPasswordResetRequest": {
+            "description": "Password reset confirmation payload",
+            "type": "object",
+            "required": [
+                "new_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "description": "New password, see the password policy",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Token from the reset email",
+                    "type": "string"
+                }
+            }
+        },
+        "api.confirmTOTPRequest": {
+            "description": "TOTP confirmation payload",
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "Current code from the authenticator app",
+                    "type": "string"
+                }
+            }
+        },
+        "api.confirmTOTPResponse": {
+            "description": "Enabled two-factor authentication",
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.createAPIKeyRequest": {
+            "description": "API key creation payload",
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_at": {
+                    "description": "Optional; the key never expires when omitted",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Label to recognize the key by",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "scopes": {
+                    "description": "e.g. [\"accounts:read\", \"transfers:write\"]",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.createAccountRequest": {
+            "type": "object",
+            "required": [
+                "currency"
+            ],
+            "properties": {
+                "currency": {
+                    "description": "This field is required and must be a valid currency code.",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Optional account type (\"checking\" or \"savings\"), defaults to checking.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.createUserRequest": {
+            "description": "Create user request payload",
+            "type": "object",
+            "required": [
+                "email",
+                "full_name",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "description": "Email, required, must be valid",
+                    "type": "string"
+                },
+                "full_name": {
+                    "description": "Full name, required",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Password, see the password policy",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Alphanumeric username, required",
+                    "type": "string"
+                }
+            }
+        },
+        "api.createWebhookRequest": {
+            "description": "Webhook registration payload",
+            "type": "object",
+            "required": [
+                "event_types",
+                "url"
+            ],
+            "properties": {
+                "event_types": {
+                    "description": "e.g. [\"transfer.created\"]",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "description": "https endpoint receiving POST callbacks",
+                    "type": "string"
+                }
+            }
+        },
+        "api.enrollTOTPResponse": {
+            "description": "Pending TOTP enrollment",
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "description": "otpauth:// URI, usually rendered as a QR code",
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Base32 secret, for manual entry",
+                    "type": "string"
+                }
+            }
+        },
+        "api.fieldError": {
+            "description": "Invalid request field",
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Name of the field as sent (JSON, query or path name)",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Human-readable explanation",
+                    "type": "string"
+                },
+                "rule": {
+                    "description": "Validation rule it failed, e.g. required, min, currency",
+                    "type": "string"
+                }
+            }
+        },
+        "api.healthResponse": {
+            "description": "Liveness status",
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "Always \"ok\" while the process serves requests",
+                    "type": "string"
+                }
+            }
+        },
+        "api.impersonateUserRequest": {
+            "description": "Impersonation request payload",
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "description": "Why the customer's view is needed, e.g. a ticket number",
+                    "type": "string",
+                    "maxLength": 500,
+                    "minLength": 3
+                }
+            }
+        },
+        "api.impersonateUserResponse": {
+            "description": "Impersonation token",
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "description": "Use as a Bearer token; acts as the user",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "The banker the token was issued to",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "description": "The impersonated user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.userResponse"
+                        }
+                    ]
+                }
+            }
+        },
+        "api.loginMFARequest": {
+            "description": "Second login step payload",
+            "type": "object",
+            "required": [
+                "code",
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "description": "TOTP code or a recovery code",
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.loginUserRequest": {
+            "description": "Login request payload",
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "description": "Length is only checked by the password policy, when a password is set",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Any existing name, including ones made under older rules",
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
         },
         "api.loginUserResponse": {
             "description": "Login response payload",
@@ -504,6 +2355,107 @@ const docTemplate = `{
                 }
             }
         },
+        "api.messageResponse": {
+            "description": "Message response",
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.migrationCheckResult": {
+            "type": "object",
+            "properties": {
+                "dirty": {
+                    "description": "The last migration failed halfway",
+                    "type": "boolean"
+                },
+                "error": {
+                    "description": "Why the check failed; the cause itself is only logged",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ok or error",
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.problemResponse": {
+            "description": "RFC 7807 problem details",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Stable machine-readable code, e.g. ACCOUNT_NOT_FOUND",
+                    "type": "string"
+                },
+                "detail": {
+                    "description": "What went wrong this time",
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Invalid fields, for VALIDATION_FAILED",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.fieldError"
+                    }
+                },
+                "instance": {
+                    "description": "Path of the request",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "X-Request-ID of the request, for support",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP status code",
+                    "type": "integer"
+                },
+                "title": {
+                    "description": "Summary of the problem type",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "URI reference identifying the problem type",
+                    "type": "string"
+                }
+            }
+        },
+        "api.readyResponse": {
+            "description": "Readiness status and the checks behind it",
+            "type": "object",
+            "properties": {
+                "database": {
+                    "$ref": "#/definitions/api.checkResult"
+                },
+                "migrations": {
+                    "$ref": "#/definitions/api.migrationCheckResult"
+                },
+                "status": {
+                    "description": "ready, not_ready or shutting_down",
+                    "type": "string"
+                },
+                "workers": {
+                    "$ref": "#/definitions/api.workerCheckResult"
+                }
+            }
+        },
+        "api.requestPasswordResetRequest": {
+            "description": "Password reset request payload",
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
         "api.transferRequest": {
             "description": "Transfer request payload",
             "type": "object",
@@ -515,7 +2467,7 @@ const docTemplate = `{
             ],
             "properties": {
                 "amount": {
-                    "description": "Transfer amount, must be \u003e 0",
+                    "description": "Transfer amount, validated by custom validator",
                     "type": "integer"
                 },
                 "currency": {
@@ -527,6 +2479,10 @@ const docTemplate = `{
                     "type": "integer",
                     "minimum": 1
                 },
+                "mfa_code": {
+                    "description": "TOTP or recovery code, required above the step-up amount",
+                    "type": "string"
+                },
                 "to_account_id": {
                     "description": "ID of receiver account, must be \u003e 0",
                     "type": "integer",
@@ -534,6 +2490,66 @@ const docTemplate = `{
                 }
             }
         },
+        "api.transferResponse": {
+            "description": "Transfer response payload",
+            "type": "object",
+            "properties": {
+                "from_account": {
+                    "description": "Sender account after the transfer",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.accountResponse"
+                        }
+                    ]
+                },
+                "from_entry": {
+                    "description": "Entry debiting the sender",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.Entry"
+                        }
+                    ]
+                },
+                "to_account": {
+                    "description": "Receiver account after the transfer",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.accountResponse"
+                        }
+                    ]
+                },
+                "to_entry": {
+                    "description": "Entry crediting the receiver",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.Entry"
+                        }
+                    ]
+                },
+                "transfer": {
+                    "description": "The transfer record",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.Transfer"
+                        }
+                    ]
+                }
+            }
+        },
+        "api.updateUserRequest": {
+            "description": "Profile update payload",
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "A new email must be verified again",
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string",
+                    "minLength": 1
+                }
+            }
+        },
         "api.userResponse": {
             "description": "User response payload",
             "type": "object",
@@ -550,33 +2566,125 @@ const docTemplate = `{
                     "description": "Full name of user",
                     "type": "string"
                 },
+                "is_email_verified": {
+                    "description": "Whether the email address has been confirmed",
+                    "type": "boolean"
+                },
                 "password_changed_at": {
                     "description": "Timestamp of last password change",
                     "type": "string"
                 },
+                "role": {
+                    "description": "depositor or banker",
+                    "type": "string"
+                },
                 "username": {
                     "description": "Username of user",
                     "type": "string"
                 }
             }
         },
-        "db.Account": {
+        "api.webhookDeliveryResponse": {
+            "description": "Webhook delivery attempt log entry",
             "type": "object",
             "properties": {
-                "balance": {
+                "attempts": {
                     "type": "integer"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "currency": {
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "event_type": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "owner": {
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, succeeded or dead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookResponse": {
+            "description": "Webhook subscription",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "secret": {
+                    "description": "Signing secret, only returned when the webhook is created",
                     "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.workerCheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the check failed; the cause itself is only logged",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ok or error",
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/worker.State"
+                    }
+                }
+            }
+        },
+        "db.AuditChainStatus": {
+            "type": "object",
+            "properties": {
+                "broken_at": {
+                    "description": "First event whose hash or link doesn't match",
+                    "type": "integer"
+                },
+                "checked": {
+                    "description": "Events verified before stopping",
+                    "type": "integer"
+                },
+                "last_hash": {
+                    "description": "Hash of the newest verified event, to anchor elsewhere",
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
                 }
             }
         },
@@ -619,23 +2727,24 @@ const docTemplate = `{
                 }
             }
         },
-        "db.TransferTxResult": {
+        "worker.State": {
             "type": "object",
             "properties": {
-                "from_account": {
-                    "$ref": "#/definitions/db.Account"
+                "error": {
+                    "description": "Why it stopped, if it panicked",
+                    "type": "string"
                 },
-                "from_entry": {
-                    "$ref": "#/definitions/db.Entry"
+                "name": {
+                    "type": "string"
                 },
-                "to_account": {
-                    "$ref": "#/definitions/db.Account"
+                "running": {
+                    "type": "boolean"
                 },
-                "to_entry": {
-                    "$ref": "#/definitions/db.Entry"
+                "started_at": {
+                    "type": "string"
                 },
-                "transfer": {
-                    "$ref": "#/definitions/db.Transfer"
+                "stopped_at": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// LogPublisher writes each event as one JSON line to an io.Writer (stdout or a file)
+type LogPublisher struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewLogPublisher creates a LogPublisher writing to w
+func NewLogPublisher(w io.Writer) Publisher {
+	return &LogPublisher{writer: w}
+}
+
+// NewFilePublisher creates a LogPublisher appending to the file at path
+func NewFilePublisher(path string) (Publisher, io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open event log: %w", err)
+	}
+	return &LogPublisher{writer: file}, file, nil
+}
+
+// Publish writes the event as a single JSON line
+func (publisher *LogPublisher) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	_, err = publisher.writer.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,52 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewLogPublisher(&buf)
+
+	for i := int64(1); i <= 2; i++ {
+		err := publisher.Publish(context.Background(), Event{
+			ID:          i,
+			Type:        "transfer.created",
+			AggregateID: "42",
+			Payload:     json.RawMessage(`{"amount":10}`),
+			CreatedAt:   time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var got Event
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+	require.Equal(t, int64(2), got.ID)
+	require.Equal(t, "transfer.created", got.Type)
+	require.JSONEq(t, `{"amount":10}`, string(got.Payload))
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	publisher, closer, err := NewFilePublisher(path)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), Event{ID: 1, Type: "account.created"}))
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"type":"account.created"`)
+}
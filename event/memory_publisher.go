@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps published events in memory, for tests
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+// NewMemoryPublisher creates an empty MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish stores the event, or returns the error set with FailWith
+func (publisher *MemoryPublisher) Publish(ctx context.Context, event Event) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if publisher.err != nil {
+		return publisher.err
+	}
+	publisher.events = append(publisher.events, event)
+	return nil
+}
+
+// FailWith makes subsequent Publish calls fail with err (nil restores success)
+func (publisher *MemoryPublisher) FailWith(err error) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	publisher.err = err
+}
+
+// Events returns a copy of the events published so far
+func (publisher *MemoryPublisher) Events() []Event {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	return append([]Event(nil), publisher.events...)
+}
@@ -0,0 +1,24 @@
+package event
+
+import "context"
+
+// MultiPublisher publishes every event to several publishers in turn
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher creates a publisher fanning out to publishers
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+// Publish delivers the event to each publisher, stopping at the first error.
+// Since the relay retries the whole event, earlier publishers may see it again.
+func (publisher *MultiPublisher) Publish(ctx context.Context, event Event) error {
+	for _, p := range publisher.publishers {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event is a domain event relayed from the outbox to downstream consumers
+type Event struct {
+	ID          int64           `json:"id"`           // Outbox row ID, increasing in insertion order
+	Type        string          `json:"type"`         // Event type, e.g. "transfer.created"
+	AggregateID string          `json:"aggregate_id"` // ID of the record the event is about
+	Payload     json.RawMessage `json:"payload"`      // JSON snapshot of the record
+	CreatedAt   time.Time       `json:"created_at"`   // When the event was recorded
+}
+
+// Publisher is an interface for delivering events to a message sink.
+// Delivery is at-least-once, so consumers must tolerate duplicates (deduplicate by ID).
+type Publisher interface {
+	// Publish delivers a single event, returning an error if it may not have been delivered
+	Publish(ctx context.Context, event Event) error
+}
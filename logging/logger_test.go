@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLoggerAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info")
+	require.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	logger.With("component", "test").InfoContext(ctx, "hello", "answer", 42)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "hello", record["msg"])
+	require.Equal(t, "INFO", record["level"])
+	require.Equal(t, "req-123", record["request_id"])
+	require.Equal(t, "test", record["component"])
+	require.Equal(t, float64(42), record["answer"])
+
+	// No request ID, no attribute
+	buf.Reset()
+	logger.Info("bye")
+	require.NotContains(t, buf.String(), "request_id")
+}
+
+func TestLoggerAddsTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info")
+	require.NoError(t, err)
+
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), span), "traced")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record["trace_id"])
+	require.Equal(t, "00f067aa0ba902b7", record["span_id"])
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "WARN")
+	require.NoError(t, err)
+
+	logger.Info("hidden")
+	require.Zero(t, buf.Len())
+	logger.Warn("shown")
+	require.Contains(t, buf.String(), "shown")
+}
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		level string
+		want  slog.Level
+		ok    bool
+	}{
+		{"", slog.LevelInfo, true},
+		{"debug", slog.LevelDebug, true},
+		{"Error", slog.LevelError, true},
+		{"verbose", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.level, func(t *testing.T) {
+			lvl, err := ParseLevel(tc.level)
+			if !tc.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, lvl)
+		})
+	}
+}
+
+func TestRequestIDFromLocals(t *testing.T) {
+	// Any context serving the key, like Fiber's request context does for its Locals
+	ctx := context.WithValue(context.Background(), RequestIDKey, "req-456")
+	require.Equal(t, "req-456", RequestID(ctx))
+	require.Empty(t, RequestID(context.Background()))
+}
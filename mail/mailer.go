@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer picks the Mailer described by config: SMTP when SMTP_HOST is set,
+// otherwise a file outbox when MAIL_OUTBOX_FILE is set, otherwise an in-memory outbox
+func NewMailer(config util.Config) Mailer {
+	switch {
+	case config.SMTPHost != "":
+		return NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	case config.MailOutboxFile != "":
+		return NewFileMailer(config.MailOutboxFile)
+	default:
+		return NewMemoryMailer()
+	}
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// MemoryMailer keeps sent emails in memory, for tests and local development
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer creates an empty in-memory outbox
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records msg
+func (mailer *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	mailer.messages = append(mailer.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far
+func (mailer *MemoryMailer) Messages() []Message {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	return append([]Message(nil), mailer.messages...)
+}
+
+// FileMailer appends emails as JSON lines to a file instead of sending them
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileMailer creates a Mailer writing to the outbox file at path
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+// Send appends msg to the outbox file
+func (mailer *FileMailer) Send(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	file, err := os.OpenFile(mailer.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,56 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	msg := Message{To: "alice@example.com", Subject: "Hello", Body: "Hi"}
+
+	require.NoError(t, mailer.Send(context.Background(), msg))
+	require.Equal(t, []Message{msg}, mailer.Messages())
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	mailer := NewFileMailer(path)
+
+	messages := []Message{
+		{To: "alice@example.com", Subject: "First", Body: "one\ntwo"},
+		{To: "bob@example.com", Subject: "Second", Body: "three"},
+	}
+	for _, msg := range messages {
+		require.NoError(t, mailer.Send(context.Background(), msg))
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var got []Message
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		got = append(got, msg)
+	}
+	require.Equal(t, messages, got)
+}
+
+func TestSMTPMailerFormat(t *testing.T) {
+	mailer := NewSMTPMailer("localhost", 25, "", "", "bank@example.com").(*SMTPMailer)
+
+	data := string(mailer.format(Message{To: "alice@example.com", Subject: "Reset", Body: "line1\nline2"}))
+	require.Contains(t, data, "From: bank@example.com\r\n")
+	require.Contains(t, data, "To: alice@example.com\r\n")
+	require.Contains(t, data, "Subject: Reset\r\n")
+	require.Contains(t, data, "\r\n\r\nline1\r\nline2")
+}
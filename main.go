@@ -14,16 +14,35 @@
 package main
 
 import (
-	"database/sql" // For database connectivity
-	"log"          // For logging errors
+	"context"   // For background worker lifetimes
+	"errors"    // For telling a closed metrics server from a failed one
+	"fmt"       // For migrate subcommand usage errors
+	"log/slog"  // Structured JSON logging
+	"net/http"  // For the metrics server
+	"os"        // For the default event sink and log output
+	"os/signal" // For graceful shutdown on SIGINT/SIGTERM
+	"strconv"   // For migrate subcommand arguments
+	"syscall"   // For SIGTERM
+	"time"      // For startup and shutdown timeouts
 
-	// Database driver for PostgreSQL
-	_ "github.com/lib/pq"
+	// Prometheus metrics registry
+	"github.com/prometheus/client_golang/prometheus"
+
+	// Postgres connection pools
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	// Import our own packages
-	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/api"        // API layer
-	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc" // SQLC-generated database queries
-	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"       // Utilities for config, password hashing, etc.
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/api"          // API layer
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/migration" // Embedded schema migrations
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"   // SQLC-generated database queries
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/event"        // Domain event publishers
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/logging"      // JSON logger with request IDs
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/mail"         // Outgoing email
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/notify"       // Real-time ledger notifications
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/tracing"      // OpenTelemetry tracing
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"         // Utilities for config, password hashing, etc.
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook"      // Webhook fan-out
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/worker"       // Background jobs
 )
 
 // ---------------------------
@@ -39,27 +58,234 @@ func main() {
 	config, err := util.LoadConfig(".")
 	if err != nil {
 		// If config fails to load, terminate program with error
-		log.Fatal("cannot load configuration:", err)
+		fatal("cannot load configuration", err)
+	}
+
+	// Log JSON lines to stdout; the standard log package is routed through it too
+	logger, err := logging.New(os.Stdout, config.LogLevel)
+	if err != nil {
+		fatal("cannot create logger", err)
+	}
+	slog.SetDefault(logger)
+
+	// `main migrate ...` manages the schema and exits without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(config.DBSource, os.Args[2:]); err != nil {
+			fatal("migrate failed", err)
+		}
+		return
+	}
+
+	// Export request and query spans; traceparent headers are honoured even when off
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    config.TracingExporter,
+		Endpoint:    config.TracingEndpoint,
+		SampleRatio: config.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("cannot set up tracing", err)
+	}
+
+	// Open the database connection pool
+	pool, err := db.NewPool(context.Background(), config.DBSource, config)
+	if err != nil {
+		fatal("cannot connect to db", err)
 	}
+	defer pool.Close()
 
-	// Open a connection to the database
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	// Create a store (wrapper around SQLC queries), sending lag-tolerant reads to the
+	// read replica when one is configured
+	var replica *pgxpool.Pool
+	store := db.NewStore(pool)
+	if config.DBReplicaSource != "" {
+		replica, err = db.NewPool(context.Background(), config.DBReplicaSource, config)
+		if err != nil {
+			fatal("cannot connect to db replica", err)
+		}
+		defer replica.Close()
+		store = db.NewStoreWithReplica(pool, replica)
+	}
+
+	// Refuse to start against a database that can't be reached
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), startupPingTimeout)
+	err = store.Ping(pingCtx)
+	cancelPing()
+	if err != nil {
+		fatal("cannot reach db", err)
+	}
+
+	// Bring the schema up to date before serving; replicas starting together take turns
+	if config.RunMigrations {
+		if err := runMigrate(config.DBSource, []string{"up"}); err != nil {
+			fatal("cannot migrate db", err)
+		}
+	}
+
+	// SIGINT (Ctrl+C) or SIGTERM (docker stop, Kubernetes) starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background jobs run until workerCtx is cancelled, after the HTTP server has drained
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+	workers := worker.NewGroup()
+
+	// Start the savings interest accrual/posting job in the background
+	interestWorker, err := worker.NewInterestWorker(config, store)
 	if err != nil {
-		log.Fatal("Cannot connect to db:", err)
+		fatal("cannot create interest worker", err)
 	}
+	workers.Go(workerCtx, "interest", interestWorker.Run)
+
+	// Relay outbox events (transfer.created, account.created, ...) to the configured sink
+	publisher := event.NewLogPublisher(os.Stdout)
+	if config.OutboxLogFile != "" {
+		filePublisher, closer, err := event.NewFilePublisher(config.OutboxLogFile)
+		if err != nil {
+			fatal("cannot create event publisher", err)
+		}
+		defer closer.Close()
+		publisher = filePublisher
+	}
+	// Events are also fanned out to users' webhook subscriptions
+	publisher = event.NewMultiPublisher(publisher, webhook.NewDispatcher(store))
+	workers.Go(workerCtx, "outbox_relay", worker.NewOutboxRelay(store, publisher).Run)
+
+	// Send queued webhook deliveries with retries
+	workers.Go(workerCtx, "webhook_delivery", worker.NewWebhookDeliveryWorker(store).Run)
 
-	// Create a store (wrapper around SQLC queries)
-	store := db.NewStore(conn)
+	// Email verification links to newly registered users
+	workers.Go(workerCtx, "email_verification", worker.NewEmailVerificationWorker(config, store, mail.NewMailer(config)).Run)
+
+	// Password reset links users asked for
+	workers.Go(workerCtx, "password_reset", worker.NewPasswordResetWorker(config, store, mail.NewMailer(config)).Run)
 
 	// Initialize the API server with configuration and store
 	server, err := api.NewServer(config, store)
 	if err != nil {
-		log.Fatal("cannot create server:", err)
+		fatal("cannot create server", err)
+	}
+
+	// Feed the notification stream from Postgres LISTEN/NOTIFY, so transfers made
+	// through any server instance reach clients connected to this one
+	listener := notify.NewListener(config.DBSource, server.Notifications())
+	workers.Go(workerCtx, "notification_listener", func(ctx context.Context) {
+		if err := listener.Run(ctx); err != nil {
+			slog.ErrorContext(ctx, "notification listener stopped", "error", err)
+		}
+	})
+	server.SetWorkers(workers)
+
+	// Serve Prometheus metrics on their own address, kept out of the public API
+	var metricsServer *http.Server
+	if config.MetricsAddress != "" {
+		prometheus.MustRegister(db.NewCollector(pool, replica))
+		metricsServer = api.NewMetricsServer(config.MetricsAddress)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server stopped", "error", err)
+			}
+		}()
 	}
 
 	// Start listening on the configured server address
-	err = server.Start(config.ServerAddress)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start(config.ServerAddress)
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("cannot start server", err)
+	case <-ctx.Done():
+		stop() // A second signal kills the process straight away
+	}
+
+	// 1. Stop taking requests and let in-flight ones finish
+	slog.Info("shutting down", "timeout", config.ShutdownTimeout.String())
+	if err := server.Shutdown(config.ShutdownTimeout); err != nil {
+		slog.Error("server did not shut down cleanly", "error", err)
+	}
+	if metricsServer != nil {
+		_ = metricsServer.Close()
+	}
+
+	// 2. Stop the background jobs, then let the deferred calls close the
+	// event sink and the database connections they were using
+	cancelWorkers()
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancelWait()
+	if err := workers.Wait(waitCtx); err != nil {
+		slog.Error("background jobs did not stop in time", "error", err)
+	}
+
+	// 3. Send the spans still buffered
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("cannot flush traces", "error", err)
+	}
+	slog.Info("shutdown complete")
+}
+
+// runMigrate runs a migrate subcommand against the database at dbSource:
+//
+//	up               apply every pending migration
+//	down [N]         roll back the last N migrations (1 by default)
+//	version          print the current schema version
+//	force VERSION    set the version after repairing a failed migration by hand
+func runMigrate(dbSource string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [N] | version | force VERSION")
+	}
+
+	// 1. Parse the arguments before connecting, so typos fail fast
+	number := 1
+	switch args[0] {
+	case "up", "version":
+	case "down", "force":
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid %s argument %q", args[0], args[1])
+			}
+			number = n
+		} else if args[0] == "force" {
+			return errors.New("usage: migrate force VERSION")
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	// 2. Run the command
+	migrator, err := migration.New(dbSource)
 	if err != nil {
-		log.Fatal("cannot start server", err)
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		return migrator.Down(number)
+	case "force":
+		return migrator.Force(number)
+	default:
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version %d (dirty: %t)\n", version, dirty)
+		return nil
 	}
 }
+
+// startupPingTimeout bounds the database check at startup
+const startupPingTimeout = 5 * time.Second
+
+// fatal logs the error that keeps the application from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
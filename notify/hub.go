@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"sync"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+)
+
+// subscriptionBuffer is how many notifications a slow subscriber may lag behind
+// before further notifications to it are dropped
+const subscriptionBuffer = 32
+
+// Hub fans transfer notifications out to in-process subscribers by username
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+	closed      bool // Set by Close; later subscriptions start out closed
+}
+
+// Subscription receives the notifications concerning one user's accounts
+type Subscription struct {
+	C <-chan db.TransferNotification // Notifications, closed when the subscription is closed
+
+	hub      *Hub
+	username string
+	ch       chan db.TransferNotification
+	once     sync.Once
+}
+
+// NewHub creates an empty Hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber for transfers touching username's accounts
+func (hub *Hub) Subscribe(username string) *Subscription {
+	ch := make(chan db.TransferNotification, subscriptionBuffer)
+	sub := &Subscription{C: ch, hub: hub, username: username, ch: ch}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.closed {
+		sub.once.Do(func() { close(ch) })
+		return sub
+	}
+	if hub.subscribers[username] == nil {
+		hub.subscribers[username] = make(map[*Subscription]struct{})
+	}
+	hub.subscribers[username][sub] = struct{}{}
+	return sub
+}
+
+// Close unregisters the subscription and closes its channel
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		hub := sub.hub
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		delete(hub.subscribers[sub.username], sub)
+		if len(hub.subscribers[sub.username]) == 0 {
+			delete(hub.subscribers, sub.username)
+		}
+		close(sub.ch)
+	})
+}
+
+// Close closes every subscription, ending the streams reading them, and makes later
+// subscriptions start out closed
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	hub.closed = true
+	var subs []*Subscription
+	for _, byUser := range hub.subscribers {
+		for sub := range byUser {
+			subs = append(subs, sub)
+		}
+	}
+	hub.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+// Publish delivers a notification to the subscribers of both account owners.
+// It never blocks: subscribers whose buffer is full miss the notification.
+func (hub *Hub) Publish(notification db.TransferNotification) {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	owners := []string{notification.FromOwner}
+	if notification.ToOwner != notification.FromOwner {
+		owners = append(owners, notification.ToOwner)
+	}
+
+	for _, owner := range owners {
+		for sub := range hub.subscribers[owner] {
+			select {
+			case sub.ch <- notification:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"testing"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	sender := util.RandomOwner()
+	receiver := util.RandomOwner()
+
+	senderSub := hub.Subscribe(sender)
+	receiverSub := hub.Subscribe(receiver)
+	otherSub := hub.Subscribe(util.RandomOwner())
+	defer senderSub.Close()
+	defer receiverSub.Close()
+	defer otherSub.Close()
+
+	notification := db.TransferNotification{
+		TransferID: 1,
+		FromOwner:  sender,
+		ToOwner:    receiver,
+		Amount:     10,
+	}
+	hub.Publish(notification)
+
+	require.Equal(t, notification, <-senderSub.C)
+	require.Equal(t, notification, <-receiverSub.C)
+	require.Empty(t, otherSub.C)
+}
+
+func TestHubSelfTransferDeliveredOnce(t *testing.T) {
+	hub := NewHub()
+	owner := util.RandomOwner()
+
+	sub := hub.Subscribe(owner)
+	defer sub.Close()
+
+	hub.Publish(db.TransferNotification{FromOwner: owner, ToOwner: owner})
+	<-sub.C
+	require.Empty(t, sub.C)
+}
+
+func TestHubSlowSubscriberDoesNotBlock(t *testing.T) {
+	hub := NewHub()
+	owner := util.RandomOwner()
+
+	sub := hub.Subscribe(owner)
+	defer sub.Close()
+
+	for i := 0; i < subscriptionBuffer*2; i++ {
+		hub.Publish(db.TransferNotification{TransferID: int64(i), FromOwner: owner})
+	}
+	require.Len(t, sub.C, subscriptionBuffer)
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	hub := NewHub()
+	owner := util.RandomOwner()
+
+	sub := hub.Subscribe(owner)
+	sub.Close()
+	sub.Close() // Closing twice is safe
+
+	_, ok := <-sub.C
+	require.False(t, ok)
+
+	// Publishing after close does not panic
+	hub.Publish(db.TransferNotification{FromOwner: owner})
+	require.Empty(t, hub.subscribers)
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub()
+	owner := util.RandomOwner()
+
+	sub := hub.Subscribe(owner)
+	other := hub.Subscribe(util.RandomOwner())
+	hub.Close()
+
+	_, ok := <-sub.C
+	require.False(t, ok)
+	_, ok = <-other.C
+	require.False(t, ok)
+	require.Empty(t, hub.subscribers)
+
+	// Subscribing after close gets a closed subscription
+	late := hub.Subscribe(owner)
+	_, ok = <-late.C
+	require.False(t, ok)
+	late.Close()
+	require.Empty(t, hub.subscribers)
+}
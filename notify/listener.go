@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+)
+
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = 90 * time.Second
+)
+
+// Listener feeds a Hub from Postgres LISTEN/NOTIFY, so every server instance
+// sees transfers committed by any instance
+type Listener struct {
+	dataSource string
+	hub        *Hub
+}
+
+// NewListener creates a Listener for the database at dataSource
+func NewListener(dataSource string, hub *Hub) *Listener {
+	return &Listener{dataSource: dataSource, hub: hub}
+}
+
+// Run listens on the ledger channel and publishes to the hub until ctx is cancelled.
+// LISTEN needs a session of its own, so it holds a dedicated connection outside the
+// pool, reconnecting with backoff whenever it is lost.
+func (listener *Listener) Run(ctx context.Context) error {
+	config, err := pgx.ParseConfig(listener.dataSource)
+	if err != nil {
+		return fmt.Errorf("cannot parse notification listener db source: %w", err)
+	}
+
+	retryIn := minReconnectInterval
+	for {
+		connected, err := listener.listen(ctx, config)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			retryIn = minReconnectInterval
+		}
+		slog.WarnContext(ctx, "notification listener connection error", "error", err, "retry_in", retryIn.String())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retryIn):
+		}
+		retryIn = min(retryIn*2, maxReconnectInterval)
+	}
+}
+
+// listen connects, subscribes to the ledger channel and publishes notifications until
+// the connection fails or ctx is cancelled. It reports whether it got as far as
+// listening, so a connection that worked for a while is retried quickly.
+func (listener *Listener) listen(ctx context.Context, config *pgx.ConnConfig) (bool, error) {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{db.LedgerChannel}.Sanitize()); err != nil {
+		return false, err
+	}
+
+	for {
+		// Wake up now and then while idle to check the connection is still alive
+		waitCtx, cancel := context.WithTimeout(ctx, pingInterval)
+		n, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+			return true, nil
+		case errors.Is(err, context.DeadlineExceeded):
+			if err := conn.Ping(ctx); err != nil {
+				return true, err
+			}
+			continue
+		case err != nil:
+			return true, err
+		}
+
+		var notification db.TransferNotification
+		if err := json.Unmarshal([]byte(n.Payload), &notification); err != nil {
+			slog.WarnContext(ctx, "notification listener: invalid payload", "error", err)
+			continue
+		}
+		listener.hub.Publish(notification)
+	}
+}
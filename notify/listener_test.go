@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerInvalidSource(t *testing.T) {
+	err := NewListener("postgresql://root@localhost:notaport/simple_bank", NewHub()).Run(context.Background())
+	require.Error(t, err)
+}
+
+func TestListenerStopsWhileReconnecting(t *testing.T) {
+	// Nothing listens on port 1, so the listener keeps retrying until cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewListener("postgresql://root@127.0.0.1:1/simple_bank?connect_timeout=1", NewHub()).Run(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not stop")
+	}
+}
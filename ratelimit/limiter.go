@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// Store names accepted by RATE_LIMIT_STORE
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+// DefaultWindow applies when RATE_LIMIT_WINDOW is not configured
+const DefaultWindow = time.Minute
+
+// Store counts requests per key in fixed windows
+type Store interface {
+	// Hit counts one request against key in the window starting at windowStart and
+	// returns how many requests the window has counted so far, this one included
+	Hit(ctx context.Context, key string, windowStart time.Time) (int64, error)
+}
+
+// Limit is a budget of Requests per Window
+type Limit struct {
+	Requests int64
+	Window   time.Duration
+}
+
+// Result tells whether a request fits its budget and how much of it is left
+type Result struct {
+	Limit     Limit
+	Allowed   bool      // Whether the request is within the budget
+	Remaining int64     // Requests left in the current window
+	Reset     time.Time // When the current window ends and the budget refills
+}
+
+// Limiter applies limits to request counts kept in a Store. Windows are aligned to the
+// clock (a one minute window starts on the minute), so replicas sharing a Store agree.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter creates a Limiter counting requests in store
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// NewStore picks the Store named by RATE_LIMIT_STORE: in memory (the default), or in
+// Postgres through queries so every replica shares the counts
+func NewStore(config util.Config, queries db.Querier) (Store, error) {
+	window := config.RateLimitWindow
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	switch config.RateLimitStore {
+	case "", StoreMemory:
+		return NewMemoryStore(window), nil
+	case StorePostgres:
+		return NewPostgresStore(queries, window), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", config.RateLimitStore)
+	}
+}
+
+// Allow counts a request against key and reports whether it is within limit
+func (limiter *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	windowStart := limiter.now().UTC().Truncate(limit.Window)
+	hits, err := limiter.store.Hit(ctx, key, windowStart)
+	if err != nil {
+		return Result{}, fmt.Errorf("cannot count request for %s: %w", key, err)
+	}
+
+	return Result{
+		Limit:     limit,
+		Allowed:   hits <= limit.Requests,
+		Remaining: max(limit.Requests-hits, 0),
+		Reset:     windowStart.Add(limit.Window),
+	}, nil
+}
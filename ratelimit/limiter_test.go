@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 30, 0, time.UTC)
+	limiter := NewLimiter(NewMemoryStore(time.Minute))
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Window: time.Minute}
+
+	// 1. The budget is spent request by request, per key
+	for _, remaining := range []int64{1, 0} {
+		result, err := limiter.Allow(context.Background(), "api:user:alice", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, remaining, result.Remaining)
+		require.Equal(t, time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC), result.Reset)
+	}
+
+	result, err := limiter.Allow(context.Background(), "api:user:alice", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	result, err = limiter.Allow(context.Background(), "api:user:bob", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// 2. The next window refills it
+	now = now.Add(30 * time.Second)
+	result, err = limiter.Allow(context.Background(), "api:user:alice", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, int64(1), result.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err := store.Hit(context.Background(), "a", start)
+	require.NoError(t, err)
+	_, err = store.Hit(context.Background(), "b", start.Add(time.Minute))
+	require.NoError(t, err)
+
+	// Only the key whose window is over is forgotten
+	require.Len(t, store.windows, 1)
+	require.Contains(t, store.windows, "b")
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	windowStart := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	queries := mockdb.NewMockStore(ctrl)
+	store := NewPostgresStore(queries, time.Minute)
+
+	// 1. The first hit also sweeps expired counters; a failed sweep doesn't fail it
+	queries.EXPECT().
+		HitRateLimit(gomock.Any(), db.HitRateLimitParams{Key: "auth:ip:203.0.113.7", WindowStart: windowStart}).
+		Times(2).
+		Return(int64(3), nil)
+	queries.EXPECT().
+		DeleteExpiredRateLimits(gomock.Any(), windowStart.Add(-time.Minute)).
+		Times(1).
+		Return(errors.New("connection reset"))
+
+	hits, err := store.Hit(context.Background(), "auth:ip:203.0.113.7", windowStart)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), hits)
+
+	// 2. The next sweep waits for the retention period
+	_, err = store.Hit(context.Background(), "auth:ip:203.0.113.7", windowStart)
+	require.NoError(t, err)
+
+	// 3. Errors counting are returned
+	queries.EXPECT().HitRateLimit(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), errors.New("connection refused"))
+	_, err = store.Hit(context.Background(), "auth:ip:203.0.113.7", windowStart)
+	require.Error(t, err)
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(util.Config{}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &MemoryStore{}, store)
+
+	store, err = NewStore(util.Config{RateLimitStore: StorePostgres}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &PostgresStore{}, store)
+
+	_, err = NewStore(util.Config{RateLimitStore: "redis"}, nil)
+	require.Error(t, err)
+}
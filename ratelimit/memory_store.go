@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// window is the request count of one key in one window
+type window struct {
+	start time.Time
+	hits  int64
+}
+
+// MemoryStore keeps request counts in memory. Each process counts on its own, so with
+// several replicas a client gets the budget from each of them.
+type MemoryStore struct {
+	retention time.Duration // Windows that started longer ago than this are over
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore whose windows last at most retention
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{retention: retention, windows: make(map[string]*window)}
+}
+
+// Hit counts a request against key in the window starting at windowStart
+func (store *MemoryStore) Hit(ctx context.Context, key string, windowStart time.Time) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.sweep(windowStart)
+
+	current, ok := store.windows[key]
+	if !ok || !current.start.Equal(windowStart) {
+		current = &window{start: windowStart}
+		store.windows[key] = current
+	}
+	current.hits++
+	return current.hits, nil
+}
+
+// sweep forgets the keys whose window is over, at most once per retention period so
+// the map doesn't grow with every client ever seen
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < store.retention {
+		return
+	}
+	store.lastSweep = now
+
+	for key, current := range store.windows {
+		if now.Sub(current.start) >= store.retention {
+			delete(store.windows, key)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+)
+
+// PostgresStore keeps request counts in the rate_limits table, so every replica of the
+// API draws from the same budget
+type PostgresStore struct {
+	queries   db.Querier
+	retention time.Duration // Windows that started longer ago than this are over
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresStore creates a PostgresStore whose windows last at most retention
+func NewPostgresStore(queries db.Querier, retention time.Duration) *PostgresStore {
+	return &PostgresStore{queries: queries, retention: retention}
+}
+
+// Hit counts a request against key in the window starting at windowStart
+func (store *PostgresStore) Hit(ctx context.Context, key string, windowStart time.Time) (int64, error) {
+	hits, err := store.queries.HitRateLimit(ctx, db.HitRateLimitParams{
+		Key:         key,
+		WindowStart: windowStart,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	store.sweep(ctx, windowStart)
+	return hits, nil
+}
+
+// sweep deletes counters whose window is over, at most once per retention period.
+// Failures are only logged: stale rows are reset by the next hit on their key anyway.
+func (store *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	store.mu.Lock()
+	if now.Sub(store.lastSweep) < store.retention {
+		store.mu.Unlock()
+		return
+	}
+	store.lastSweep = now
+	store.mu.Unlock()
+
+	if err := store.queries.DeleteExpiredRateLimits(ctx, now.Add(-store.retention)); err != nil {
+		slog.WarnContext(ctx, "cannot delete expired rate limits", "error", err)
+	}
+}
@@ -0,0 +1,173 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidNonce is returned by Exchange when the ID token wasn't minted for this login
+var ErrInvalidNonce = errors.New("id token nonce does not match")
+
+// Identity is a user as described by the provider's ID token
+type Identity struct {
+	Issuer            string   `json:"issuer"`
+	Subject           string   `json:"subject"` // Stable ID of the user at the provider
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Groups            []string `json:"groups"` // Values of the configured role claim
+}
+
+// Provider signs users in with an OpenID Connect provider using the authorization code
+// flow with PKCE
+type Provider struct {
+	verifier  *oidc.IDTokenVerifier
+	oauth2    oauth2.Config
+	roleClaim string
+	roles     map[string]string
+}
+
+// NewProvider discovers the provider at OIDC_ISSUER_URL and prepares the client
+// registered there
+func NewProvider(ctx context.Context, config util.Config) (*Provider, error) {
+	roles, err := ParseRoleMapping(config.OIDCRoleMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.OIDCIssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot discover OIDC provider: %w", err)
+	}
+
+	return &Provider{
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.OIDCClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+			RedirectURL:  config.OIDCRedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		roleClaim: config.OIDCRoleClaim,
+		roles:     roles,
+	}, nil
+}
+
+// AuthCodeURL returns the provider's sign-in page for a login identified by state. Only
+// the S256 challenge of verifier leaves the server.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems the authorization code from the callback, verifies the ID token and
+// returns the identity it describes
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	// 1. Redeem the code, proving with the verifier that we started this login
+	oauth2Token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("cannot exchange code: %w", err)
+	}
+
+	// 2. Verify the ID token's signature, issuer, audience and expiry
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, ErrInvalidNonce
+	}
+
+	// 3. Read the claims we map to a user
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             stringClaim(claims, "email"),
+		Name:              stringClaim(claims, "name"),
+		PreferredUsername: stringClaim(claims, "preferred_username"),
+		Groups:            stringsClaim(claims, p.roleClaim),
+	}
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+
+	return identity, nil
+}
+
+// Role maps the identity's groups to a local role. It returns false when no mapping
+// is configured, in which case roles are managed locally. Identities in no mapped
+// group are depositors; banker wins over depositor.
+func (p *Provider) Role(identity Identity) (string, bool) {
+	if len(p.roles) == 0 {
+		return "", false
+	}
+
+	role := util.DepositorRole
+	for _, group := range identity.Groups {
+		if p.roles[group] == util.BankerRole {
+			role = util.BankerRole
+		}
+	}
+	return role, true
+}
+
+// ParseRoleMapping parses a comma-separated list of GROUP=ROLE pairs
+// (e.g. "bank-staff=banker") into a map of provider groups to local roles.
+func ParseRoleMapping(s string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid role mapping %q: expected GROUP=ROLE", pair)
+		}
+
+		group = strings.TrimSpace(group)
+		role = strings.TrimSpace(role)
+		if group == "" || !util.IsSupportedRole(role) {
+			return nil, fmt.Errorf("invalid role mapping %q: unsupported role", pair)
+		}
+		roles[group] = role
+	}
+	return roles, nil
+}
+
+// stringClaim returns a string claim, or "" when it is missing or not a string
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringsClaim returns a claim that may hold one string or a list of them
+func stringsClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package sso
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/sso/ssotest"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func newTestProvider(t *testing.T, roleMapping string) (*Provider, *ssotest.IdP) {
+	idp, err := ssotest.NewIdP("simple-bank", util.RandomString(32))
+	require.NoError(t, err)
+	t.Cleanup(idp.Close)
+
+	provider, err := NewProvider(context.Background(), util.Config{
+		OIDCIssuerURL:    idp.URL,
+		OIDCClientID:     idp.ClientID,
+		OIDCClientSecret: idp.ClientSecret,
+		OIDCRedirectURL:  "http://localhost:8080/users/login/oidc/callback",
+		OIDCRoleClaim:    "groups",
+		OIDCRoleMapping:  roleMapping,
+	})
+	require.NoError(t, err)
+	return provider, idp
+}
+
+func TestExchange(t *testing.T) {
+	provider, idp := newTestProvider(t, "bank-staff=banker")
+
+	verifier := oauth2.GenerateVerifier()
+	callback, err := idp.Login(provider.AuthCodeURL("state", "nonce", verifier), map[string]any{
+		"sub":                "user-1",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"name":               "Alice Banker",
+		"preferred_username": "alice",
+		"groups":             []string{"everyone", "bank-staff"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "state", callback.Query().Get("state"))
+
+	identity, err := provider.Exchange(context.Background(), callback.Query().Get("code"), verifier, "nonce")
+	require.NoError(t, err)
+	require.Equal(t, Identity{
+		Issuer:            idp.URL,
+		Subject:           "user-1",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		Name:              "Alice Banker",
+		PreferredUsername: "alice",
+		Groups:            []string{"everyone", "bank-staff"},
+	}, identity)
+
+	role, ok := provider.Role(identity)
+	require.True(t, ok)
+	require.Equal(t, util.BankerRole, role)
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	provider, idp := newTestProvider(t, "")
+
+	callback, err := idp.Login(provider.AuthCodeURL("state", "nonce", oauth2.GenerateVerifier()), map[string]any{"sub": "user-1"})
+	require.NoError(t, err)
+
+	_, err = provider.Exchange(context.Background(), callback.Query().Get("code"), oauth2.GenerateVerifier(), "nonce")
+	require.Error(t, err)
+}
+
+func TestExchangeWrongNonce(t *testing.T) {
+	provider, idp := newTestProvider(t, "")
+
+	verifier := oauth2.GenerateVerifier()
+	callback, err := idp.Login(provider.AuthCodeURL("state", "nonce", verifier), map[string]any{"sub": "user-1"})
+	require.NoError(t, err)
+
+	_, err = provider.Exchange(context.Background(), callback.Query().Get("code"), verifier, "other-nonce")
+	require.ErrorIs(t, err, ErrInvalidNonce)
+}
+
+func TestExchangeCodeReuse(t *testing.T) {
+	provider, idp := newTestProvider(t, "")
+
+	verifier := oauth2.GenerateVerifier()
+	callback, err := idp.Login(provider.AuthCodeURL("state", "nonce", verifier), map[string]any{"sub": "user-1"})
+	require.NoError(t, err)
+	code := callback.Query().Get("code")
+
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce")
+	require.NoError(t, err)
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce")
+	require.Error(t, err)
+}
+
+func TestRole(t *testing.T) {
+	provider, _ := newTestProvider(t, "bank-staff=banker, customers=depositor")
+
+	role, ok := provider.Role(Identity{Groups: []string{"customers"}})
+	require.True(t, ok)
+	require.Equal(t, util.DepositorRole, role)
+
+	role, ok = provider.Role(Identity{})
+	require.True(t, ok)
+	require.Equal(t, util.DepositorRole, role)
+
+	unmapped, _ := newTestProvider(t, "")
+	_, ok = unmapped.Role(Identity{Groups: []string{"bank-staff"}})
+	require.False(t, ok)
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	roles, err := ParseRoleMapping(" bank-staff = banker ,customers=depositor,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"bank-staff": util.BankerRole, "customers": util.DepositorRole}, roles)
+
+	_, err = ParseRoleMapping("bank-staff")
+	require.Error(t, err)
+
+	_, err = ParseRoleMapping("bank-staff=admin")
+	require.Error(t, err)
+}
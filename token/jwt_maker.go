@@ -26,14 +26,34 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 	return &JWTMaker{secretKey}, nil
 }
 
-// CreateToken generates a new JWT token for a given username and duration
+// CreateToken generates a new JWT access token for a given username and duration
 func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	// Create a new Payload object with the provided username and duration
-	payload, err := NewPayload(username, duration)
+	return maker.CreateTypedToken(username, TypeAccess, duration)
+}
+
+// CreateTypedToken generates a new JWT token of the given type
+func (maker *JWTMaker) CreateTypedToken(username string, tokenType string, duration time.Duration) (string, *Payload, error) {
+	// Create a new Payload object with the provided username, type and duration
+	payload, err := NewTypedPayload(username, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	return maker.sign(payload)
+}
+
+// CreateImpersonationToken generates a JWT token letting actor act as subject
+func (maker *JWTMaker) CreateImpersonationToken(actor string, subject string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewImpersonationPayload(actor, subject, duration)
 	if err != nil {
 		return "", payload, err
 	}
 
+	return maker.sign(payload)
+}
+
+// sign signs the payload into a token
+func (maker *JWTMaker) sign(payload *Payload) (string, *Payload, error) {
 	// Create a new JWT token with the HS256 signing method and the payload
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 	// Sign the token using the maker's secret key
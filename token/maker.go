@@ -9,6 +9,12 @@ type Maker interface {
 	// CreateToken creates a new token for a specific username and duration
 	CreateToken(username string, duration time.Duration) (string, *Payload, error)
 
+	// CreateTypedToken creates a new token of the given type (e.g., TypeMFAChallenge)
+	CreateTypedToken(username string, tokenType string, duration time.Duration) (string, *Payload, error)
+
+	// CreateImpersonationToken creates a token letting actor (a banker) act as subject
+	CreateImpersonationToken(actor string, subject string, duration time.Duration) (string, *Payload, error)
+
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
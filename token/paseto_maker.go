@@ -33,14 +33,34 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-// CreateToken generates a new token for a username and duration using PASETO encryption
+// CreateToken generates a new access token for a username and duration using PASETO encryption
 func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	// Create a new Payload object with username and duration
-	payload, err := NewPayload(username, duration)
+	return maker.CreateTypedToken(username, TypeAccess, duration)
+}
+
+// CreateTypedToken generates a new token of the given type using PASETO encryption
+func (maker *PasetoMaker) CreateTypedToken(username string, tokenType string, duration time.Duration) (string, *Payload, error) {
+	// Create a new Payload object with username, type and duration
+	payload, err := NewTypedPayload(username, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	return maker.encrypt(payload)
+}
+
+// CreateImpersonationToken generates a token letting actor act as subject using PASETO encryption
+func (maker *PasetoMaker) CreateImpersonationToken(actor string, subject string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewImpersonationPayload(actor, subject, duration)
 	if err != nil {
 		return "", payload, err
 	}
 
+	return maker.encrypt(payload)
+}
+
+// encrypt seals the payload into a token
+func (maker *PasetoMaker) encrypt(payload *Payload) (string, *Payload, error) {
 	// Encrypt the payload using the Paseto instance and symmetric key
 	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
 	return token, payload, err
@@ -35,11 +35,41 @@ func TestPasetoMaker(t *testing.T) {
 
 	// Assert various properties of the extracted payload
 	require.NotZero(t, payload.ID, "Payload ID is zero")
+	require.Equal(t, TypeAccess, payload.Type, "Type mismatch")
 	require.Equal(t, username, payload.Username, "Username mismatch")
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second, "IssuedAt time mismatch")
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second, "ExpiredAt time mismatch")
 }
 
+// TestTypedPasetoToken tests that the token type survives a round trip
+func TestTypedPasetoToken(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateTypedToken(util.RandomOwner(), TypeMFAChallenge, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, TypeMFAChallenge, payload.Type)
+}
+
+// TestImpersonationPasetoToken tests that the actor and subject survive a round trip
+func TestImpersonationPasetoToken(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	actor, subject := util.RandomOwner(), util.RandomOwner()
+	token, _, err := maker.CreateImpersonationToken(actor, subject, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, TypeImpersonation, payload.Type)
+	require.Equal(t, actor, payload.Actor)
+	require.Equal(t, subject, payload.Username)
+}
+
 // TestExpiredPasetoToken tests handling of expired tokens
 func TestExpiredPasetoToken(t *testing.T) {
 	// Create a new PasetoMaker instance with a random key
@@ -15,16 +15,31 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Token types, so a token minted for one purpose can't be used for another
+const (
+	TypeAccess        = "access"        // Grants access to protected routes
+	TypeMFAChallenge  = "mfa_challenge" // Proves the password step of a two-step login
+	TypeAPIKey        = "api_key"       // Stands for a personal API key; never minted as a token
+	TypeImpersonation = "impersonation" // Lets a banker (the actor) act as a customer
+)
+
 // Payload represents the data contained within a token
 type Payload struct {
-	ID        uuid.UUID `json:"id"`         // Unique identifier for the token (UUID)
-	Username  string    `json:"username"`   // Username associated with the token
-	IssuedAt  time.Time `json:"issued_at"`  // Time the token was issued
-	ExpiredAt time.Time `json:"expired_at"` // Time the token expires
+	ID        uuid.UUID `json:"id"`              // Unique identifier for the token (UUID)
+	Type      string    `json:"type"`            // Token type (TypeAccess, TypeMFAChallenge, TypeAPIKey, TypeImpersonation)
+	Username  string    `json:"username"`        // Username associated with the token; the impersonated user for TypeImpersonation
+	Actor     string    `json:"actor,omitempty"` // Banker acting as Username; only set for TypeImpersonation
+	IssuedAt  time.Time `json:"issued_at"`       // Time the token was issued
+	ExpiredAt time.Time `json:"expired_at"`      // Time the token expires
 }
 
-// NewPayload creates a new Payload object with a username and duration
+// NewPayload creates a new access token Payload object with a username and duration
 func NewPayload(username string, duration time.Duration) (*Payload, error) {
+	return NewTypedPayload(username, TypeAccess, duration)
+}
+
+// NewTypedPayload creates a new Payload object of the given type with a username and duration
+func NewTypedPayload(username string, tokenType string, duration time.Duration) (*Payload, error) {
 	// Generate a random UUID for the token ID
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
@@ -34,6 +49,7 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	// Create a new Payload object with provided data and calculated expiration
 	payload := &Payload{
 		ID:        tokenID,
+		Type:      tokenType,
 		Username:  username,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
@@ -42,6 +58,17 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	return payload, nil
 }
 
+// NewImpersonationPayload creates a Payload letting actor act as subject for duration
+func NewImpersonationPayload(actor string, subject string, duration time.Duration) (*Payload, error) {
+	payload, err := NewTypedPayload(subject, TypeImpersonation, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.Actor = actor
+	return payload, nil
+}
+
 // Valid checks if the Payload object represents a valid, unexpired token
 func (payload *Payload) Valid() error {
 	// Check if current time is past the token's expiration
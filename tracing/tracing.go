@@ -0,0 +1,82 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter spans are sent to, the
+// sampler, and W3C trace context propagation.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ServiceName identifies this application's spans
+const ServiceName = "simplebank"
+
+// Exporters selectable with TRACING_EXPORTER
+const (
+	ExporterNone   = "none"   // Spans are propagated but not recorded
+	ExporterStdout = "stdout" // Pretty-printed JSON spans, for local use
+	ExporterOTLP   = "otlp"   // OTLP over HTTP to a collector (Jaeger, Tempo, ...)
+)
+
+// Options configures Setup
+type Options struct {
+	Exporter    string    // One of the Exporter* constants; empty means none
+	Endpoint    string    // OTLP endpoint URL (e.g. http://localhost:4318); empty uses OTEL_EXPORTER_OTLP_ENDPOINT or the default
+	SampleRatio float64   // Share of new traces recorded, 0 to 1; traces started by a caller follow its decision
+	Output      io.Writer // Where the stdout exporter writes; os.Stdout when nil
+}
+
+// Setup installs the global tracer provider and the W3C traceparent/baggage propagator.
+// The returned function flushes buffered spans and stops the exporter; call it on
+// shutdown.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+
+	case ExporterStdout:
+		output := opts.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(output), stdouttrace.WithPrettyPrint())
+
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupStdout(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterStdout, SampleRatio: 1, Output: &buf})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "GET /accounts/:id")
+	span.End()
+
+	// Spans are batched until shutdown flushes them
+	require.NoError(t, shutdown(context.Background()))
+	require.Contains(t, buf.String(), `"Name": "GET /accounts/:id"`)
+	require.Contains(t, buf.String(), ServiceName)
+}
+
+func TestSetupNone(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Options{Exporter: "zipkin"})
+	require.Error(t, err)
+}
@@ -0,0 +1,17 @@
+package util
+
+// constants for all supported account types
+
+const (
+	CheckingAccount = "checking"
+	SavingsAccount  = "savings"
+)
+
+// IsSupportedAccountType returns true if the account type is supported
+func IsSupportedAccountType(accountType string) bool {
+	switch accountType {
+	case CheckingAccount, SavingsAccount:
+		return true
+	}
+	return false
+}
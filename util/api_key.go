@@ -0,0 +1,45 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// apiKeyLabel starts every API key, so leaked keys are easy to recognize and scan for
+const apiKeyLabel = "sbk"
+
+// apiKeyPrefixBytes is the size of the public, lookup part of a key
+const apiKeyPrefixBytes = 6
+
+// ErrInvalidAPIKey is returned by ParseAPIKey for strings that aren't API keys
+var ErrInvalidAPIKey = errors.New("api key is invalid")
+
+// GenerateAPIKey returns a new API key of the form sbk_<prefix>_<secret>, along with its
+// prefix and secret parts. Only the prefix and the hash of the secret should be stored.
+func GenerateAPIKey() (key, prefix, secret string, err error) {
+	buf := make([]byte, apiKeyPrefixBytes)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix = hex.EncodeToString(buf)
+
+	secret, err = GenerateSecureToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return fmt.Sprintf("%s_%s_%s", apiKeyLabel, prefix, secret), prefix, secret, nil
+}
+
+// ParseAPIKey splits an API key into its prefix and secret parts
+func ParseAPIKey(key string) (prefix, secret string, err error) {
+	// The secret is URL-safe base64 and may itself contain underscores
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyLabel || len(parts[1]) != 2*apiKeyPrefixBytes || parts[2] == "" {
+		return "", "", ErrInvalidAPIKey
+	}
+	return parts[1], parts[2], nil
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, secret, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, "sbk_"+prefix+"_"))
+
+	parsedPrefix, parsedSecret, err := ParseAPIKey(key)
+	require.NoError(t, err)
+	require.Equal(t, prefix, parsedPrefix)
+	require.Equal(t, secret, parsedSecret)
+
+	// Keys are random
+	other, _, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, other)
+
+	// Underscores in the secret are kept
+	_, parsedSecret, err = ParseAPIKey("sbk_0123456789ab_se_cret")
+	require.NoError(t, err)
+	require.Equal(t, "se_cret", parsedSecret)
+
+	for _, invalid := range []string{"", "sbk", "sbk_0123456789ab", "sbk_0123456789ab_", "xyz_0123456789ab_secret", "sbk_short_secret"} {
+		_, _, err := ParseAPIKey(invalid)
+		require.ErrorIs(t, err, ErrInvalidAPIKey, invalid)
+	}
+}
@@ -9,12 +9,71 @@ import (
 
 // Config represents the application configuration loaded from a file or environment variables
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`             // Database driver to use (e.g., "mysql", "postgres")
-	DBSource            string        `mapstructure:"DB_SOURCE"`             // Database connection source string
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`        // Server address where the application listens (e.g., "localhost:8080")
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`   // Symmetric key used for token signing (should be kept secret)
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"` // Duration for which access tokens are valid
-	AllowedOrigins      string        `mapstructure:"ALLOWED_ORIGINS"`       // Comma-separated list of allowed origins for CORS
+	DBDriver            string        `mapstructure:"DB_DRIVER"`                     // Database driver to use (e.g., "mysql", "postgres")
+	DBSource            string        `mapstructure:"DB_SOURCE"`                     // Database connection source string
+	DBReplicaSource     string        `mapstructure:"DB_REPLICA_SOURCE"`             // Read replica serving lag-tolerant reads; empty sends every query to DB_SOURCE
+	ReplicaStickiness   time.Duration `mapstructure:"DB_REPLICA_STICKINESS"`         // How long after a write the same user's reads stay on the primary
+	DBMaxConns          int           `mapstructure:"DB_MAX_CONNS"`                  // Most connections the pool opens; 0 keeps pgx's default (4 or the number of CPUs)
+	DBMinConns          int           `mapstructure:"DB_MIN_CONNS"`                  // Connections the pool keeps open even when idle
+	DBMaxConnLifetime   time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`          // Connections older than this are closed and replaced (default 1h)
+	DBMaxConnIdleTime   time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`         // Idle connections above DB_MIN_CONNS are closed after this (default 30m)
+	DBStatementCache    int           `mapstructure:"DB_STATEMENT_CACHE_SIZE"`       // Prepared statements cached per connection; 0 sends queries unprepared (for PgBouncer)
+	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`                // Server address where the application listens (e.g., "localhost:8080")
+	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`           // Symmetric key used for token signing (should be kept secret)
+	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`         // Duration for which access tokens are valid
+	AllowedOrigins      string        `mapstructure:"ALLOWED_ORIGINS"`               // Comma-separated list of allowed origins for CORS
+	TrustedProxies      string        `mapstructure:"TRUSTED_PROXIES"`               // Comma-separated IPs or CIDRs of the load balancers allowed to report client addresses
+	ProxyHeader         string        `mapstructure:"PROXY_HEADER"`                  // Header trusted proxies report the client address in (default X-Forwarded-For)
+	LogLevel            string        `mapstructure:"LOG_LEVEL"`                     // Minimum level of the JSON logs: debug, info (default), warn or error
+	ShutdownTimeout     time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`              // How long a shutdown waits for in-flight requests and background jobs
+	MetricsAddress      string        `mapstructure:"METRICS_ADDRESS"`               // Separate address serving Prometheus metrics at /metrics; empty disables them
+	TracingExporter     string        `mapstructure:"TRACING_EXPORTER"`              // Where OpenTelemetry spans go: none (default), stdout or otlp
+	TracingEndpoint     string        `mapstructure:"TRACING_OTLP_ENDPOINT"`         // OTLP/HTTP collector URL (e.g., "http://localhost:4318")
+	TracingSampleRatio  float64       `mapstructure:"TRACING_SAMPLE_RATIO"`          // Share of new traces recorded, from 0 to 1
+	RunMigrations       bool          `mapstructure:"RUN_MIGRATIONS_ON_START"`       // Apply pending embedded migrations before serving
+	InterestRates       string        `mapstructure:"INTEREST_RATES"`                // Annual savings rates per currency in basis points (e.g., "USD=250,EUR=150")
+	InterestHouseOwner  string        `mapstructure:"INTEREST_HOUSE_OWNER"`          // Username owning the house accounts that interest is paid from
+	OutboxLogFile       string        `mapstructure:"OUTBOX_LOG_FILE"`               // File the outbox relay appends events to (stdout when empty)
+	SMTPHost            string        `mapstructure:"SMTP_HOST"`                     // SMTP relay host; emails go to the outbox when empty
+	SMTPPort            int           `mapstructure:"SMTP_PORT"`                     // SMTP relay port (e.g., 587)
+	SMTPUsername        string        `mapstructure:"SMTP_USERNAME"`                 // SMTP username (no auth when empty)
+	SMTPPassword        string        `mapstructure:"SMTP_PASSWORD"`                 // SMTP password
+	MailFrom            string        `mapstructure:"MAIL_FROM"`                     // Sender address of outgoing emails
+	MailOutboxFile      string        `mapstructure:"MAIL_OUTBOX_FILE"`              // File emails are appended to when SMTP is not configured (in-memory when empty)
+	PasswordResetURL    string        `mapstructure:"PASSWORD_RESET_URL"`            // Frontend page the emailed reset token is appended to
+	VerifyEmailURL      string        `mapstructure:"VERIFY_EMAIL_URL"`              // Link emailed to new users; the verification token is appended as ?token=
+	VerifyEmailDuration time.Duration `mapstructure:"VERIFY_EMAIL_TOKEN_DURATION"`   // How long an email verification token stays valid
+	MFAIssuer           string        `mapstructure:"MFA_ISSUER"`                    // Issuer name shown in authenticator apps
+	MFATokenDuration    time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`        // How long the MFA challenge token from login stays valid
+	MFAStepUpAmount     int64         `mapstructure:"MFA_STEP_UP_AMOUNT"`            // Transfers above this amount need a TOTP code (0 disables step-up)
+	LoginMaxAttempts    int32         `mapstructure:"LOGIN_MAX_ATTEMPTS"`            // Consecutive failed logins a user gets before being locked out
+	LoginIPMaxAttempts  int32         `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`         // Failed logins an IP gets within a day before being locked out
+	LoginLockoutBase    time.Duration `mapstructure:"LOGIN_LOCKOUT_BASE"`            // First lockout, doubled with every further failure
+	LoginLockoutMax     time.Duration `mapstructure:"LOGIN_LOCKOUT_MAX"`             // Longest lockout
+	PasswordHasher      string        `mapstructure:"PASSWORD_HASHER"`               // Algorithm for new password hashes: argon2id (default) or bcrypt
+	Argon2Memory        uint32        `mapstructure:"ARGON2_MEMORY_KIB"`             // argon2id memory in KiB
+	Argon2Iterations    uint32        `mapstructure:"ARGON2_ITERATIONS"`             // argon2id passes over memory
+	Argon2Threads       uint8         `mapstructure:"ARGON2_PARALLELISM"`            // argon2id lanes
+	BcryptCost          int           `mapstructure:"BCRYPT_COST"`                   // bcrypt cost when PASSWORD_HASHER=bcrypt
+	PasswordMinLength   int           `mapstructure:"PASSWORD_MIN_LENGTH"`           // Minimum characters in a new password
+	BreachedPasswords   string        `mapstructure:"BREACHED_PASSWORDS_FILE"`       // File of known breached passwords, one per line, refused as new passwords
+	ResetTokenDuration  time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"` // How long a password reset token stays valid
+	OIDCIssuerURL       string        `mapstructure:"OIDC_ISSUER_URL"`               // OpenID Connect provider for single sign-on (disabled when empty)
+	OIDCClientID        string        `mapstructure:"OIDC_CLIENT_ID"`                // Client ID registered at the provider
+	OIDCClientSecret    string        `mapstructure:"OIDC_CLIENT_SECRET"`            // Client secret registered at the provider
+	OIDCRedirectURL     string        `mapstructure:"OIDC_REDIRECT_URL"`             // Callback URL registered at the provider
+	OIDCRoleClaim       string        `mapstructure:"OIDC_ROLE_CLAIM"`               // ID token claim holding the user's groups or roles (e.g., "groups")
+	OIDCRoleMapping     string        `mapstructure:"OIDC_ROLE_MAPPING"`             // Provider groups to local roles (e.g., "bank-staff=banker"); roles aren't synced when empty
+	OIDCProvisioning    bool          `mapstructure:"OIDC_JIT_PROVISIONING"`         // Create users on their first single sign-on
+	OIDCLoginDuration   time.Duration `mapstructure:"OIDC_LOGIN_DURATION"`           // How long a user has to complete a single sign-on
+	ActAsDuration       time.Duration `mapstructure:"IMPERSONATION_TOKEN_DURATION"`  // How long a banker's act-as token stays valid
+	ActAsMoneyMovement  bool          `mapstructure:"IMPERSONATION_MONEY_MOVEMENT"`  // Let act-as tokens make transfers and close accounts
+	RateLimitStore      string        `mapstructure:"RATE_LIMIT_STORE"`              // Where request counts live: memory (per process) or postgres (shared by replicas)
+	RateLimitWindow     time.Duration `mapstructure:"RATE_LIMIT_WINDOW"`             // Length of the fixed window every budget applies to
+	RateLimitDefault    int           `mapstructure:"RATE_LIMIT_DEFAULT"`            // Authenticated requests a user gets per window (0 disables)
+	RateLimitAuth       int           `mapstructure:"RATE_LIMIT_AUTH"`               // Sign-up, login and password reset requests an IP gets per window (0 disables)
+	RateLimitTransfers  int           `mapstructure:"RATE_LIMIT_TRANSFERS"`          // Transfers a user can make per window (0 disables)
+	RootAliasSunset     string        `mapstructure:"ROOT_ALIAS_SUNSET"`             // Date (YYYY-MM-DD) the unversioned aliases of /v1 routes are removed, sent as Sunset
 }
 
 // LoadConfig reads the application configuration from a specified file or environment variables
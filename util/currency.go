@@ -16,3 +16,12 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// MaxAmount caps a single amount of money, in the currency's smallest unit, far below
+// where sums of balances could overflow
+const MaxAmount int64 = 1_000_000_000_000
+
+// IsValidAmount returns true if the amount is positive and at most MaxAmount
+func IsValidAmount(amount int64) bool {
+	return amount > 0 && amount <= MaxAmount
+}
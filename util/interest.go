@@ -0,0 +1,90 @@
+package util
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MicrosPerUnit is the number of accrual micro-units in one minor currency unit (e.g. one cent)
+	MicrosPerUnit = 1_000_000
+
+	basisPointsPerUnit = 10_000
+	daysPerYear        = 365
+)
+
+// ParseInterestRates parses a comma-separated list of CURRENCY=BPS pairs
+// (e.g. "USD=250,EUR=150") into a map of annual rates in basis points.
+func ParseInterestRates(s string) (map[string]int64, error) {
+	rates := make(map[string]int64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		currency, bps, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid interest rate %q: expected CURRENCY=BPS", pair)
+		}
+
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("invalid interest rate %q: unsupported currency", pair)
+		}
+
+		rate, err := strconv.ParseInt(strings.TrimSpace(bps), 10, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid interest rate %q: basis points must be a non-negative integer", pair)
+		}
+		rates[currency] = rate
+	}
+	return rates, nil
+}
+
+// DailyInterestMicros returns one day of interest on balance (in minor units)
+// at an annual rate of rateBps, expressed in micro-units and rounded half-to-even.
+// Non-positive balances earn no interest.
+func DailyInterestMicros(balance int64, rateBps int64) int64 {
+	if balance <= 0 || rateBps <= 0 {
+		return 0
+	}
+
+	num := new(big.Int).Mul(big.NewInt(balance), big.NewInt(rateBps))
+	num.Mul(num, big.NewInt(MicrosPerUnit))
+	den := big.NewInt(basisPointsPerUnit * daysPerYear)
+
+	return roundHalfEven(num, den).Int64()
+}
+
+// MicrosToUnits converts accrued micro-units into whole minor units, rounded half-to-even.
+func MicrosToUnits(micros int64) int64 {
+	return roundHalfEven(big.NewInt(micros), big.NewInt(MicrosPerUnit)).Int64()
+}
+
+// roundHalfEven divides num by den (den > 0) and rounds the quotient to the
+// nearest integer, breaking ties towards the even neighbour (banker's rounding).
+func roundHalfEven(num, den *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// Compare twice the remainder's magnitude against the divisor
+	twiceR := new(big.Int).Abs(r)
+	twiceR.Lsh(twiceR, 1)
+
+	step := int64(1)
+	if num.Sign() < 0 {
+		step = -1
+	}
+
+	switch twiceR.Cmp(den) {
+	case 1:
+		q.Add(q, big.NewInt(step))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(step))
+		}
+	}
+	return q
+}
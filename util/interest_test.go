@@ -0,0 +1,58 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseInterestRates tests parsing of the per-currency rate configuration
+func TestParseInterestRates(t *testing.T) {
+	rates, err := ParseInterestRates("USD=250, eur=150,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{USD: 250, EUR: 150}, rates)
+
+	rates, err = ParseInterestRates("")
+	require.NoError(t, err)
+	require.Empty(t, rates)
+
+	_, err = ParseInterestRates("USD:250")
+	require.Error(t, err)
+
+	_, err = ParseInterestRates("XYZ=250")
+	require.Error(t, err)
+
+	_, err = ParseInterestRates("USD=-1")
+	require.Error(t, err)
+}
+
+// TestDailyInterestMicros tests the daily accrual calculation and its rounding
+func TestDailyInterestMicros(t *testing.T) {
+	// 100.00 at 3.65% for one day is exactly 1 cent
+	require.Equal(t, int64(MicrosPerUnit), DailyInterestMicros(10_000, 365))
+
+	// 1 cent at 1bp for one day: 1e6 / 3.65e6 = 0.27 -> 0
+	require.Equal(t, int64(0), DailyInterestMicros(1, 1))
+
+	// 73 cents at 1bp: 73e6 / 3.65e6 = 20 exactly
+	require.Equal(t, int64(20), DailyInterestMicros(73, 1))
+
+	// Non-positive balances and rates earn nothing
+	require.Equal(t, int64(0), DailyInterestMicros(0, 250))
+	require.Equal(t, int64(0), DailyInterestMicros(-10_000, 250))
+	require.Equal(t, int64(0), DailyInterestMicros(10_000, 0))
+
+	// Same inputs always produce the same result
+	require.Equal(t, DailyInterestMicros(123_456_789, 275), DailyInterestMicros(123_456_789, 275))
+}
+
+// TestMicrosToUnits tests banker's rounding when posting accrued interest
+func TestMicrosToUnits(t *testing.T) {
+	require.Equal(t, int64(0), MicrosToUnits(499_999))
+	require.Equal(t, int64(0), MicrosToUnits(500_000))
+	require.Equal(t, int64(1), MicrosToUnits(500_001))
+	require.Equal(t, int64(2), MicrosToUnits(1_500_000))
+	require.Equal(t, int64(2), MicrosToUnits(2_500_000))
+	require.Equal(t, int64(-2), MicrosToUnits(-2_500_000))
+	require.Equal(t, int64(-3), MicrosToUnits(-2_500_001))
+}
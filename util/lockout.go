@@ -0,0 +1,22 @@
+package util
+
+import "time"
+
+// LockoutDuration returns how long to lock out after the given number of consecutive
+// failures. The first `allowed` failures are free; after that the lockout starts at
+// base and doubles with every further failure, up to max.
+func LockoutDuration(failures, allowed int32, base, max time.Duration) time.Duration {
+	if failures < allowed || base <= 0 {
+		return 0
+	}
+
+	duration := base
+	for i := allowed; i < failures; i++ {
+		duration *= 2
+		if duration >= max {
+			return max
+		}
+	}
+
+	return min(duration, max)
+}
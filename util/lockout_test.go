@@ -0,0 +1,21 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	base := time.Minute
+	max := time.Hour
+
+	require.Zero(t, LockoutDuration(0, 5, base, max))
+	require.Zero(t, LockoutDuration(4, 5, base, max))
+	require.Equal(t, time.Minute, LockoutDuration(5, 5, base, max))
+	require.Equal(t, 2*time.Minute, LockoutDuration(6, 5, base, max))
+	require.Equal(t, 32*time.Minute, LockoutDuration(10, 5, base, max))
+	require.Equal(t, time.Hour, LockoutDuration(11, 5, base, max))
+	require.Equal(t, time.Hour, LockoutDuration(1000, 5, base, max))
+}
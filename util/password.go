@@ -1,14 +1,192 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password
+// Supported password hashing algorithms, as named in config and PHC strings
+const (
+	Argon2idAlgorithm = "argon2id"
+	BcryptAlgorithm   = "bcrypt"
+)
+
+// Default argon2id parameters (OWASP minimum: 19 MiB, 2 iterations, 1 thread)
+const (
+	DefaultArgon2Memory      = 19 * 1024
+	DefaultArgon2Iterations  = 2
+	DefaultArgon2Parallelism = 1
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrPasswordMismatch is returned by CheckPassword when the password is wrong
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher hashes new passwords with one algorithm and set of parameters
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password, naming its algorithm and parameters
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether a stored hash was made with another algorithm or parameters
+	NeedsRehash(hashedPassword string) bool
+}
+
+// NewPasswordHasher returns the hasher for the configured algorithm, argon2id by default
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHasher {
+	case "", Argon2idAlgorithm:
+		hasher := DefaultArgon2idHasher()
+		if config.Argon2Memory > 0 {
+			hasher.Memory = config.Argon2Memory
+		}
+		if config.Argon2Iterations > 0 {
+			hasher.Iterations = config.Argon2Iterations
+		}
+		if config.Argon2Threads > 0 {
+			hasher.Parallelism = config.Argon2Threads
+		}
+		return hasher, nil
+	case BcryptAlgorithm:
+		cost := config.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", cost)
+		}
+		return BcryptHasher{Cost: cost}, nil
+	default:
+		return nil, fmt.Errorf("unsupported password hasher %q", config.PasswordHasher)
+	}
+}
+
+// HashPassword returns the argon2id hash of the password with the default parameters
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return DefaultArgon2idHasher().Hash(password)
+}
+
+// CheckPassword checks if the provided password is correct or not. The algorithm is
+// taken from the hash, so passwords hashed by any supported hasher can be checked.
+func CheckPassword(password string, hashedPassword string) error {
+	switch {
+	case strings.HasPrefix(hashedPassword, "$"+Argon2idAlgorithm+"$"):
+		return checkArgon2id(password, hashedPassword)
+	case isBcryptHash(hashedPassword):
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	default:
+		return errors.New("unsupported password hash format")
+	}
+}
+
+// ---------------------------
+// argon2id
+// ---------------------------
+
+// Argon2idHasher hashes passwords with argon2id into PHC strings of the form
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultArgon2idHasher returns an argon2id hasher with the default parameters
+func DefaultArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{
+		Memory:      DefaultArgon2Memory,
+		Iterations:  DefaultArgon2Iterations,
+		Parallelism: DefaultArgon2Parallelism,
+	}
+}
+
+// Hash returns the PHC encoded argon2id hash of the password under a random salt
+func (hasher Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, hasher.Iterations, hasher.Memory, hasher.Parallelism, argon2KeyLength)
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2idAlgorithm, argon2.Version, hasher.Memory, hasher.Iterations, hasher.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// NeedsRehash reports whether the hash is not argon2id with this hasher's parameters
+func (hasher Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, _, _, err := decodeArgon2id(hashedPassword)
+	return err != nil || params != hasher
+}
+
+// checkArgon2id recomputes the key with the hash's own parameters and salt
+func checkArgon2id(password, hashedPassword string) error {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// decodeArgon2id parses an argon2id PHC string into its parameters, salt and key
+func decodeArgon2id(hashedPassword string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != Argon2idAlgorithm {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2id key")
+	}
+
+	return params, salt, key, nil
+}
+
+// ---------------------------
+// bcrypt
+// ---------------------------
+
+// BcryptHasher hashes passwords with bcrypt, kept for existing hashes and legacy setups
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash returns the bcrypt hash of the password
+func (hasher BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.Cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -16,7 +194,15 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
-func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// NeedsRehash reports whether the hash is not bcrypt at this hasher's cost
+func (hasher BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != hasher.Cost
+}
+
+// isBcryptHash reports whether the hash is in bcrypt's $2a$/$2b$/$2y$ format
+func isBcryptHash(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
 }
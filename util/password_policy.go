@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultPasswordMinLength applies when PASSWORD_MIN_LENGTH is not configured
+const DefaultPasswordMinLength = 8
+
+// passwordMaxLength caps passwords so hashing stays cheap (and within bcrypt's 72 bytes)
+const passwordMaxLength = 72
+
+// Password policy violations, safe to show to the user
+var (
+	ErrPasswordTooShort   = errors.New("password is too short")
+	ErrPasswordTooLong    = fmt.Errorf("password must be at most %d bytes", passwordMaxLength)
+	ErrPasswordBreached   = errors.New("password appears in a list of breached passwords; choose another")
+	ErrPasswordIsUsername = errors.New("password must not be the username")
+)
+
+// PasswordPolicy decides which new passwords are acceptable
+type PasswordPolicy struct {
+	MinLength int
+	breached  map[string]struct{}
+}
+
+// NewPasswordPolicy builds the policy from config, loading the breached password list
+// (one password per line, # for comments) when BREACHED_PASSWORDS_FILE is set
+func NewPasswordPolicy(config Config) (PasswordPolicy, error) {
+	policy := PasswordPolicy{
+		MinLength: config.PasswordMinLength,
+		breached:  map[string]struct{}{},
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = DefaultPasswordMinLength
+	}
+
+	if config.BreachedPasswords == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(config.BreachedPasswords)
+	if err != nil {
+		return PasswordPolicy{}, fmt.Errorf("cannot open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return PasswordPolicy{}, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+
+	return policy, nil
+}
+
+// Validate returns the first rule the new password breaks, or nil
+func (policy PasswordPolicy) Validate(username, password string) error {
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrPasswordTooShort, policy.MinLength)
+	}
+	if len(password) > passwordMaxLength {
+		return ErrPasswordTooLong
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return ErrPasswordIsUsername
+	}
+	if _, ok := policy.breached[strings.ToLower(password)]; ok {
+		return ErrPasswordBreached
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(file, []byte("# common passwords\n\npassword123\n  Qwerty12345  \n"), 0o600))
+
+	policy, err := NewPasswordPolicy(Config{BreachedPasswords: file})
+	require.NoError(t, err)
+	require.Equal(t, DefaultPasswordMinLength, policy.MinLength)
+
+	testCases := []struct {
+		name     string
+		username string
+		password string
+		err      error
+	}{
+		{name: "OK", username: "alice", password: "correct horse battery"},
+		{name: "TooShort", username: "alice", password: "short", err: ErrPasswordTooShort},
+		{name: "TooLong", username: "alice", password: strings.Repeat("a", 73), err: ErrPasswordTooLong},
+		{name: "IsUsername", username: "alice12345", password: "ALICE12345", err: ErrPasswordIsUsername},
+		{name: "Breached", username: "alice", password: "Password123", err: ErrPasswordBreached},
+		{name: "BreachedTrimmed", username: "alice", password: "qwerty12345", err: ErrPasswordBreached},
+		{name: "CommentIsNotAPassword", username: "alice", password: "# common passwords"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.username, tc.password)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestPasswordPolicyMissingFile(t *testing.T) {
+	_, err := NewPasswordPolicy(Config{BreachedPasswords: filepath.Join(t.TempDir(), "missing.txt")})
+	require.Error(t, err)
+}
+
+func TestPasswordPolicyShippedList(t *testing.T) {
+	policy, err := NewPasswordPolicy(Config{PasswordMinLength: 1, BreachedPasswords: "../breached_passwords.txt"})
+	require.NoError(t, err)
+	require.ErrorIs(t, policy.Validate("alice", "password123"), ErrPasswordBreached)
+}
@@ -1,10 +1,11 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
-	"golang.org/x/crypto/bcrypt" // Used for password hashing with bcrypt
+	"golang.org/x/crypto/bcrypt" // Used for legacy bcrypt hashes
 )
 
 // TestPassword tests the HashPassword and CheckPassword functions
@@ -12,12 +13,12 @@ func TestPassword(t *testing.T) {
 	// Generate a random string to use as password
 	password := RandomString(6)
 
-	// Hash the password with bcrypt
+	// Hash the password with argon2id
 	hashedPassword1, err := HashPassword(password)
 	require.NoError(t, err, "Failed to hash password") // Assert no errors during hashing
 
-	// Assert that the hashed password is not empty
-	require.NotEmpty(t, hashedPassword1, "Empty hashed password")
+	// Assert that the hash is a PHC string naming the algorithm and parameters
+	require.True(t, strings.HasPrefix(hashedPassword1, "$argon2id$v=19$m=19456,t=2,p=1$"), hashedPassword1)
 
 	// Check if the original password matches the hashed password
 	err = CheckPassword(password, hashedPassword1)
@@ -28,15 +29,64 @@ func TestPassword(t *testing.T) {
 
 	// Check if the wrong password matches the original hashed password (should fail)
 	err = CheckPassword(wrongPassword, hashedPassword1)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error(), "Expected bcrypt error for wrong password") // Assert specific error for mismatch
+	require.ErrorIs(t, err, ErrPasswordMismatch, "Expected mismatch error for wrong password")
 
-	// Hash the password again (should result in a different hash due to bcrypt salting)
+	// Hash the password again (should result in a different hash due to salting)
 	hashedPassword2, err := HashPassword(password)
 	require.NoError(t, err, "Failed to hash password") // Assert no errors during second hashing
 
-	// Assert that the second hashed password is not empty
-	require.NotEmpty(t, hashedPassword2, "Empty hashed password")
-
-	// Assert that the two hashed passwords are different due to bcrypt salting
+	// Assert that the two hashed passwords are different due to salting
 	require.NotEqual(t, hashedPassword1, hashedPassword2, "Hashed passwords should be different")
 }
+
+// TestCheckLegacyBcryptPassword tests that bcrypt hashes from before argon2id still verify
+func TestCheckLegacyBcryptPassword(t *testing.T) {
+	password := RandomString(6)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	require.NoError(t, CheckPassword(password, string(hashedPassword)))
+	require.ErrorIs(t, CheckPassword(RandomString(6), string(hashedPassword)), ErrPasswordMismatch)
+	require.Error(t, CheckPassword(password, "plaintext"))
+}
+
+// TestNewPasswordHasher tests hasher selection from config and rehash detection
+func TestNewPasswordHasher(t *testing.T) {
+	password := RandomString(6)
+
+	argon, err := NewPasswordHasher(Config{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultArgon2idHasher(), argon)
+
+	stronger, err := NewPasswordHasher(Config{Argon2Memory: 32 * 1024, Argon2Iterations: 3})
+	require.NoError(t, err)
+
+	legacy, err := NewPasswordHasher(Config{PasswordHasher: BcryptAlgorithm, BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+
+	argonHash, err := argon.Hash(password)
+	require.NoError(t, err)
+	strongerHash, err := stronger.Hash(password)
+	require.NoError(t, err)
+	require.Contains(t, strongerHash, "$m=32768,t=3,p=1$")
+	bcryptHash, err := legacy.Hash(password)
+	require.NoError(t, err)
+
+	// Every hash verifies, whichever hasher made it
+	for _, hash := range []string{argonHash, strongerHash, bcryptHash} {
+		require.NoError(t, CheckPassword(password, hash))
+	}
+
+	// Hashes from another algorithm or other parameters need a rehash
+	require.False(t, argon.NeedsRehash(argonHash))
+	require.True(t, argon.NeedsRehash(strongerHash))
+	require.True(t, argon.NeedsRehash(bcryptHash))
+	require.False(t, legacy.NeedsRehash(bcryptHash))
+	require.True(t, legacy.NeedsRehash(argonHash))
+
+	_, err = NewPasswordHasher(Config{PasswordHasher: "md5"})
+	require.Error(t, err)
+	_, err = NewPasswordHasher(Config{PasswordHasher: BcryptAlgorithm, BcryptCost: 100})
+	require.Error(t, err)
+}
@@ -0,0 +1,22 @@
+package util
+
+// constants for all API key scopes
+
+const (
+	ScopeAccountsRead      = "accounts:read"
+	ScopeAccountsWrite     = "accounts:write"
+	ScopeTransfersWrite    = "transfers:write"
+	ScopeWebhooksRead      = "webhooks:read"
+	ScopeProfileRead       = "profile:read"
+	ScopeNotificationsRead = "notifications:read"
+)
+
+// IsSupportedScope returns true if API keys can be granted the scope
+func IsSupportedScope(scope string) bool {
+	switch scope {
+	case ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersWrite, ScopeWebhooksRead,
+		ScopeProfileRead, ScopeNotificationsRead:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// secureTokenBytes is the entropy of tokens sent to users (256 bits)
+const secureTokenBytes = 32
+
+// GenerateSecureToken returns a random URL-safe token suitable for emailed links
+func GenerateSecureToken() (string, error) {
+	buf := make([]byte, secureTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashSecureToken returns the hex SHA-256 of a token, which is what gets stored.
+// Tokens are high-entropy, so a fast unsalted hash is enough to make a leaked table useless.
+func HashSecureToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
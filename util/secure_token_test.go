@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureToken(t *testing.T) {
+	token1, err := GenerateSecureToken()
+	require.NoError(t, err)
+	require.Len(t, token1, 43)
+
+	token2, err := GenerateSecureToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token1, token2)
+
+	hash := HashSecureToken(token1)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashSecureToken(token1))
+	require.NotEqual(t, hash, HashSecureToken(token2))
+}
@@ -0,0 +1,21 @@
+package util
+
+// Bounds on the length of usernames
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 64
+)
+
+// IsValidUsername returns true if the username is MinUsernameLength to MaxUsernameLength
+// ASCII letters and digits
+func IsValidUsername(username string) bool {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return false
+	}
+	for _, r := range username {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
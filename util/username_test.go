@@ -0,0 +1,20 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidUsername(t *testing.T) {
+	require.True(t, IsValidUsername("nahasat"))
+	require.True(t, IsValidUsername("Abc123"))
+	require.True(t, IsValidUsername(strings.Repeat("a", MaxUsernameLength)))
+
+	require.False(t, IsValidUsername("ab"))
+	require.False(t, IsValidUsername(strings.Repeat("a", MaxUsernameLength+1)))
+	require.False(t, IsValidUsername("with space"))
+	require.False(t, IsValidUsername("dash-ed"))
+	require.False(t, IsValidUsername("ünïcode"))
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+)
+
+// Delivery statuses stored in webhook_deliveries.status
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// Body is the JSON document POSTed to subscribers
+type Body struct {
+	ID        int64           `json:"id"`         // Event ID; the same event is never sent twice to one endpoint with a different ID
+	Type      string          `json:"type"`       // Event type
+	CreatedAt time.Time       `json:"created_at"` // When the delivery was queued
+	Data      json.RawMessage `json:"data"`       // Event payload
+}
+
+// NewRequest builds the signed HTTP request for a delivery
+func NewRequest(ctx context.Context, subscription db.WebhookSubscription, delivery db.WebhookDelivery, now time.Time) (*http.Request, error) {
+	body, err := json.Marshal(Body{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, now, body))
+	return req, nil
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/event"
+)
+
+// IsSupportedEventType returns true if webhooks can subscribe to the event type
+func IsSupportedEventType(eventType string) bool {
+	switch eventType {
+	case db.EventTransferCreated, db.EventAccountCreated, db.EventAccountClosed:
+		return true
+	}
+	return false
+}
+
+// Dispatcher is an event.Publisher that queues a webhook delivery for every
+// active subscription of the users an event concerns. Queuing is idempotent,
+// so events relayed more than once produce a single delivery per subscription.
+type Dispatcher struct {
+	store db.Store
+}
+
+// NewDispatcher creates a webhook dispatcher
+func NewDispatcher(store db.Store) event.Publisher {
+	return &Dispatcher{store: store}
+}
+
+// Publish queues deliveries for the event
+func (dispatcher *Dispatcher) Publish(ctx context.Context, e event.Event) error {
+	if !IsSupportedEventType(e.Type) {
+		return nil
+	}
+
+	// 1. Work out whose subscriptions the event goes to
+	owners, err := dispatcher.owners(ctx, e)
+	if err != nil {
+		return err
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	// 2. Find their active subscriptions for this event type
+	subscriptions, err := dispatcher.store.ListActiveWebhookSubscriptionsForEvent(ctx, db.ListActiveWebhookSubscriptionsForEventParams{
+		Owners:    owners,
+		EventType: e.Type,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 3. Queue one delivery per subscription
+	for _, subscription := range subscriptions {
+		_, err := dispatcher.store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			SubscriptionID: subscription.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        e.Payload,
+		})
+		if err != nil {
+			return fmt.Errorf("subscription %d: %w", subscription.ID, err)
+		}
+	}
+	return nil
+}
+
+// owners returns the distinct usernames an event concerns: the account owner for
+// account events, and the owners of both accounts for transfers
+func (dispatcher *Dispatcher) owners(ctx context.Context, e event.Event) ([]string, error) {
+	switch e.Type {
+	case db.EventAccountCreated, db.EventAccountClosed:
+		var account db.Account
+		if err := json.Unmarshal(e.Payload, &account); err != nil {
+			return nil, fmt.Errorf("cannot decode %s payload: %w", e.Type, err)
+		}
+		return []string{account.Owner}, nil
+
+	case db.EventTransferCreated:
+		var transfer db.Transfer
+		if err := json.Unmarshal(e.Payload, &transfer); err != nil {
+			return nil, fmt.Errorf("cannot decode %s payload: %w", e.Type, err)
+		}
+
+		var owners []string
+		for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+			// Read from the primary: the transfer was just made and may not be on a replica yet
+			account, err := dispatcher.store.GetAccount(db.WithPrimary(ctx), accountID)
+			if err != nil {
+				// The account may have been closed since; nobody left to notify
+				if errors.Is(err, db.ErrRecordNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			if len(owners) == 0 || owners[0] != account.Owner {
+				owners = append(owners, account.Owner)
+			}
+		}
+		return owners, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/event"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherTransferCreated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	transfer := db.Transfer{ID: 3, FromAccountID: 1, ToAccountID: 2, Amount: 10}
+	payload, err := json.Marshal(transfer)
+	require.NoError(t, err)
+	e := event.Event{ID: 77, Type: db.EventTransferCreated, AggregateID: "3", Payload: payload}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(db.Account{ID: 1, Owner: "alice"}, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(2))).Times(1).Return(db.Account{ID: 2, Owner: "merchant"}, nil)
+	store.EXPECT().
+		ListActiveWebhookSubscriptionsForEvent(gomock.Any(), gomock.Eq(db.ListActiveWebhookSubscriptionsForEventParams{
+			Owners:    []string{"alice", "merchant"},
+			EventType: db.EventTransferCreated,
+		})).
+		Times(1).
+		Return([]db.WebhookSubscription{{ID: 9, Owner: "merchant"}}, nil)
+	store.EXPECT().
+		CreateWebhookDelivery(gomock.Any(), gomock.Eq(db.CreateWebhookDeliveryParams{
+			SubscriptionID: 9,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        e.Payload,
+		})).
+		Times(1).
+		Return(int64(1), nil)
+
+	require.NoError(t, NewDispatcher(store).Publish(context.Background(), e))
+}
+
+func TestDispatcherAccountClosed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	payload, err := json.Marshal(db.Account{ID: 5, Owner: "bob"})
+	require.NoError(t, err)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListActiveWebhookSubscriptionsForEvent(gomock.Any(), gomock.Eq(db.ListActiveWebhookSubscriptionsForEventParams{
+			Owners:    []string{"bob"},
+			EventType: db.EventAccountClosed,
+		})).
+		Times(1).
+		Return([]db.WebhookSubscription{}, nil)
+	store.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+
+	err = NewDispatcher(store).Publish(context.Background(), event.Event{ID: 1, Type: db.EventAccountClosed, Payload: payload})
+	require.NoError(t, err)
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(secret, secretPrefix))
+
+	body := []byte(`{"type":"transfer.created"}`)
+	now := time.Now()
+	header := Sign(secret, now, body)
+
+	require.NoError(t, Verify(secret, header, body, time.Minute, now))
+
+	// Tampered body, wrong secret and malformed headers are rejected
+	require.ErrorIs(t, Verify(secret, header, []byte(`{}`), time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("whsec_other", header, body, time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, "v1=abc", body, time.Minute, now), ErrInvalidSignature)
+
+	// Old signatures are rejected to prevent replays
+	require.ErrorIs(t, Verify(secret, header, body, time.Minute, now.Add(2*time.Minute)), ErrExpiredSignature)
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Errors explaining why a webhook URL is refused
+var (
+	ErrInsecureURL    = errors.New("webhook URL must use https")
+	ErrForbiddenHost  = errors.New("webhook host must not resolve to a private, loopback or link-local address")
+	ErrRedirect       = errors.New("webhook endpoints must not redirect")
+	ErrUnresolvedHost = errors.New("webhook host cannot be resolved")
+)
+
+// forbiddenPrefixes are the special-purpose ranges netip has no predicate for
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which maps onto any IPv4 address
+}
+
+// Resolver looks up the addresses of a host; *net.Resolver implements it
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// IsSecureURL reports whether rawURL is an absolute https URL, the only kind webhooks
+// are sent to
+func IsSecureURL(rawURL string) bool {
+	target, err := url.Parse(rawURL)
+	return err == nil && target.Scheme == "https" && target.Hostname() != "" && target.User == nil
+}
+
+// IsPublicAddr reports whether webhooks may be sent to addr: it must not be loopback,
+// private, link-local, multicast, unspecified or another special-purpose address, which
+// would let a subscriber make the server call its own network
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL checks rawURL is an https URL whose host only resolves to public addresses.
+// Delivery checks every address again as it dials (see NewClient), since DNS answers
+// may change after the webhook is registered.
+func CheckURL(ctx context.Context, resolver Resolver, rawURL string) error {
+	if !IsSecureURL(rawURL) {
+		return ErrInsecureURL
+	}
+	target, _ := url.Parse(rawURL)
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", target.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return ErrUnresolvedHost
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return ErrForbiddenHost
+		}
+	}
+	return nil
+}
+
+// NewClient returns the HTTP client deliveries are sent with. It only connects to public
+// addresses, checked on the address actually dialled so DNS rebinding can't get around
+// it, never goes through a proxy and refuses to follow redirects.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrForbiddenHost, err)
+			}
+			if !IsPublicAddr(addrPort.Addr()) {
+				return ErrForbiddenHost
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return ErrRedirect
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/mail"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+const (
+	defaultVerifyEmailBatchSize = 50
+	defaultVerifyEmailInterval  = 5 * time.Second
+	defaultVerifyEmailDuration  = 24 * time.Hour
+)
+
+// EmailVerificationWorker emails verification links to newly registered users
+type EmailVerificationWorker struct {
+	store     db.Store
+	mailer    mail.Mailer
+	verifyURL string
+	duration  time.Duration
+	batchSize int64
+	interval  time.Duration
+}
+
+// NewEmailVerificationWorker creates a worker sending verification emails through mailer
+func NewEmailVerificationWorker(config util.Config, store db.Store, mailer mail.Mailer) *EmailVerificationWorker {
+	duration := config.VerifyEmailDuration
+	if duration <= 0 {
+		duration = defaultVerifyEmailDuration
+	}
+
+	return &EmailVerificationWorker{
+		store:     store,
+		mailer:    mailer,
+		verifyURL: config.VerifyEmailURL,
+		duration:  duration,
+		batchSize: defaultVerifyEmailBatchSize,
+		interval:  defaultVerifyEmailInterval,
+	}
+}
+
+// Run sends pending verification emails until ctx is cancelled
+func (worker *EmailVerificationWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := worker.RunOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "email verification worker failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of verification emails and returns how many were sent
+func (worker *EmailVerificationWorker) RunOnce(ctx context.Context) (int, error) {
+	return worker.store.SendEmailVerificationsTx(ctx, worker.batchSize, worker.send)
+}
+
+// send emails a fresh token for verification; only the token's hash is kept
+func (worker *EmailVerificationWorker) send(ctx context.Context, verification db.EmailVerification) (db.SentEmailVerification, error) {
+	token, err := util.GenerateSecureToken()
+	if err != nil {
+		return db.SentEmailVerification{}, err
+	}
+
+	err = worker.mailer.Send(ctx, mail.Message{
+		To:      verification.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm this is your email address. The link expires in %s.\n\n%s\n",
+			verification.Username, worker.duration, worker.link(token),
+		),
+	})
+	if err != nil {
+		return db.SentEmailVerification{}, err
+	}
+
+	return db.SentEmailVerification{
+		TokenHash: util.HashSecureToken(token),
+		ExpiresAt: time.Now().UTC().Add(worker.duration),
+	}, nil
+}
+
+// link builds the verification link, or just the token when no VERIFY_EMAIL_URL is configured
+func (worker *EmailVerificationWorker) link(token string) string {
+	if worker.verifyURL == "" {
+		return "Verification token: " + token
+	}
+	return worker.verifyURL + "?token=" + url.QueryEscape(token)
+}
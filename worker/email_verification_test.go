@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/mail"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// failingMailer is a Mailer that always fails
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mail.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestEmailVerificationWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	verification := db.EmailVerification{ID: 1, Username: util.RandomOwner(), Email: util.RandomEmail()}
+	config := util.Config{VerifyEmailURL: "http://localhost:8080/users/verify_email", VerifyEmailDuration: time.Hour}
+	mailer := mail.NewMemoryMailer()
+
+	var sent db.SentEmailVerification
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		SendEmailVerificationsTx(gomock.Any(), gomock.Eq(int64(defaultVerifyEmailBatchSize)), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, limit int64, send func(context.Context, db.EmailVerification) (db.SentEmailVerification, error)) (int, error) {
+			var err error
+			sent, err = send(ctx, verification)
+			require.NoError(t, err)
+			return 1, nil
+		})
+
+	n, err := NewEmailVerificationWorker(config, store, mailer).RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.WithinDuration(t, time.Now().UTC().Add(time.Hour), sent.ExpiresAt, time.Second)
+
+	// The emailed link carries the token whose hash is stored
+	messages := mailer.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, verification.Email, messages[0].To)
+
+	start := strings.Index(messages[0].Body, config.VerifyEmailURL)
+	require.GreaterOrEqual(t, start, 0)
+	link, err := url.Parse(strings.Fields(messages[0].Body[start:])[0])
+	require.NoError(t, err)
+	require.Equal(t, sent.TokenHash, util.HashSecureToken(link.Query().Get("token")))
+}
+
+func TestEmailVerificationWorkerMailerFailure(t *testing.T) {
+	worker := NewEmailVerificationWorker(util.Config{}, nil, failingMailer{})
+	require.Equal(t, defaultVerifyEmailDuration, worker.duration)
+
+	_, err := worker.send(context.Background(), db.EmailVerification{Email: util.RandomEmail()})
+	require.Error(t, err)
+}
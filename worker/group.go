@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State describes one background job run by a Group
+type State struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	Error     string     `json:"error,omitempty"` // Why it stopped, if it panicked
+}
+
+// Group runs background jobs, keeping track of which are still running so readiness
+// checks can report them and shutdown can wait for them
+type Group struct {
+	mu     sync.Mutex
+	states map[string]*State
+	wg     sync.WaitGroup
+}
+
+// NewGroup creates an empty Group
+func NewGroup() *Group {
+	return &Group{states: make(map[string]*State)}
+}
+
+// Go runs job in its own goroutine under the given name. The job should return once
+// ctx is cancelled. A panic stops only that job; it is logged and kept in its State.
+func (group *Group) Go(ctx context.Context, name string, job func(ctx context.Context)) {
+	state := &State{Name: name, Running: true, StartedAt: time.Now()}
+	group.mu.Lock()
+	group.states[name] = state
+	group.mu.Unlock()
+
+	group.wg.Add(1)
+	go func() {
+		defer group.wg.Done()
+
+		var cause string
+		defer func() {
+			if r := recover(); r != nil {
+				cause = fmt.Sprint("panic: ", r)
+				slog.ErrorContext(ctx, "worker panicked", "worker", name, "panic", r)
+			}
+
+			now := time.Now()
+			group.mu.Lock()
+			state.Running = false
+			state.StoppedAt = &now
+			state.Error = cause
+			group.mu.Unlock()
+		}()
+
+		job(ctx)
+	}()
+}
+
+// States returns a snapshot of every job's state, ordered by name
+func (group *Group) States() []State {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+
+	states := make([]State, 0, len(group.states))
+	for _, state := range group.states {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Wait blocks until every job has returned or ctx is done, whichever comes first
+func (group *Group) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		group.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	group := NewGroup()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// One job runs until cancelled, the other panics straight away
+	group.Go(ctx, "ticker", func(ctx context.Context) { <-ctx.Done() })
+	group.Go(ctx, "broken", func(ctx context.Context) { panic("boom") })
+
+	require.Eventually(t, func() bool {
+		return !group.States()[0].Running
+	}, time.Second, 10*time.Millisecond)
+
+	states := group.States()
+	require.Len(t, states, 2)
+	require.Equal(t, "broken", states[0].Name)
+	require.Contains(t, states[0].Error, "boom")
+	require.NotNil(t, states[0].StoppedAt)
+	require.Equal(t, "ticker", states[1].Name)
+	require.True(t, states[1].Running)
+	require.Nil(t, states[1].StoppedAt)
+
+	// Wait returns once every job has stopped
+	cancel()
+	require.NoError(t, group.Wait(context.Background()))
+	for _, state := range group.States() {
+		require.False(t, state.Running)
+	}
+}
+
+func TestGroupWaitTimeout(t *testing.T) {
+	group := NewGroup()
+	release := make(chan struct{})
+	defer close(release)
+	group.Go(context.Background(), "stuck", func(ctx context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, group.Wait(ctx), context.DeadlineExceeded)
+}
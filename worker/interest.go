@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+// defaultInterestInterval is how often the interest worker checks for work.
+// Accrual and posting are idempotent, so running more often than daily is safe.
+const defaultInterestInterval = time.Hour
+
+// InterestWorker accrues daily interest on savings accounts and posts it monthly
+type InterestWorker struct {
+	store      db.Store
+	rates      map[string]int64 // Annual rate in basis points per currency
+	houseOwner string           // Owner of the house accounts interest is paid from
+	interval   time.Duration
+	now        func() time.Time
+}
+
+// NewInterestWorker creates an interest worker from the application configuration
+func NewInterestWorker(config util.Config, store db.Store) (*InterestWorker, error) {
+	rates, err := util.ParseInterestRates(config.InterestRates)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse interest rates: %w", err)
+	}
+
+	return &InterestWorker{
+		store:      store,
+		rates:      rates,
+		houseOwner: config.InterestHouseOwner,
+		interval:   defaultInterestInterval,
+		now:        time.Now,
+	}, nil
+}
+
+// Run processes interest on every tick until ctx is cancelled
+func (worker *InterestWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := worker.RunOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "interest worker failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce accrues interest for every day up to yesterday that hasn't been accrued yet
+// and posts everything accrued before the current month. Days missed while the worker
+// was down are caught up, starting from the day after the last completely accrued day, or
+// the day the oldest savings account was opened if there is none.
+func (worker *InterestWorker) RunOnce(ctx context.Context) error {
+	today := startOfDay(worker.now())
+
+	currencies := make([]string, 0, len(worker.rates))
+	for currency := range worker.rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	next, err := worker.store.GetNextAccrualDate(ctx, db.GetNextAccrualDateParams{
+		Fallback:   today,
+		Currencies: currencies,
+	})
+	if err != nil {
+		return fmt.Errorf("accrue: %w", err)
+	}
+	for day := startOfDay(next); day.Before(today); day = day.AddDate(0, 0, 1) {
+		if err := worker.AccrueDay(ctx, day); err != nil {
+			return fmt.Errorf("accrue %s: %w", day.Format(time.DateOnly), err)
+		}
+	}
+
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if err := worker.PostBefore(ctx, monthStart); err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	return nil
+}
+
+// AccrueDay records one day of interest for every savings account with a positive
+// balance, computed on the balance at the end of that day, then marks the day done.
+// Accounts that were already accrued for the day are skipped, so a day can be accrued
+// again safely.
+func (worker *InterestWorker) AccrueDay(ctx context.Context, day time.Time) error {
+	day = startOfDay(day)
+
+	// 1. Reconstruct end-of-day balances from the ledger
+	balances, err := worker.store.ListSavingsBalancesAt(ctx, day.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	// 2. Record one accrual per account with a configured rate
+	for _, account := range balances {
+		rate, ok := worker.rates[account.Currency]
+		if !ok {
+			continue
+		}
+
+		_, err := worker.store.CreateInterestAccrual(ctx, db.CreateInterestAccrualParams{
+			AccountID:    account.ID,
+			AccrualDate:  day,
+			Balance:      account.Balance,
+			RateBps:      rate,
+			AmountMicros: util.DailyInterestMicros(account.Balance, rate),
+		})
+		if err != nil {
+			return fmt.Errorf("account %d: %w", account.ID, err)
+		}
+	}
+
+	// 3. Remember the day, so catching up starts after it
+	return worker.store.CreateInterestAccrualDay(ctx, day)
+}
+
+// PostBefore posts all unposted interest accrued before the given day, one
+// transaction per account, paid from the house account of the same currency.
+// An account that can't be posted is logged and skipped, so it doesn't hold up the
+// others; its interest stays unposted and is retried on the next run.
+func (worker *InterestWorker) PostBefore(ctx context.Context, before time.Time) error {
+	before = startOfDay(before)
+
+	accounts, err := worker.store.ListAccountsWithUnpostedInterest(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	houseAccounts := make(map[string]int64) // 0 when the currency's house account can't be found
+	for _, account := range accounts {
+		houseAccountID, ok := houseAccounts[account.Currency]
+		if !ok {
+			house, err := worker.store.GetAccountByOwner(ctx, db.GetAccountByOwnerParams{
+				Owner:    worker.houseOwner,
+				Currency: account.Currency,
+				Type:     util.CheckingAccount,
+			})
+			if err != nil {
+				if errors.Is(err, db.ErrRecordNotFound) {
+					err = fmt.Errorf("house interest account for %s not found", account.Currency)
+				}
+				slog.ErrorContext(ctx, "cannot post interest", "currency", account.Currency, "error", err)
+			}
+			houseAccountID = house.ID
+			houseAccounts[account.Currency] = houseAccountID
+		}
+		if houseAccountID == 0 {
+			continue
+		}
+
+		_, err := worker.store.PostInterestTx(ctx, db.PostInterestTxParams{
+			AccountID:      account.ID,
+			HouseAccountID: houseAccountID,
+			Before:         before,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "cannot post interest", "account_id", account.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// startOfDay truncates t to midnight UTC
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
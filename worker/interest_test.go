@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestInterestWorker creates an interest worker with a fixed clock
+func newTestInterestWorker(t *testing.T, store db.Store, now time.Time) *InterestWorker {
+	worker, err := NewInterestWorker(util.Config{
+		InterestRates:      "USD=365",
+		InterestHouseOwner: "house",
+	}, store)
+	require.NoError(t, err)
+	worker.now = func() time.Time { return now }
+	return worker
+}
+
+func TestInterestWorkerAccrueDay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	day := time.Date(2026, time.March, 14, 0, 0, 0, 0, time.UTC)
+
+	store.EXPECT().
+		ListSavingsBalancesAt(gomock.Any(), gomock.Eq(day.AddDate(0, 0, 1))).
+		Times(1).
+		Return([]db.ListSavingsBalancesAtRow{
+			{ID: 1, Currency: util.USD, Balance: 10_000},
+			{ID: 2, Currency: util.EUR, Balance: 10_000}, // No EUR rate configured
+		}, nil)
+
+	store.EXPECT().
+		CreateInterestAccrual(gomock.Any(), gomock.Eq(db.CreateInterestAccrualParams{
+			AccountID:    1,
+			AccrualDate:  day,
+			Balance:      10_000,
+			RateBps:      365,
+			AmountMicros: util.MicrosPerUnit,
+		})).
+		Times(1).
+		Return(int64(1), nil)
+	store.EXPECT().
+		CreateInterestAccrualDay(gomock.Any(), gomock.Eq(day)).
+		Times(1).
+		Return(nil)
+
+	worker := newTestInterestWorker(t, store, day)
+	require.NoError(t, worker.AccrueDay(context.Background(), day.Add(15*time.Hour)))
+}
+
+func TestInterestWorkerPostBefore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	before := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	house := db.Account{ID: 99, Owner: "house", Currency: util.USD, Type: util.CheckingAccount}
+
+	store.EXPECT().
+		ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Eq(before)).
+		Times(1).
+		Return([]db.ListAccountsWithUnpostedInterestRow{
+			{ID: 1, Currency: util.USD},
+			{ID: 2, Currency: util.USD},
+		}, nil)
+
+	// The house account is looked up once per currency
+	store.EXPECT().
+		GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{
+			Owner:    "house",
+			Currency: util.USD,
+			Type:     util.CheckingAccount,
+		})).
+		Times(1).
+		Return(house, nil)
+
+	for _, id := range []int64{1, 2} {
+		store.EXPECT().
+			PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{
+				AccountID:      id,
+				HouseAccountID: house.ID,
+				Before:         before,
+			})).
+			Times(1).
+			Return(db.PostInterestTxResult{}, nil)
+	}
+
+	worker := newTestInterestWorker(t, store, before)
+	require.NoError(t, worker.PostBefore(context.Background(), before))
+}
+
+func TestInterestWorkerPostBeforeSkipsFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	before := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	house := db.Account{ID: 99, Owner: "house", Currency: util.USD, Type: util.CheckingAccount}
+
+	store.EXPECT().
+		ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ListAccountsWithUnpostedInterestRow{
+			{ID: 1, Currency: util.CAD},
+			{ID: 2, Currency: util.CAD},
+			{ID: 3, Currency: util.USD},
+			{ID: 4, Currency: util.USD},
+		}, nil)
+
+	// There is no CAD house account: both CAD accounts are skipped after one lookup
+	store.EXPECT().
+		GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: "house", Currency: util.CAD, Type: util.CheckingAccount})).
+		Times(1).
+		Return(db.Account{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: "house", Currency: util.USD, Type: util.CheckingAccount})).
+		Times(1).
+		Return(house, nil)
+
+	// A failing account doesn't stop the next one
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 3, HouseAccountID: house.ID, Before: before})).
+		Times(1).
+		Return(db.PostInterestTxResult{}, sql.ErrConnDone)
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 4, HouseAccountID: house.ID, Before: before})).
+		Times(1).
+		Return(db.PostInterestTxResult{}, nil)
+
+	worker := newTestInterestWorker(t, store, before)
+	require.NoError(t, worker.PostBefore(context.Background(), before))
+}
+
+func TestInterestWorkerRunOnceCatchesUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	now := time.Date(2026, time.March, 3, 9, 30, 0, 0, time.UTC)
+	today := startOfDay(now)
+	monthStart := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	// The worker was down since February 27, so four days are accrued in order
+	store.EXPECT().
+		GetNextAccrualDate(gomock.Any(), gomock.Eq(db.GetNextAccrualDateParams{
+			Fallback:   today,
+			Currencies: []string{util.USD},
+		})).
+		Times(1).
+		Return(time.Date(2026, time.February, 27, 0, 0, 0, 0, time.UTC), nil)
+
+	var calls []*gomock.Call
+	for day := 27; day <= 30; day++ {
+		asOf := time.Date(2026, time.February, day+1, 0, 0, 0, 0, time.UTC)
+		calls = append(calls, store.EXPECT().
+			ListSavingsBalancesAt(gomock.Any(), gomock.Eq(asOf)).
+			Times(1).
+			Return([]db.ListSavingsBalancesAtRow{{ID: 1, Currency: util.USD, Balance: 10_000}}, nil))
+	}
+	gomock.InOrder(calls...)
+	store.EXPECT().
+		CreateInterestAccrual(gomock.Any(), gomock.Any()).
+		Times(4).
+		Return(int64(1), nil)
+	store.EXPECT().
+		CreateInterestAccrualDay(gomock.Any(), gomock.Any()).
+		Times(4).
+		Return(nil)
+
+	store.EXPECT().
+		ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Eq(monthStart)).
+		Times(1).
+		Return([]db.ListAccountsWithUnpostedInterestRow{}, nil)
+
+	worker := newTestInterestWorker(t, store, now)
+	require.NoError(t, worker.RunOnce(context.Background()))
+}
+
+func TestInterestWorkerRunOnceUpToDate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	now := time.Date(2026, time.March, 14, 9, 30, 0, 0, time.UTC)
+
+	// Yesterday was already accrued, so there is nothing to do
+	store.EXPECT().
+		GetNextAccrualDate(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(startOfDay(now), nil)
+	store.EXPECT().
+		ListSavingsBalancesAt(gomock.Any(), gomock.Any()).
+		Times(0)
+	store.EXPECT().
+		ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ListAccountsWithUnpostedInterestRow{}, nil)
+
+	worker := newTestInterestWorker(t, store, now)
+	require.NoError(t, worker.RunOnce(context.Background()))
+}
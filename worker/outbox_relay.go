@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/event"
+)
+
+const (
+	defaultOutboxBatchSize = 100
+	defaultOutboxInterval  = time.Second
+)
+
+// OutboxRelay publishes pending outbox events through a Publisher
+type OutboxRelay struct {
+	store     db.Store
+	publisher event.Publisher
+	batchSize int64
+	interval  time.Duration
+}
+
+// NewOutboxRelay creates an outbox relay publishing through publisher
+func NewOutboxRelay(store db.Store, publisher event.Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		batchSize: defaultOutboxBatchSize,
+		interval:  defaultOutboxInterval,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled. Full batches are drained
+// back-to-back; otherwise the relay waits one interval between polls.
+func (relay *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := relay.RunOnce(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "outbox relay failed", "error", err)
+		}
+
+		if err == nil && int64(published) == relay.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes one batch of pending events and returns how many were published
+func (relay *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	return relay.store.PublishOutboxTx(ctx, relay.batchSize, func(ctx context.Context, outbox db.Outbox) error {
+		return relay.publisher.Publish(ctx, event.Event{
+			ID:          outbox.ID,
+			Type:        outbox.EventType,
+			AggregateID: outbox.AggregateID,
+			Payload:     outbox.Payload,
+			CreatedAt:   outbox.CreatedAt,
+		})
+	})
+}
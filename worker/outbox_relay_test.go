@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/event"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPublishOutboxTx makes PublishOutboxTx hand the given rows to the relay's callback,
+// stopping at the first failure like the real store does
+func stubPublishOutboxTx(store *mockdb.MockStore, rows []db.Outbox) {
+	store.EXPECT().
+		PublishOutboxTx(gomock.Any(), gomock.Eq(int64(defaultOutboxBatchSize)), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, limit int64, publish func(context.Context, db.Outbox) error) (int, error) {
+			published := 0
+			for _, row := range rows {
+				if err := publish(ctx, row); err != nil {
+					break
+				}
+				published++
+			}
+			return published, nil
+		})
+}
+
+func TestOutboxRelayRunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rows := []db.Outbox{
+		{ID: 1, EventType: db.EventAccountCreated, AggregateID: "7", Payload: json.RawMessage(`{"id":7}`)},
+		{ID: 2, EventType: db.EventTransferCreated, AggregateID: "3", Payload: json.RawMessage(`{"id":3}`)},
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	stubPublishOutboxTx(store, rows)
+
+	publisher := event.NewMemoryPublisher()
+	relay := NewOutboxRelay(store, publisher)
+
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, published)
+
+	events := publisher.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, int64(1), events[0].ID)
+	require.Equal(t, db.EventAccountCreated, events[0].Type)
+	require.Equal(t, "7", events[0].AggregateID)
+	require.JSONEq(t, `{"id":3}`, string(events[1].Payload))
+}
+
+func TestOutboxRelayPublishFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	stubPublishOutboxTx(store, []db.Outbox{{ID: 1, EventType: db.EventAccountClosed}})
+
+	publisher := event.NewMemoryPublisher()
+	publisher.FailWith(errors.New("broker unavailable"))
+	relay := NewOutboxRelay(store, publisher)
+
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, published)
+	require.Empty(t, publisher.Events())
+}
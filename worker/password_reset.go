@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/mail"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+)
+
+const (
+	defaultPasswordResetBatchSize = 50
+	defaultPasswordResetInterval  = 5 * time.Second
+	defaultResetTokenDuration     = 30 * time.Minute
+)
+
+// PasswordResetWorker emails the reset links users asked for
+type PasswordResetWorker struct {
+	store     db.Store
+	mailer    mail.Mailer
+	resetURL  string
+	duration  time.Duration
+	batchSize int64
+	interval  time.Duration
+}
+
+// NewPasswordResetWorker creates a worker sending password reset emails through mailer
+func NewPasswordResetWorker(config util.Config, store db.Store, mailer mail.Mailer) *PasswordResetWorker {
+	duration := config.ResetTokenDuration
+	if duration <= 0 {
+		duration = defaultResetTokenDuration
+	}
+
+	return &PasswordResetWorker{
+		store:     store,
+		mailer:    mailer,
+		resetURL:  config.PasswordResetURL,
+		duration:  duration,
+		batchSize: defaultPasswordResetBatchSize,
+		interval:  defaultPasswordResetInterval,
+	}
+}
+
+// Run sends pending reset emails until ctx is cancelled
+func (worker *PasswordResetWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := worker.RunOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "password reset worker failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of reset emails and returns how many were sent
+func (worker *PasswordResetWorker) RunOnce(ctx context.Context) (int, error) {
+	return worker.store.SendPasswordResetsTx(ctx, worker.batchSize, worker.send)
+}
+
+// send emails a fresh reset token; only the token's hash is kept
+func (worker *PasswordResetWorker) send(ctx context.Context, reset db.PasswordResetToken) (db.SentPasswordReset, error) {
+	token, err := util.GenerateSecureToken()
+	if err != nil {
+		return db.SentPasswordReset{}, err
+	}
+
+	err = worker.mailer.Send(ctx, mail.Message{
+		To:      reset.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and works once.\n\n%s\n\nIf you didn't ask for this, you can ignore this email.\n",
+			reset.Username, worker.duration, worker.link(token),
+		),
+	})
+	if err != nil {
+		return db.SentPasswordReset{}, err
+	}
+
+	return db.SentPasswordReset{
+		TokenHash: util.HashSecureToken(token),
+		ExpiresAt: time.Now().UTC().Add(worker.duration),
+	}, nil
+}
+
+// link builds the reset link, or just the token when no PASSWORD_RESET_URL is configured
+func (worker *PasswordResetWorker) link(token string) string {
+	if worker.resetURL == "" {
+		return "Reset token: " + token
+	}
+	return worker.resetURL + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/mail"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	reset := db.PasswordResetToken{ID: 1, Username: util.RandomOwner(), Email: util.RandomEmail()}
+	config := util.Config{PasswordResetURL: "http://localhost:3000/reset-password"}
+	mailer := mail.NewMemoryMailer()
+
+	var sent db.SentPasswordReset
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		SendPasswordResetsTx(gomock.Any(), gomock.Eq(int64(defaultPasswordResetBatchSize)), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, limit int64, send func(context.Context, db.PasswordResetToken) (db.SentPasswordReset, error)) (int, error) {
+			var err error
+			sent, err = send(ctx, reset)
+			require.NoError(t, err)
+			return 1, nil
+		})
+
+	n, err := NewPasswordResetWorker(config, store, mailer).RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.WithinDuration(t, time.Now().UTC().Add(defaultResetTokenDuration), sent.ExpiresAt, time.Second)
+
+	// The emailed link carries the token whose hash is stored
+	messages := mailer.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, reset.Email, messages[0].To)
+
+	start := strings.Index(messages[0].Body, config.PasswordResetURL)
+	require.GreaterOrEqual(t, start, 0)
+	link, err := url.Parse(strings.Fields(messages[0].Body[start:])[0])
+	require.NoError(t, err)
+	require.Equal(t, sent.TokenHash, util.HashSecureToken(link.Query().Get("token")))
+}
+
+func TestPasswordResetWorkerMailerFailure(t *testing.T) {
+	worker := NewPasswordResetWorker(util.Config{ResetTokenDuration: time.Hour}, nil, failingMailer{})
+	require.Equal(t, time.Hour, worker.duration)
+
+	_, err := worker.send(context.Background(), db.PasswordResetToken{Email: util.RandomEmail()})
+	require.Error(t, err)
+}
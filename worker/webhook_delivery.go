@@ -0,0 +1,185 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook"
+)
+
+const (
+	defaultWebhookBatchSize   = 20
+	defaultWebhookInterval    = 2 * time.Second
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookBaseBackoff = 30 * time.Second
+	defaultWebhookMaxBackoff  = 6 * time.Hour
+
+	// webhookLeaseMargin is added to a batch's lease for the database work around the sends
+	webhookLeaseMargin = 30 * time.Second
+)
+
+// WebhookDeliveryWorker sends queued webhook deliveries, retrying failures with
+// exponential backoff and dead-lettering them after too many attempts
+type WebhookDeliveryWorker struct {
+	store       db.Store
+	client      *http.Client
+	timeout     time.Duration // Per delivery; the client gives up after it
+	batchSize   int64
+	interval    time.Duration
+	maxAttempts int32
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	now         func() time.Time
+}
+
+// NewWebhookDeliveryWorker creates a webhook delivery worker
+func NewWebhookDeliveryWorker(store db.Store) *WebhookDeliveryWorker {
+	return &WebhookDeliveryWorker{
+		store:       store,
+		client:      webhook.NewClient(defaultWebhookTimeout),
+		timeout:     defaultWebhookTimeout,
+		batchSize:   defaultWebhookBatchSize,
+		interval:    defaultWebhookInterval,
+		maxAttempts: defaultWebhookMaxAttempts,
+		baseBackoff: defaultWebhookBaseBackoff,
+		maxBackoff:  defaultWebhookMaxBackoff,
+		now:         time.Now,
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled
+func (worker *WebhookDeliveryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := worker.RunOnce(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "webhook worker failed", "error", err)
+		}
+
+		if err == nil && int64(sent) == worker.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims a batch of due deliveries and attempts each one, returning how many were attempted
+func (worker *WebhookDeliveryWorker) RunOnce(ctx context.Context) (int, error) {
+	// 1. Claim due deliveries; the lease keeps other replicas from sending them meanwhile
+	deliveries, err := worker.store.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		LeaseSeconds: int64(worker.lease() / time.Second),
+		Limit:        worker.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions := make(map[int64]db.WebhookSubscription)
+	for _, delivery := range deliveries {
+		// 2. Look up the subscription (URL and secret)
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = worker.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+			if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+				return 0, err
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		// 3. Send and record the outcome
+		if err := worker.attempt(ctx, subscription, delivery); err != nil {
+			return 0, fmt.Errorf("delivery %d: %w", delivery.ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends one delivery and records success, a scheduled retry, or dead-lettering
+func (worker *WebhookDeliveryWorker) attempt(ctx context.Context, subscription db.WebhookSubscription, delivery db.WebhookDelivery) error {
+	if subscription.ID == 0 || !subscription.IsActive {
+		return worker.recordFailure(ctx, delivery, 0, errors.New("subscription is disabled"), true)
+	}
+
+	statusCode, err := worker.send(ctx, subscription, delivery)
+	if err == nil {
+		return worker.store.MarkWebhookDeliverySucceeded(ctx, db.MarkWebhookDeliverySucceededParams{
+			StatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: true},
+			ID:         delivery.ID,
+		})
+	}
+
+	return worker.recordFailure(ctx, delivery, statusCode, err, delivery.Attempts+1 >= worker.maxAttempts)
+}
+
+// send POSTs the signed delivery and treats any 2xx response as success. The client
+// only dials public addresses and doesn't follow redirects; plain http URLs registered
+// before https was required are refused here.
+func (worker *WebhookDeliveryWorker) send(ctx context.Context, subscription db.WebhookSubscription, delivery db.WebhookDelivery) (int, error) {
+	if !webhook.IsSecureURL(subscription.Url) {
+		return 0, webhook.ErrInsecureURL
+	}
+
+	req, err := webhook.NewRequest(ctx, subscription, delivery, worker.now())
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := worker.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordFailure stores the failed attempt and either schedules a retry or dead-letters the delivery
+func (worker *WebhookDeliveryWorker) recordFailure(ctx context.Context, delivery db.WebhookDelivery, statusCode int, cause error, dead bool) error {
+	status := webhook.StatusPending
+	if dead {
+		status = webhook.StatusDead
+	}
+
+	return worker.store.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+		Status:            status,
+		StatusCode:        sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+		LastError:         sql.NullString{String: cause.Error(), Valid: true},
+		RetryAfterSeconds: int64(worker.backoff(delivery.Attempts) / time.Second),
+		ID:                delivery.ID,
+	})
+}
+
+// lease returns how long a claimed batch stays hidden from other replicas: long enough to
+// send every delivery in it one after the other, each until it times out. The lease
+// runs on the database clock, like the due times it is compared with.
+func (worker *WebhookDeliveryWorker) lease() time.Duration {
+	return time.Duration(worker.batchSize)*worker.timeout + webhookLeaseMargin
+}
+
+// backoff returns the delay before the next attempt after `attempts` previous failures
+func (worker *WebhookDeliveryWorker) backoff(attempts int32) time.Duration {
+	delay := worker.baseBackoff
+	for i := int32(0); i < attempts && delay < worker.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, worker.maxBackoff)
+}
@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/mock"
+	db "github.com/nibir1/go-fiber-postgres-REST-boilerplate/db/sqlc"
+	"github.com/nibir1/go-fiber-postgres-REST-boilerplate/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebhookReceiver starts an httptest TLS server that verifies signatures and
+// answers with status, sending every verified body on the returned channel
+func newWebhookReceiver(t *testing.T, secret string, status int) (*httptest.Server, chan webhook.Body) {
+	bodies := make(chan webhook.Body, 10)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), data, time.Minute, time.Now()))
+
+		var body webhook.Body
+		require.NoError(t, json.Unmarshal(data, &body))
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestWebhookDeliveryWorker(t *testing.T) {
+	secret, err := webhook.GenerateSecret()
+	require.NoError(t, err)
+
+	delivery := db.WebhookDelivery{
+		ID:             5,
+		SubscriptionID: 1,
+		EventID:        42,
+		EventType:      db.EventTransferCreated,
+		Payload:        json.RawMessage(`{"id":9,"amount":10}`),
+		Status:         webhook.StatusPending,
+	}
+
+	testCases := []struct {
+		name       string
+		status     int
+		attempts   int32
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:   "OK",
+			status: http.StatusNoContent,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					MarkWebhookDeliverySucceeded(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.MarkWebhookDeliverySucceededParams) error {
+						require.Equal(t, delivery.ID, arg.ID)
+						require.Equal(t, int32(http.StatusNoContent), arg.StatusCode.Int32)
+						return nil
+					})
+			},
+		},
+		{
+			name:   "RetryWithBackoff",
+			status: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+						require.Equal(t, webhook.StatusPending, arg.Status)
+						require.Equal(t, int32(http.StatusInternalServerError), arg.StatusCode.Int32)
+						require.Equal(t, int64(defaultWebhookBaseBackoff/time.Second), arg.RetryAfterSeconds)
+						return nil
+					})
+			},
+		},
+		{
+			name:     "DeadLetter",
+			status:   http.StatusBadGateway,
+			attempts: defaultWebhookMaxAttempts - 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+						require.Equal(t, webhook.StatusDead, arg.Status)
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			receiver, bodies := newWebhookReceiver(t, secret, tc.status)
+			subscription := db.WebhookSubscription{ID: 1, Url: receiver.URL, Secret: secret, IsActive: true}
+
+			claimed := delivery
+			claimed.Attempts = tc.attempts
+
+			store := mockdb.NewMockStore(ctrl)
+			// The lease outlasts a whole batch of deliveries that all time out
+			store.EXPECT().
+				ClaimDueWebhookDeliveries(gomock.Any(), gomock.Eq(db.ClaimDueWebhookDeliveriesParams{
+					LeaseSeconds: int64((defaultWebhookBatchSize*defaultWebhookTimeout + webhookLeaseMargin) / time.Second),
+					Limit:        defaultWebhookBatchSize,
+				})).
+				Times(1).
+				Return([]db.WebhookDelivery{claimed}, nil)
+			store.EXPECT().
+				GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).
+				Times(1).
+				Return(subscription, nil)
+			tc.buildStubs(store)
+
+			// The receiver listens on loopback, which the default client refuses
+			worker := NewWebhookDeliveryWorker(store)
+			worker.client = receiver.Client()
+
+			sent, err := worker.RunOnce(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, sent)
+
+			body := <-bodies
+			require.Equal(t, delivery.EventID, body.ID)
+			require.Equal(t, delivery.EventType, body.Type)
+			require.JSONEq(t, string(delivery.Payload), string(body.Data))
+		})
+	}
+}
+
+func TestWebhookDeliveryRefusesUnsafeTargets(t *testing.T) {
+	secret, err := webhook.GenerateSecret()
+	require.NoError(t, err)
+	receiver, bodies := newWebhookReceiver(t, secret, http.StatusNoContent)
+
+	testCases := []struct {
+		name  string
+		url   string
+		cause error
+	}{
+		{name: "Loopback", url: receiver.URL, cause: webhook.ErrForbiddenHost},
+		{name: "PlainHTTP", url: "http://example.com/hooks", cause: webhook.ErrInsecureURL},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			subscription := db.WebhookSubscription{ID: 1, Url: tc.url, Secret: secret, IsActive: true}
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return([]db.WebhookDelivery{{ID: 5, SubscriptionID: 1, EventType: db.EventTransferCreated}}, nil)
+			store.EXPECT().
+				GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).
+				Times(1).
+				Return(subscription, nil)
+			store.EXPECT().
+				MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(ctx context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+					require.Contains(t, arg.LastError.String, tc.cause.Error())
+					return nil
+				})
+
+			_, err := NewWebhookDeliveryWorker(store).RunOnce(context.Background())
+			require.NoError(t, err)
+			require.Empty(t, bodies)
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	worker := NewWebhookDeliveryWorker(nil)
+
+	require.Equal(t, defaultWebhookBaseBackoff, worker.backoff(0))
+	require.Equal(t, 2*defaultWebhookBaseBackoff, worker.backoff(1))
+	require.Equal(t, 8*defaultWebhookBaseBackoff, worker.backoff(3))
+	require.Equal(t, defaultWebhookMaxBackoff, worker.backoff(30))
+}